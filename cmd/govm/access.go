@@ -0,0 +1,60 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const GuestAgentAccessMethod = "agent"
+const CloudInitAccessMethod = "cloud-init"
+
+// sshKeyTypes are the public key types accepted in authorized keys
+var sshKeyTypes = []string{
+	"ssh-ed25519",
+	"ssh-rsa",
+	"ecdsa-sha2-nistp256",
+	"ecdsa-sha2-nistp384",
+	"ecdsa-sha2-nistp521",
+	"sk-ssh-ed25519@openssh.com",
+	"sk-ecdsa-sha2-nistp256@openssh.com",
+}
+
+// ParseAuthorizedKeys validates public SSH keys in the authorized_keys format
+// without options, e.g. "ssh-ed25519 AAAA... user@host". The keys are
+// returned trimmed.
+func ParseAuthorizedKeys(keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys")
+	}
+	if len(keys) > MaxAuthorizedKeys {
+		return nil, fmt.Errorf("too many keys: %d", len(keys))
+	}
+	list := make([]string, len(keys))
+	for i, key := range keys {
+		key = strings.TrimSpace(key)
+		if strings.ContainsAny(key, "\r\n") {
+			return nil, fmt.Errorf("key %d: more than one line", i+1)
+		}
+		fields := strings.Fields(key)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("key %d: missing type or data", i+1)
+		}
+		if !contains(sshKeyTypes, fields[0]) {
+			return nil, fmt.Errorf("key %d: unsupported type: %s", i+1, fields[0])
+		}
+		data, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(data) < 4 {
+			return nil, fmt.Errorf("key %d: illegal data", i+1)
+		}
+		// The data starts with the length and the name of the type
+		size := int(data[0])<<24 | int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+		if size != len(fields[0]) || len(data) < 4+size || string(data[4:4+size]) != fields[0] {
+			return nil, fmt.Errorf("key %d: data does not match the type", i+1)
+		}
+		list[i] = key
+	}
+	return list, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// testPublicKey returns a public key in the authorized_keys format with the
+// type encoded in the data
+func testPublicKey(keyType string) string {
+	data := binary.BigEndian.AppendUint32(nil, uint32(len(keyType)))
+	data = append(data, keyType...)
+	data = binary.BigEndian.AppendUint32(data, 32)
+	data = append(data, make([]byte, 32)...)
+	return keyType + " " + base64.StdEncoding.EncodeToString(data) + " user@example.com"
+}
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	key := testPublicKey("ssh-ed25519")
+	list, err := ParseAuthorizedKeys([]string{"  " + key + "\n"})
+	if err != nil {
+		t.Fatalf("valid key rejected: %v", err)
+	}
+	if list[0] != key {
+		t.Errorf("key = %q, want trimmed %q", list[0], key)
+	}
+
+	invalid := map[string][]string{
+		"no keys":          {},
+		"missing data":     {"ssh-ed25519"},
+		"unknown type":     {testPublicKey("ssh-dss")},
+		"mismatching type": {"ssh-rsa " + strings.Fields(key)[1]},
+		"illegal base64":   {"ssh-ed25519 not-base64!"},
+		"two lines":        {key + "\n" + key},
+	}
+	for name, keys := range invalid {
+		if _, err := ParseAuthorizedKeys(keys); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestGetCloudInitUserData(t *testing.T) {
+	withPassword := getCloudInitUserData("$6$salt$hash")
+	if !strings.Contains(withPassword, "passwd: $6$salt$hash") {
+		t.Errorf("password missing:\n%s", withPassword)
+	}
+	if strings.Contains(getCloudInitUserData(""), " passwd:") {
+		t.Errorf("empty password should be left out")
+	}
+}
+
+func TestPendingAccessUserData(t *testing.T) {
+	pending := &pendingAccess{Password: "$6$hash"}
+	pending.setKeys([]string{"ssh-ed25519 AAAA first"}, true)
+	pending.setKeys([]string{"ssh-ed25519 AAAA second"}, true)
+
+	userData, err := getPendingAccessUserData(pending)
+	if err != nil {
+		t.Fatalf("getPendingAccessUserData failed: %v", err)
+	}
+	for _, want := range []string{"password: $6$hash", "first\\nssh-ed25519 AAAA second", "append: true"} {
+		if !strings.Contains(userData, want) {
+			t.Errorf("user data does not contain %q:\n%s", want, userData)
+		}
+	}
+
+	pending.setKeys([]string{"ssh-ed25519 AAAA third"}, false)
+	userData, err = getPendingAccessUserData(pending)
+	if err != nil {
+		t.Fatalf("getPendingAccessUserData failed: %v", err)
+	}
+	if strings.Contains(userData, "first") || !strings.Contains(userData, "append: false") {
+		t.Errorf("replaced keys were kept:\n%s", userData)
+	}
+	if !strings.Contains(userData, "password: $6$hash") {
+		t.Errorf("password was dropped when the keys were replaced:\n%s", userData)
+	}
+}
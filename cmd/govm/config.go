@@ -11,30 +11,84 @@ import (
 
 // Config holds the overall configuration
 type Config struct {
-	Servers ServerConfigList `yaml:"servers"`
+	Servers   ServerConfigList          `yaml:"servers"`
+	Networks  NetworkConfigList         `yaml:"networks,omitempty"`
+	Forwards  PortForwardConfigList     `yaml:"forwards,omitempty"`
+	Firewalls FirewallRuleSetConfigList `yaml:"firewalls,omitempty"`
+	Users     UserConfigList            `yaml:"users,omitempty"`
+	Groups    GroupConfigList           `yaml:"groups,omitempty"`
+	Projects  ProjectConfigList         `yaml:"projects,omitempty"`
+	Hosts     HostConfigList            `yaml:"hosts,omitempty"`
 }
 
 func NewConfig(
 	config ServerConfigList,
 ) *Config {
 	return &Config{
-		config,
+		Servers: config,
 	}
 }
 
-// AddServer adds a new server in the config and returns a new config object
-func (c *Config) AddServer(name string, users UserEmailList) *Config {
-	newList := append(c.Servers, NewServerConfig(name, users))
-	return NewConfig(newList)
+// AddServer adds a new server in the config and returns a new config object.
+// The project is empty for a server which is not in a project.
+func (c *Config) AddServer(name, project string, users UserEmailList) *Config {
+	item := NewServerConfig(name, users)
+	item.Project = project
+	newList := append(c.Servers, item)
+	newConfig := *c
+	newConfig.Servers = newList
+	return &newConfig
 }
 
-// ServerHasAccessToEmail checks if an email address exists in the allowed list of email addresses to have access
+// AddPortForward adds a new port forward in the config and returns a new config object
+func (c *Config) AddPortForward(forward *PortForwardConfig) *Config {
+	newList := append(PortForwardConfigList{}, c.Forwards...)
+	newConfig := *c
+	newConfig.Forwards = append(newList, forward)
+	return &newConfig
+}
+
+// RemovePortForward removes a port forward from the config and returns a new config object
+func (c *Config) RemovePortForward(protocol string, hostPort int) *Config {
+	var newList PortForwardConfigList
+	for _, item := range c.Forwards {
+		if item.Protocol != protocol || item.HostPort != hostPort {
+			newList = append(newList, item)
+		}
+	}
+	newConfig := *c
+	newConfig.Forwards = newList
+	return &newConfig
+}
+
+// RemoveServerPortForwards removes all port forwards of the server from the config and returns a new config object
+func (c *Config) RemoveServerPortForwards(name string) *Config {
+	var newList PortForwardConfigList
+	for _, item := range c.Forwards {
+		if item.Server != name {
+			newList = append(newList, item)
+		}
+	}
+	newConfig := *c
+	newConfig.Forwards = newList
+	return &newConfig
+}
+
+// ServerHasAccessToEmail checks if an email address exists in the allowed list of email addresses to have access,
+// or is a member of the project of the server
 func (c *Config) ServerHasAccessToEmail(name, email string) bool {
 	item := c.Servers.findByName(name)
 	if item == nil {
 		return false
 	}
-	return item.Users.contains(email)
+	if item.Users.contains(email) {
+		return true
+	}
+	if item.Project == "" {
+		return false
+	}
+	project := c.Projects.findByName(item.Project)
+	return project != nil && project.GetRole(email) != ""
 }
 
 // LoadConfig reads and parses the YAML configuration file
@@ -4,6 +4,7 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"sync"
 )
 
@@ -34,16 +35,152 @@ func (m *ConfigManager) GetConfig() *Config {
 }
 
 // AddServerConfig adds a server to the config and queues a write operation
-func (m *ConfigManager) AddServerConfig(name string, users UserEmailList) {
+func (m *ConfigManager) AddServerConfig(name, project string, users UserEmailList) {
 
 	m.configMutex.Lock()
-	m.config = m.config.AddServer(name, users)
+	m.config = m.config.AddServer(name, project, users)
 	m.configMutex.Unlock()
 
 	// Queue the write operation
 	m.queue <- name
 }
 
+// SetServerHostConfig records the host of a server and queues a write operation
+func (m *ConfigManager) SetServerHostConfig(name, host string) {
+
+	m.configMutex.Lock()
+	m.config = m.config.SetServerHost(name, host)
+	m.configMutex.Unlock()
+
+	m.queue <- name
+}
+
+// AddProjectConfig adds a project owned by the user and queues a write operation
+func (m *ConfigManager) AddProjectConfig(name, owner string) error {
+
+	m.configMutex.Lock()
+	if m.config.Projects.findByName(name) != nil {
+		m.configMutex.Unlock()
+		return fmt.Errorf("AddProjectConfig: %s: %w", name, ErrProjectExists)
+	}
+	m.config = m.config.AddProject(name, owner)
+	m.configMutex.Unlock()
+
+	m.queue <- name
+	return nil
+}
+
+// RemoveProjectConfig removes a project without servers and queues a write operation
+func (m *ConfigManager) RemoveProjectConfig(name string) error {
+
+	m.configMutex.Lock()
+	if m.config.Projects.findByName(name) == nil {
+		m.configMutex.Unlock()
+		return fmt.Errorf("RemoveProjectConfig: %s: %w", name, ErrProjectNotFound)
+	}
+	if m.config.CountProjectServers(name) > 0 {
+		m.configMutex.Unlock()
+		return fmt.Errorf("RemoveProjectConfig: %s: %w", name, ErrProjectNotEmpty)
+	}
+	m.config = m.config.RemoveProject(name)
+	m.configMutex.Unlock()
+
+	m.queue <- name
+	return nil
+}
+
+// SetProjectMemberConfig adds or updates a member of a project and queues a
+// write operation. The last owner is checked under the lock, so concurrent
+// changes cannot leave the project without owners.
+func (m *ConfigManager) SetProjectMemberConfig(project, email, role string) error {
+
+	m.configMutex.Lock()
+	item := m.config.Projects.findByName(project)
+	if item == nil {
+		m.configMutex.Unlock()
+		return fmt.Errorf("SetProjectMemberConfig: %s: %w", project, ErrProjectNotFound)
+	}
+	if err := item.checkProjectMember(email, role); err != nil {
+		m.configMutex.Unlock()
+		return fmt.Errorf("SetProjectMemberConfig: %s: %w", project, err)
+	}
+	m.config = m.config.SetProjectMember(project, email, role)
+	m.configMutex.Unlock()
+
+	m.queue <- project
+	return nil
+}
+
+// RemoveProjectMemberConfig removes a member of a project and queues a write
+// operation. The last owner is not removed.
+func (m *ConfigManager) RemoveProjectMemberConfig(project, email string) error {
+
+	m.configMutex.Lock()
+	item := m.config.Projects.findByName(project)
+	if item == nil {
+		m.configMutex.Unlock()
+		return fmt.Errorf("RemoveProjectMemberConfig: %s: %w", project, ErrProjectNotFound)
+	}
+	if item.GetRole(email) == "" {
+		m.configMutex.Unlock()
+		return fmt.Errorf("RemoveProjectMemberConfig: %s: %w", project, ErrProjectMemberNotFound)
+	}
+	if err := item.checkProjectMember(email, ""); err != nil {
+		m.configMutex.Unlock()
+		return fmt.Errorf("RemoveProjectMemberConfig: %s: %w", project, err)
+	}
+	m.config = m.config.RemoveProjectMember(project, email)
+	m.configMutex.Unlock()
+
+	m.queue <- project
+	return nil
+}
+
+// MigrateProjects moves the servers of a config without projects into the
+// default project and queues a write operation
+func (m *ConfigManager) MigrateProjects() {
+
+	m.configMutex.Lock()
+	config, migrated := m.config.MigrateProjects()
+	m.config = config
+	m.configMutex.Unlock()
+
+	if migrated {
+		log.Printf("MigrateProjects: Servers moved to the %s project", DefaultProjectName)
+		m.queue <- DefaultProjectName
+	}
+}
+
+// AddPortForwardConfig adds a port forward to the config and queues a write operation
+func (m *ConfigManager) AddPortForwardConfig(forward *PortForwardConfig) {
+
+	m.configMutex.Lock()
+	m.config = m.config.AddPortForward(forward)
+	m.configMutex.Unlock()
+
+	m.queue <- forward.Server
+}
+
+// RemovePortForwardConfig removes a port forward from the config and queues a write operation
+func (m *ConfigManager) RemovePortForwardConfig(forward *PortForwardConfig) {
+
+	m.configMutex.Lock()
+	m.config = m.config.RemovePortForward(forward.Protocol, forward.HostPort)
+	m.configMutex.Unlock()
+
+	m.queue <- forward.Server
+}
+
+// RemoveServerPortForwardConfigs removes the port forwards of a server from the config and queues a write operation
+func (m *ConfigManager) RemoveServerPortForwardConfigs(name string) {
+
+	m.configMutex.Lock()
+	m.config = m.config.RemoveServerPortForwards(name)
+	m.configMutex.Unlock()
+
+	m.queue <- name
+}
+
 // runWorker processes the queue in the background
 func (m *ConfigManager) runWorker() {
 	for range m.queue {
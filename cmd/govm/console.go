@@ -0,0 +1,34 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+const (
+	VncConsoleType   = "vnc"
+	SpiceConsoleType = "spice"
+)
+
+// ConsoleModel This is the data model of the graphical console of the server
+type ConsoleModel struct {
+
+	// Type the console protocol, vnc or spice
+	Type string
+
+	// Network the network to dial the console, tcp or unix
+	Network string
+
+	// Address the host and port or the path of the UNIX socket
+	Address string
+}
+
+func NewConsoleModel(consoleType, network, address string) *ConsoleModel {
+	return &ConsoleModel{
+		Type:    consoleType,
+		Network: network,
+		Address: address,
+	}
+}
+
+// IsConsoleType returns true if the type is a supported console type
+func IsConsoleType(consoleType string) bool {
+	return consoleType == VncConsoleType || consoleType == SpiceConsoleType
+}
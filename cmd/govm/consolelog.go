@@ -0,0 +1,56 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readLastLines returns at most the last n lines of the file. Only the last
+// maxBytes of the file are read. A missing file has no lines.
+func readLastLines(path string, n int, maxBytes int64) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("readLastLines: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("readLastLines: %v", err)
+	}
+	// Start one byte early to see if the first line is complete
+	offset := info.Size() - maxBytes - 1
+	if offset < 0 {
+		offset = 0
+	}
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, fmt.Errorf("readLastLines: %v", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("readLastLines: %v", err)
+	}
+
+	text := string(data)
+	if offset > 0 {
+		_, text, _ = strings.Cut(text, "\n")
+	}
+	text = strings.ReplaceAll(text, "\r", "")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil, nil
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
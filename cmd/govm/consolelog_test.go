@@ -0,0 +1,39 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadLastLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.log")
+	if err := os.WriteFile(path, []byte("one\r\ntwo\r\nthree\r\nfour\r\n"), 0644); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	lines, err := readLastLines(path, 2, 1024)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"three", "four"}) {
+		t.Errorf("Unexpected lines: %q", lines)
+	}
+
+	// Only the end of the file is read and the partial line is skipped
+	lines, err = readLastLines(path, 10, 13)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(lines, []string{"three", "four"}) {
+		t.Errorf("Unexpected lines: %q", lines)
+	}
+
+	lines, err = readLastLines(filepath.Join(t.TempDir(), "missing.log"), 10, 1024)
+	if err != nil || lines != nil {
+		t.Errorf("Expected no lines for a missing file, got %q: %v", lines, err)
+	}
+}
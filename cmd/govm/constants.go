@@ -2,7 +2,64 @@
 
 package main
 
+import (
+	"time"
+)
+
 const (
-	DefaultAdminUserEmail   = "admin@example.com"
-	ConfigManagerBufferSize = 100
+	DefaultAdminUserEmail     = "admin@example.com"
+	ConfigManagerBufferSize   = 100
+	QemuImgCommand            = "qemu-img"
+	RootDiskDevice            = "vda"
+	DataVolumeFilePrefix      = "-data-"
+	PoolVolumeSeparator       = "."
+	DirectoryStorageType      = "directory"
+	PoolStorageType           = "pool"
+	DefaultInterfaceAddress   = "192.168.123.2"
+	DefaultInterfacePrefix    = 24
+	DefaultInterfaceGateway   = "192.168.123.1"
+	DefaultPortForwardRange   = "20000-20999"
+	PortForwardDialTimeout    = 10 * time.Second
+	PortForwardUDPIdleTimeout = 2 * time.Minute
+	ConsoleLogFileSuffix      = "-console.log"
+	ConsoleLogMaxReadSize     = 1024 * 1024
+	DefaultConsoleLogTail     = 100
+	MaxConsoleLogTail         = 10000
+	DefaultVncSessionTTL      = time.Hour
+	SerialTokenTTL            = time.Minute
+	DefaultVncSessionLimit    = 2
+	VncSessionExpireInterval  = 10 * time.Second
+	VncProxyBufferSize        = 32 * 1024
+	VncPingInterval           = 30 * time.Second
+	VncPongTimeout            = 75 * time.Second
+	VncWriteTimeout           = 10 * time.Second
+	VncDialTimeout            = 10 * time.Second
+	VncIdleTimeout            = 30 * time.Minute
+	ConsoleSocketFileSuffix   = ".sock"
+	ScreenshotMaxSize         = 64 * 1024 * 1024
+	ScreenshotMaxDimension    = 8192
+	ThumbnailWidth            = 320
+	DefaultThumbnailInterval  = time.Minute
+	MaxKeyComboSize           = 16
+	KeyHoldTime               = 100
+	DefaultStopTimeout        = 2 * time.Minute
+	MaxStopTimeout            = time.Hour
+	OperationPollInterval     = time.Second
+	DefaultGuestInfoInterval  = time.Minute
+	MinServerPasswordLength   = 8
+	MaxServerPasswordLength   = 128
+	GeneratedPasswordLength   = 16
+	MaxAuthorizedKeys         = 100
+	GuestAgentTimeout         = 10 * time.Second
+	DefaultFreezeTimeout      = time.Minute
+	MaxFreezeTimeout          = 10 * time.Minute
+	DefaultMetricsCacheTTL    = 15 * time.Second
+	DefaultMetricsInterval    = 15 * time.Second
+	DefaultMetricsRetention   = 24 * time.Hour
+	DefaultMetricsRange       = time.Hour
+	MaxMetricsPoints          = 1000
+	DefaultServerCPUs         = 1
+	DefaultServerMemory       = 1024 * 1024 * 1024
+	DefaultCPUOvercommit      = 4.0
+	DefaultMemoryOvercommit   = 1.5
 )
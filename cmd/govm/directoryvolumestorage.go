@@ -0,0 +1,156 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"libvirt.org/go/libvirt"
+)
+
+// DirectoryVolumeStorage stores volumes as files in a subdirectory per server
+type DirectoryVolumeStorage struct {
+	volumesPath string
+}
+
+func NewDirectoryVolumeStorage(volumesPath string) *DirectoryVolumeStorage {
+	return &DirectoryVolumeStorage{
+		volumesPath: volumesPath,
+	}
+}
+
+var _ VolumeStorage = &DirectoryVolumeStorage{}
+
+func (s *DirectoryVolumeStorage) CreateVolume(conn *libvirt.Connect, server, key, format string, size uint64) error {
+	file := s.getVolumeFile(server, key)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("CreateVolume: failed to create volume directory: %v", err)
+	}
+	if format != "qcow2" {
+		return fmt.Errorf("CreateVolume: unsupported format: %s", format)
+	}
+	return qemuImgCreate(file, size)
+}
+
+func (s *DirectoryVolumeStorage) ImportVolume(conn *libvirt.Connect, server, key, format, sourceFile string) error {
+	err := copyImageFile(sourceFile, s.getVolumeFile(server, key))
+	if err != nil {
+		return fmt.Errorf("ImportVolume: %v", err)
+	}
+	return nil
+}
+
+func (s *DirectoryVolumeStorage) DeleteVolume(conn *libvirt.Connect, server, key string) error {
+	err := os.Remove(s.getVolumeFile(server, key))
+	if err != nil {
+		return fmt.Errorf("DeleteVolume: %v", err)
+	}
+	return nil
+}
+
+func (s *DirectoryVolumeStorage) HasVolume(conn *libvirt.Connect, server, key string) (bool, error) {
+	_, err := os.Stat(s.getVolumeFile(server, key))
+	if err == nil {
+		return true, nil
+	} else if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, fmt.Errorf("HasVolume: %v", err)
+}
+
+func (s *DirectoryVolumeStorage) ListVolumes(conn *libvirt.Connect, server string) ([]string, error) {
+	entries, err := os.ReadDir(s.getServerDirectory(server))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ListVolumes: %v", err)
+	}
+	var keys []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), server+"-") {
+			keys = append(keys, entry.Name())
+		}
+	}
+	return keys, nil
+}
+
+func (s *DirectoryVolumeStorage) ListAllVolumes(conn *libvirt.Connect) (map[string][]string, error) {
+	entries, err := os.ReadDir(s.volumesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ListAllVolumes: %v", err)
+	}
+	volumes := make(map[string][]string)
+	for _, entry := range entries {
+		if !entry.IsDir() || !ValidateName(entry.Name()) {
+			continue
+		}
+		keys, err := s.ListVolumes(conn, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("ListAllVolumes: %v", err)
+		}
+		volumes[entry.Name()] = keys
+	}
+	return volumes, nil
+}
+
+func (s *DirectoryVolumeStorage) GetVolumeSize(conn *libvirt.Connect, server, key string) (uint64, error) {
+	info, err := qemuImgGetInfo(s.getVolumeFile(server, key))
+	if err != nil {
+		return 0, fmt.Errorf("GetVolumeSize: %v", err)
+	}
+	return info.VirtualSize, nil
+}
+
+func (s *DirectoryVolumeStorage) ResizeVolume(conn *libvirt.Connect, server, key string, size uint64) error {
+	return qemuImgResize(s.getVolumeFile(server, key), size)
+}
+
+func (s *DirectoryVolumeStorage) GetDiskSource(server, key string) (string, string) {
+	return "file", `<source file='` + s.getVolumeFile(server, key) + `'/>`
+}
+
+func (s *DirectoryVolumeStorage) FindVolumeKey(server string, disk DomainDiskXML) string {
+	file := disk.Source.File
+	if file == "" || filepath.Dir(file) != s.getServerDirectory(server) {
+		return ""
+	}
+	return filepath.Base(file)
+}
+
+func (s *DirectoryVolumeStorage) GetStorage(conn *libvirt.Connect) (*StorageModel, error) {
+	return getDirectoryStorage(s.volumesPath)
+}
+
+// getDirectoryStorage returns the space of the filesystem of the directory
+func getDirectoryStorage(path string) (*StorageModel, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, fmt.Errorf("getDirectoryStorage: %s: %v", path, err)
+	}
+	blockSize := uint64(stat.Bsize)
+	return &StorageModel{
+		Type:       DirectoryStorageType,
+		Name:       path,
+		Capacity:   stat.Blocks * blockSize,
+		Allocation: (stat.Blocks - stat.Bfree) * blockSize,
+		Available:  stat.Bavail * blockSize,
+	}, nil
+}
+
+// getServerDirectory returns the directory which holds the files of the server
+func (s *DirectoryVolumeStorage) getServerDirectory(server string) string {
+	return filepath.Join(s.volumesPath, server)
+}
+
+// getVolumeFile returns the path of a volume of the server
+func (s *DirectoryVolumeStorage) getVolumeFile(server, key string) string {
+	return filepath.Join(s.getServerDirectory(server), key)
+}
@@ -53,6 +53,41 @@ type IndexDTO struct {
 
 	// Permissions is permissions available to the user
 	Permissions ServerPermissionDTO `json:"permissions"`
+
+	// Quotas are the quotas of the user and the groups of the user with their usage
+	Quotas []QuotaDTO `json:"quotas,omitempty"`
+}
+
+// QuotaDTO defines the structure of a quota of the user or a group
+type QuotaDTO struct {
+
+	// Scope is user or group
+	Scope string `json:"scope"`
+
+	// Name is the email address of the user or the name of the group
+	Name string `json:"name"`
+
+	// Limit is the quota, where zero is unlimited
+	Limit QuotaResourcesDTO `json:"limit"`
+
+	// Usage is the resources of the servers counted against the quota
+	Usage QuotaResourcesDTO `json:"usage"`
+}
+
+// QuotaResourcesDTO defines the structure of the resources of a quota
+type QuotaResourcesDTO struct {
+
+	// Servers is the number of servers
+	Servers int `json:"servers"`
+
+	// CPUs is the number of virtual CPUs
+	CPUs uint `json:"cpus"`
+
+	// Memory is the memory in bytes
+	Memory uint64 `json:"memory"`
+
+	// Disk is the size of the volumes in bytes
+	Disk uint64 `json:"disk"`
 }
 
 // ServerDTO struct defines the structure of the response DTO returned from the server
@@ -64,11 +99,574 @@ type ServerDTO struct {
 	// Status is the status of the virtual server
 	Status string `json:"status"`
 
+	// Project is the project of the server, or empty if the server is not in a project
+	Project string `json:"project,omitempty"`
+
+	// Host is the host the server was migrated to, or empty if it was not
+	Host string `json:"host,omitempty"`
+
 	// Actions which are available to perform on the server
 	Actions []string `json:"actions"`
 
 	// Permissions is permissions available to the user
 	Permissions ServerPermissionDTO `json:"permissions"`
+
+	// Volumes is the list of disk volumes of the server, including the root disk
+	Volumes []VolumeDTO `json:"volumes"`
+
+	// Interfaces is the list of network interfaces of the server
+	Interfaces []NetworkInterfaceDTO `json:"interfaces"`
+
+	// Console is the type of the graphical console, vnc or spice
+	Console string `json:"console,omitempty"`
+
+	// Thumbnail is the URL of a recent small screenshot of a running server
+	Thumbnail string `json:"thumbnail,omitempty"`
+
+	// Operation is the latest long running operation, like a graceful stop
+	Operation *OperationDTO `json:"operation,omitempty"`
+
+	// Hostname is the hostname the guest agent reported
+	Hostname string `json:"hostname,omitempty"`
+
+	// OS is the name of the operating system the guest agent reported
+	OS string `json:"os,omitempty"`
+
+	// Addresses are the IP addresses the guest agent reported
+	Addresses []string `json:"addresses,omitempty"`
+
+	// ConsistentSnapshots is true if the guest agent can freeze the filesystems for snapshots
+	ConsistentSnapshots bool `json:"consistentSnapshots"`
+
+	// Frozen is true if the filesystems of the server are frozen
+	Frozen bool `json:"frozen,omitempty"`
+}
+
+// GuestInfoDTO defines the structure of the information from the guest agent
+type GuestInfoDTO struct {
+
+	// Hostname is the hostname of the guest
+	Hostname string `json:"hostname"`
+
+	// OS is the operating system of the guest
+	OS GuestOSDTO `json:"os"`
+
+	// Timezone is the name of the timezone of the guest
+	Timezone string `json:"timezone,omitempty"`
+
+	// Users are the logged in users
+	Users []GuestUserDTO `json:"users"`
+
+	// FileSystems are the mounted filesystems with their usage
+	FileSystems []GuestFileSystemDTO `json:"fileSystems"`
+
+	// Interfaces are the network interfaces with their addresses
+	Interfaces []GuestInterfaceDTO `json:"interfaces"`
+
+	// Updated is the time the information was read in RFC 3339 format
+	Updated string `json:"updated"`
+}
+
+// GuestOSDTO defines the structure of the operating system of the guest
+type GuestOSDTO struct {
+
+	// ID is the short name of the operating system, e.g. debian
+	ID string `json:"id,omitempty"`
+
+	// Name is the name of the operating system
+	Name string `json:"name,omitempty"`
+
+	// PrettyName is the name with the version
+	PrettyName string `json:"prettyName,omitempty"`
+
+	// Version is the version of the operating system
+	Version string `json:"version,omitempty"`
+
+	// KernelRelease is the release of the kernel
+	KernelRelease string `json:"kernelRelease,omitempty"`
+
+	// Machine is the architecture, e.g. x86_64
+	Machine string `json:"machine,omitempty"`
+}
+
+// GuestUserDTO defines the structure of a user logged in to the guest
+type GuestUserDTO struct {
+
+	// Name is the name of the user
+	Name string `json:"name"`
+
+	// Domain is the domain of the user on Windows
+	Domain string `json:"domain,omitempty"`
+
+	// LoginTime is the time the user logged in in RFC 3339 format
+	LoginTime string `json:"loginTime,omitempty"`
+}
+
+// GuestFileSystemDTO defines the structure of a filesystem mounted in the guest
+type GuestFileSystemDTO struct {
+
+	// MountPoint is the path the filesystem is mounted at
+	MountPoint string `json:"mountPoint"`
+
+	// Device is the name of the device in the guest
+	Device string `json:"device,omitempty"`
+
+	// Type is the type of the filesystem, e.g. ext4
+	Type string `json:"type,omitempty"`
+
+	// TotalBytes is the size of the filesystem
+	TotalBytes uint64 `json:"totalBytes"`
+
+	// UsedBytes is the used space of the filesystem
+	UsedBytes uint64 `json:"usedBytes"`
+}
+
+// GuestInterfaceDTO defines the structure of a network interface of the guest
+type GuestInterfaceDTO struct {
+
+	// Name is the name of the interface in the guest
+	Name string `json:"name"`
+
+	// MAC is the MAC address of the interface
+	MAC string `json:"mac,omitempty"`
+
+	// Addresses are the IP addresses with the prefix, e.g. 10.0.0.2/24
+	Addresses []string `json:"addresses"`
+}
+
+// ServerMetricsDTO defines the structure of the usage history of the server
+type ServerMetricsDTO struct {
+
+	// Name is the name of the server
+	Name string `json:"name"`
+
+	// Range is the time covered in seconds, ending now
+	Range int `json:"range"`
+
+	// Step is the time between the points in seconds
+	Step int `json:"step"`
+
+	// Points are the averages of each step, oldest first
+	Points []ServerMetricsPointDTO `json:"points"`
+}
+
+// ServerMetricsPointDTO defines the structure of the usage of the server
+// during a step. The disk and network values are bytes per second.
+type ServerMetricsPointDTO struct {
+
+	// Time is the start of the step in RFC 3339 format
+	Time string `json:"time"`
+
+	// CPU is the number of CPU cores used, e.g. 0.5 is half of one core
+	CPU float64 `json:"cpu"`
+
+	// MemoryUsed is the memory used by the guest in bytes
+	MemoryUsed float64 `json:"memoryUsed"`
+
+	// MemoryTotal is the memory of the server in bytes
+	MemoryTotal float64 `json:"memoryTotal"`
+
+	// DiskRead is the rate of bytes read from all disks
+	DiskRead float64 `json:"diskRead"`
+
+	// DiskWrite is the rate of bytes written to all disks
+	DiskWrite float64 `json:"diskWrite"`
+
+	// NetworkReceive is the rate of bytes received by all interfaces
+	NetworkReceive float64 `json:"networkReceive"`
+
+	// NetworkTransmit is the rate of bytes transmitted by all interfaces
+	NetworkTransmit float64 `json:"networkTransmit"`
+}
+
+// OperationDTO defines the structure of a long running operation of the server
+type OperationDTO struct {
+
+	// ID identifies the operation
+	ID string `json:"id"`
+
+	// Action is the action performed, e.g. stop or restart
+	Action string `json:"action"`
+
+	// State is running, succeeded or failed
+	State string `json:"state"`
+
+	// Error is the reason the operation failed
+	Error string `json:"error,omitempty"`
+
+	// Progress is the percentage done, when the operation reports it
+	Progress int `json:"progress,omitempty"`
+
+	// Started is the time the operation was started in RFC 3339 format
+	Started string `json:"started"`
+
+	// Finished is the time the operation finished in RFC 3339 format
+	Finished string `json:"finished,omitempty"`
+}
+
+// NetworkInterfaceDTO defines the structure of a network interface of the server
+type NetworkInterfaceDTO struct {
+
+	// Type is the interface type, one of network, bridge or user
+	Type string `json:"type"`
+
+	// Source is the name of the libvirt network or the bridge
+	Source string `json:"source,omitempty"`
+
+	// MAC is the MAC address of the interface
+	MAC string `json:"mac,omitempty"`
+
+	// Address is the optional static IPv4 address with the prefix, e.g. 10.0.0.2/24
+	Address string `json:"address,omitempty"`
+
+	// Gateway is the optional IPv4 gateway for the static address
+	Gateway string `json:"gateway,omitempty"`
+
+	// InboundAverage is the optional inbound bandwidth limit in kilobytes per second
+	InboundAverage uint `json:"inboundAverage,omitempty"`
+
+	// OutboundAverage is the optional outbound bandwidth limit in kilobytes per second
+	OutboundAverage uint `json:"outboundAverage,omitempty"`
+}
+
+// NetworkDTO defines the structure of a network servers can be connected to
+type NetworkDTO struct {
+
+	// Type is the interface type used to connect to the network
+	Type string `json:"type"`
+
+	// Source is the name of the libvirt network or the bridge
+	Source string `json:"source,omitempty"`
+
+	// Bridge is the host bridge interface of the network, if known
+	Bridge string `json:"bridge,omitempty"`
+
+	// Active is true if the network is running
+	Active bool `json:"active"`
+
+	// Autostart is true if the libvirt network is started on boot
+	Autostart bool `json:"autostart,omitempty"`
+
+	// Mode is the forward mode of a libvirt network, nat or isolated
+	Mode string `json:"mode,omitempty"`
+
+	// Address is the IPv4 address of the host on the network with the prefix
+	Address string `json:"address,omitempty"`
+
+	// DHCPStart is the first address of the dynamic DHCP range
+	DHCPStart string `json:"dhcpStart,omitempty"`
+
+	// DHCPEnd is the last address of the dynamic DHCP range
+	DHCPEnd string `json:"dhcpEnd,omitempty"`
+
+	// Hosts is the list of static DNS host entries, only sent to the admin
+	Hosts []DNSHostDTO `json:"hosts,omitempty"`
+
+	// Leases is the list of static DHCP leases, only sent to the admin
+	Leases []DHCPLeaseDTO `json:"leases,omitempty"`
+}
+
+// DNSHostDTO defines the structure of a static DNS host entry of a network
+type DNSHostDTO struct {
+
+	// IP is the address of the host
+	IP string `json:"ip"`
+
+	// Hostnames is the list of names for the address
+	Hostnames []string `json:"hostnames"`
+}
+
+// DHCPLeaseDTO defines the structure of a static DHCP lease of a network
+type DHCPLeaseDTO struct {
+
+	// MAC is the MAC address of the interface
+	MAC string `json:"mac"`
+
+	// Name is the host name of the lease
+	Name string `json:"name,omitempty"`
+
+	// IP is the leased address
+	IP string `json:"ip"`
+}
+
+// CreateNetworkDTO defines the structure of the request body to create a libvirt network
+type CreateNetworkDTO struct {
+
+	// Name is the name of the network
+	Name string `json:"name"`
+
+	// Mode is the forward mode, nat or isolated
+	Mode string `json:"mode"`
+
+	// Address is the IPv4 address of the host on the network with the prefix, e.g. 10.0.0.1/24
+	Address string `json:"address"`
+
+	// DHCPStart is the optional first address of the dynamic DHCP range
+	DHCPStart string `json:"dhcpStart,omitempty"`
+
+	// DHCPEnd is the optional last address of the dynamic DHCP range
+	DHCPEnd string `json:"dhcpEnd,omitempty"`
+
+	// Hosts is the optional list of static DNS host entries
+	Hosts []DNSHostDTO `json:"hosts,omitempty"`
+}
+
+// NetworkListDTO struct defines the structure of the response DTO for networks
+type NetworkListDTO struct {
+	Payload []NetworkDTO `json:"payload"`
+}
+
+// PortForwardDTO defines the structure of a host port forwarded to the server
+type PortForwardDTO struct {
+
+	// Protocol is tcp or udp
+	Protocol string `json:"protocol"`
+
+	// HostPort is the port on the host
+	HostPort int `json:"hostPort"`
+
+	// GuestAddress is the IP address of the server
+	GuestAddress string `json:"guestAddress"`
+
+	// GuestPort is the port on the server
+	GuestPort int `json:"guestPort"`
+}
+
+// CreatePortForwardDTO defines the structure of the request body to forward a host port
+type CreatePortForwardDTO struct {
+
+	// Protocol is tcp or udp, defaults to tcp
+	Protocol string `json:"protocol,omitempty"`
+
+	// HostPort is the optional port on the host, allocated from the configured range if omitted
+	HostPort int `json:"hostPort,omitempty"`
+
+	// GuestAddress is the optional IP address of an interface of the server, the first one if omitted
+	GuestAddress string `json:"guestAddress,omitempty"`
+
+	// GuestPort is the port on the server
+	GuestPort int `json:"guestPort"`
+}
+
+// PortForwardListDTO struct defines the structure of the response DTO for port forwards
+type PortForwardListDTO struct {
+	Payload []PortForwardDTO `json:"payload"`
+}
+
+// FirewallRuleDTO defines the structure of a rule allowing traffic to or from the server
+type FirewallRuleDTO struct {
+
+	// Direction is ingress for traffic to the server or egress for traffic from the server
+	Direction string `json:"direction"`
+
+	// Protocol is tcp, udp, icmp or all, defaults to all
+	Protocol string `json:"protocol,omitempty"`
+
+	// PortStart is the first allowed port for tcp and udp, all ports if omitted
+	PortStart int `json:"portStart,omitempty"`
+
+	// PortEnd is the last allowed port, defaults to PortStart
+	PortEnd int `json:"portEnd,omitempty"`
+
+	// CIDR is the remote IPv4 network, defaults to 0.0.0.0/0
+	CIDR string `json:"cidr,omitempty"`
+}
+
+// FirewallDTO defines the structure of the firewall policy of the server
+type FirewallDTO struct {
+
+	// AllowSpoofing disables the built-in anti-spoofing filter. Only the admin
+	// may change it.
+	AllowSpoofing bool `json:"allowSpoofing"`
+
+	// RuleSets are the names of the shared rule sets from the config
+	RuleSets []string `json:"ruleSets"`
+
+	// Rules are the rules of the server
+	Rules []FirewallRuleDTO `json:"rules"`
+}
+
+// VolumeDTO defines the structure of a disk volume of the server
+type VolumeDTO struct {
+
+	// Name is the name of the volume
+	Name string `json:"name"`
+
+	// Device is the target device inside the guest, empty if the volume is detached
+	Device string `json:"device,omitempty"`
+
+	// Size is the virtual size of the volume in bytes
+	Size uint64 `json:"size"`
+
+	// Root is true if this is the boot disk of the server
+	Root bool `json:"root"`
+
+	// Attached is true if the volume is attached to the server
+	Attached bool `json:"attached"`
+}
+
+// StorageDTO defines the structure of the response DTO for the volume storage capacity
+type StorageDTO struct {
+
+	// Type is the type of the storage, either directory or pool
+	Type string `json:"type"`
+
+	// Name is the directory path or the name of the libvirt storage pool
+	Name string `json:"name"`
+
+	// Capacity is the total size of the storage in bytes
+	Capacity uint64 `json:"capacity"`
+
+	// Allocation is the size in use in bytes
+	Allocation uint64 `json:"allocation"`
+
+	// Available is the size available for new volumes in bytes
+	Available uint64 `json:"available"`
+}
+
+// HostDTO defines the structure of the capacity of the host
+type HostDTO struct {
+
+	// CPU is the CPU capacity of the host
+	CPU HostCPUDTO `json:"cpu"`
+
+	// Memory is the memory capacity of the host
+	Memory HostMemoryDTO `json:"memory"`
+
+	// Images is the space of the images directory
+	Images *StorageDTO `json:"images,omitempty"`
+
+	// Volumes is the space of the volume storage
+	Volumes *StorageDTO `json:"volumes,omitempty"`
+
+	// Servers is the number of defined servers
+	Servers int `json:"servers"`
+}
+
+// HostCPUDTO defines the structure of the CPUs of the host
+type HostCPUDTO struct {
+
+	// Model is the model of the CPUs
+	Model string `json:"model"`
+
+	// Count is the number of active CPUs
+	Count uint `json:"count"`
+
+	// Mhz is the frequency of the CPUs in MHz
+	Mhz uint `json:"mhz"`
+
+	// Committed is the sum of the virtual CPUs of the defined servers
+	Committed uint `json:"committed"`
+
+	// Overcommit is how many virtual CPUs there may be per CPU, or zero without a limit
+	Overcommit float64 `json:"overcommit"`
+
+	// Limit is the number of virtual CPUs the servers may have in total, or zero without a limit
+	Limit uint `json:"limit"`
+}
+
+// HostMemoryDTO defines the structure of the memory of the host in bytes
+type HostMemoryDTO struct {
+
+	// Total is the memory of the host
+	Total uint64 `json:"total"`
+
+	// Free is the memory not in use on the host
+	Free uint64 `json:"free"`
+
+	// Committed is the sum of the maximum memory of the defined servers
+	Committed uint64 `json:"committed"`
+
+	// Overcommit is how many times the memory may be committed, or zero without a limit
+	Overcommit float64 `json:"overcommit"`
+
+	// Limit is the memory the servers may have in total, or zero without a limit
+	Limit uint64 `json:"limit"`
+}
+
+// ResetPasswordDTO defines the structure of the request body to reset the
+// password of the user in the server, and the structure of the response
+type ResetPasswordDTO struct {
+
+	// User is the name of the user in the server
+	User string `json:"user,omitempty"`
+
+	// Password is the new password, or generated if empty in the request
+	Password string `json:"password,omitempty"`
+
+	// Method is agent if the change was made, or cloud-init if it is made on the next boot
+	Method string `json:"method,omitempty"`
+}
+
+// AuthorizedKeysDTO defines the structure of the request body to set the SSH
+// keys of the user in the server, and the structure of the response
+type AuthorizedKeysDTO struct {
+
+	// Keys are the public keys in the authorized_keys format
+	Keys []string `json:"keys"`
+
+	// Append adds the keys instead of replacing the existing keys
+	Append bool `json:"append,omitempty"`
+
+	// User is the name of the user in the server
+	User string `json:"user,omitempty"`
+
+	// Method is agent if the change was made, or cloud-init if it is made on the next boot
+	Method string `json:"method,omitempty"`
+}
+
+// FreezeDTO defines the structure of the optional request body to freeze the
+// filesystems of the server, and the structure of the response
+type FreezeDTO struct {
+
+	// Timeout is how many seconds the filesystems stay frozen at most, or the default if zero
+	Timeout int `json:"timeout,omitempty"`
+
+	// Frozen is true if the filesystems are frozen
+	Frozen bool `json:"frozen"`
+}
+
+// StopServerDTO defines the structure of the optional request body to stop or restart a server
+type StopServerDTO struct {
+
+	// Timeout is how many seconds the guest is given to shut down, or the default if zero
+	Timeout int `json:"timeout,omitempty"`
+
+	// Force powers the server off if it did not shut down in time
+	Force bool `json:"force,omitempty"`
+}
+
+// ResizeServerDTO defines the structure of the request body to grow the root disk of the server
+type ResizeServerDTO struct {
+
+	// Size is the new size of the root disk in bytes
+	Size uint64 `json:"size"`
+}
+
+// MigrateServerDTO defines the structure of the request body to move the server to another host
+type MigrateServerDTO struct {
+
+	// Host is the name of the destination host
+	Host string `json:"host"`
+
+	// Mode is live for a running server or offline for a stopped server. It
+	// defaults to the mode the status of the server needs, and must be given
+	// for a server migrated to another host before.
+	Mode string `json:"mode,omitempty"`
+
+	// CopyStorage copies the volumes to the destination in a live migration
+	// when they are not on shared storage
+	CopyStorage bool `json:"copyStorage,omitempty"`
+}
+
+// CreateVolumeDTO defines the structure of the request body to create a new data volume
+type CreateVolumeDTO struct {
+
+	// Name is the name of the volume
+	Name string `json:"name"`
+
+	// Size is the size of the volume in bytes
+	Size uint64 `json:"size"`
 }
 
 // CreateServerDTO defines the structure of the request body to deploy a new server
@@ -76,6 +674,61 @@ type CreateServerDTO struct {
 
 	// Name Optional name of the server
 	Name *string `json:"name,omitempty"`
+
+	// Interfaces Optional network interfaces, otherwise the default interface is used
+	Interfaces []NetworkInterfaceDTO `json:"interfaces,omitempty"`
+
+	// Console Optional type of the graphical console, vnc (default) or spice
+	Console string `json:"console,omitempty"`
+
+	// Project Optional project of the server, otherwise the default project of the user
+	Project string `json:"project,omitempty"`
+}
+
+// ProjectListDTO defines the structure of the projects of the user
+type ProjectListDTO struct {
+	Payload []ProjectDTO `json:"payload"`
+}
+
+// CreateProjectDTO defines the structure of the request body to create a project
+type CreateProjectDTO struct {
+
+	// Name is the name of the project
+	Name string `json:"name"`
+}
+
+// ProjectDTO defines the structure of a project
+type ProjectDTO struct {
+
+	// Name is the name of the project
+	Name string `json:"name"`
+
+	// Role is the role of the user in the project, owner or member
+	Role string `json:"role"`
+
+	// Members are the users of the project
+	Members []ProjectMemberDTO `json:"members"`
+
+	// Servers is the number of servers in the project
+	Servers int `json:"servers"`
+}
+
+// ProjectMemberDTO defines the structure of a member of a project, and the
+// structure of the request body to add a member
+type ProjectMemberDTO struct {
+
+	// Email is the email address of the user
+	Email string `json:"email"`
+
+	// Role is owner or member
+	Role string `json:"role"`
+}
+
+// SendKeysDTO defines the structure of the request body to press keys on the server
+type SendKeysDTO struct {
+
+	// Keys are the names of the keys to press together, e.g. ctrl+alt+delete or alt+sysrq+b
+	Keys string `json:"keys"`
 }
 
 // ServerActionDTO defines the structure of the request body to perform an action on the server
@@ -91,16 +744,75 @@ type ServerListDTO struct {
 	Permissions ServerPermissionDTO `json:"permissions"`
 }
 
-// ServerVncDTO defines an response to open a VNC console
+// ConsoleLogDTO defines the structure of the response DTO for the serial console log
+type ConsoleLogDTO struct {
+
+	// Payload is the last lines of the serial console output
+	Payload []string `json:"payload"`
+}
+
+// ServerSerialDTO defines an response to open a serial console
+type ServerSerialDTO struct {
+
+	// URL is the URL to the bundled terminal page
+	URL string `json:"url"`
+
+	// WS is the path for the websocket interface
+	WS string `json:"ws"`
+
+	// Token is the single use token for the websocket
+	Token string `json:"token"`
+}
+
+// VncSessionDTO defines the structure of an open VNC console session
+type VncSessionDTO struct {
+
+	// ID identifies the session
+	ID string `json:"id"`
+
+	// Owner is the email of the user who opened the session
+	Owner string `json:"owner"`
+
+	// Server is the name of the server
+	Server string `json:"server"`
+
+	// Type is the console protocol, vnc or spice
+	Type string `json:"type"`
+
+	// Created is the time the session was opened
+	Created string `json:"created"`
+
+	// Expires is the time the session is closed at the latest
+	Expires string `json:"expires"`
+
+	// Connected is true if the WebSocket is connected
+	Connected bool `json:"connected"`
+
+	// BytesToServer is the number of bytes sent from the browser to the VNC server
+	BytesToServer uint64 `json:"bytesToServer"`
+
+	// BytesToClient is the number of bytes sent from the VNC server to the browser
+	BytesToClient uint64 `json:"bytesToClient"`
+}
+
+// VncSessionListDTO struct defines the structure of the response DTO for VNC sessions
+type VncSessionListDTO struct {
+	Payload []VncSessionDTO `json:"payload"`
+}
+
+// ServerVncDTO defines an response to open a VNC or SPICE console
 type ServerVncDTO struct {
 
-	// URL is the URL to bundled novnc service
+	// Type is the console protocol, vnc or spice
+	Type string `json:"type,omitempty"`
+
+	// URL is the URL to bundled novnc or spice-html5 service
 	URL string `json:"url"`
 
 	// Path is the path for websocket interface
 	WS string `json:"ws"`
 
-	// Password is the VNC password
+	// Password is the console password
 	Password string `json:"password"`
 
 	// Token is the VNC token
@@ -108,28 +820,52 @@ type ServerVncDTO struct {
 }
 
 type ServerPermissionDTO struct {
-	EnabledActions []ServerAction `json:"enabledActions"`
-	CreateEnabled  bool           `json:"createEnabled"`
-	DeployEnabled  bool           `json:"deployEnabled"`
-	StartEnabled   bool           `json:"startEnabled"`
-	StopEnabled    bool           `json:"stopEnabled"`
-	RestartEnabled bool           `json:"restartEnabled"`
-	DeleteEnabled  bool           `json:"deleteEnabled"`
-	ConsoleEnabled bool           `json:"consoleEnabled"`
+	EnabledActions   []ServerAction `json:"enabledActions"`
+	CreateEnabled    bool           `json:"createEnabled"`
+	DeployEnabled    bool           `json:"deployEnabled"`
+	StartEnabled     bool           `json:"startEnabled"`
+	StopEnabled      bool           `json:"stopEnabled"`
+	RestartEnabled   bool           `json:"restartEnabled"`
+	DeleteEnabled    bool           `json:"deleteEnabled"`
+	ConsoleEnabled   bool           `json:"consoleEnabled"`
+	ResizeEnabled    bool           `json:"resizeEnabled"`
+	VolumeEnabled    bool           `json:"volumeEnabled"`
+	NetworkEnabled   bool           `json:"networkEnabled"`
+	ForceOffEnabled  bool           `json:"forceOffEnabled"`
+	ResetEnabled     bool           `json:"resetEnabled"`
+	PauseEnabled     bool           `json:"pauseEnabled"`
+	ResumeEnabled    bool           `json:"resumeEnabled"`
+	SuspendEnabled   bool           `json:"suspendEnabled"`
+	HibernateEnabled bool           `json:"hibernateEnabled"`
+	RestoreEnabled   bool           `json:"restoreEnabled"`
+	AccessEnabled    bool           `json:"accessEnabled"`
+	MigrateEnabled   bool           `json:"migrateEnabled"`
 }
 
 func NewServerPermissionDTOFromServerActionList(
 	enabledActions []ServerAction,
 ) ServerPermissionDTO {
 	return ServerPermissionDTO{
-		EnabledActions: enabledActions,
-		CreateEnabled:  HasServerAction(enabledActions, CreateServerAction),
-		DeployEnabled:  HasServerAction(enabledActions, DeployServerAction),
-		StartEnabled:   HasServerAction(enabledActions, StartServerAction),
-		StopEnabled:    HasServerAction(enabledActions, StopServerAction),
-		RestartEnabled: HasServerAction(enabledActions, RestartServerAction),
-		DeleteEnabled:  HasServerAction(enabledActions, DeleteServerAction),
-		ConsoleEnabled: HasServerAction(enabledActions, ConsoleServerAction),
+		EnabledActions:   enabledActions,
+		CreateEnabled:    HasServerAction(enabledActions, CreateServerAction),
+		DeployEnabled:    HasServerAction(enabledActions, DeployServerAction),
+		StartEnabled:     HasServerAction(enabledActions, StartServerAction),
+		StopEnabled:      HasServerAction(enabledActions, StopServerAction),
+		RestartEnabled:   HasServerAction(enabledActions, RestartServerAction),
+		DeleteEnabled:    HasServerAction(enabledActions, DeleteServerAction),
+		ConsoleEnabled:   HasServerAction(enabledActions, ConsoleServerAction),
+		ResizeEnabled:    HasServerAction(enabledActions, ResizeServerAction),
+		VolumeEnabled:    HasServerAction(enabledActions, VolumeServerAction),
+		NetworkEnabled:   HasServerAction(enabledActions, NetworkServerAction),
+		ForceOffEnabled:  HasServerAction(enabledActions, ForceOffServerAction),
+		ResetEnabled:     HasServerAction(enabledActions, ResetServerAction),
+		PauseEnabled:     HasServerAction(enabledActions, PauseServerAction),
+		ResumeEnabled:    HasServerAction(enabledActions, ResumeServerAction),
+		SuspendEnabled:   HasServerAction(enabledActions, SuspendServerAction),
+		HibernateEnabled: HasServerAction(enabledActions, HibernateServerAction),
+		RestoreEnabled:   HasServerAction(enabledActions, RestoreServerAction),
+		AccessEnabled:    HasServerAction(enabledActions, AccessServerAction),
+		MigrateEnabled:   HasServerAction(enabledActions, MigrateServerAction),
 	}
 }
 
@@ -137,13 +873,25 @@ func NewServerPermissionDTOFromServerActionCodeList(
 	enabledActions ServerActionCodeList,
 ) ServerPermissionDTO {
 	return ServerPermissionDTO{
-		EnabledActions: enabledActions.ToServerAction(),
-		CreateEnabled:  HasServerActionCode(enabledActions, CreateServerActionCode),
-		DeployEnabled:  HasServerActionCode(enabledActions, DeployServerActionCode),
-		StartEnabled:   HasServerActionCode(enabledActions, StartServerActionCode),
-		StopEnabled:    HasServerActionCode(enabledActions, StopServerActionCode),
-		RestartEnabled: HasServerActionCode(enabledActions, RestartServerActionCode),
-		DeleteEnabled:  HasServerActionCode(enabledActions, DeleteServerActionCode),
-		ConsoleEnabled: HasServerActionCode(enabledActions, ConsoleServerActionCode),
+		EnabledActions:   enabledActions.ToServerAction(),
+		CreateEnabled:    HasServerActionCode(enabledActions, CreateServerActionCode),
+		DeployEnabled:    HasServerActionCode(enabledActions, DeployServerActionCode),
+		StartEnabled:     HasServerActionCode(enabledActions, StartServerActionCode),
+		StopEnabled:      HasServerActionCode(enabledActions, StopServerActionCode),
+		RestartEnabled:   HasServerActionCode(enabledActions, RestartServerActionCode),
+		DeleteEnabled:    HasServerActionCode(enabledActions, DeleteServerActionCode),
+		ConsoleEnabled:   HasServerActionCode(enabledActions, ConsoleServerActionCode),
+		ResizeEnabled:    HasServerActionCode(enabledActions, ResizeServerActionCode),
+		VolumeEnabled:    HasServerActionCode(enabledActions, VolumeServerActionCode),
+		NetworkEnabled:   HasServerActionCode(enabledActions, NetworkServerActionCode),
+		ForceOffEnabled:  HasServerActionCode(enabledActions, ForceOffServerActionCode),
+		ResetEnabled:     HasServerActionCode(enabledActions, ResetServerActionCode),
+		PauseEnabled:     HasServerActionCode(enabledActions, PauseServerActionCode),
+		ResumeEnabled:    HasServerActionCode(enabledActions, ResumeServerActionCode),
+		SuspendEnabled:   HasServerActionCode(enabledActions, SuspendServerActionCode),
+		HibernateEnabled: HasServerActionCode(enabledActions, HibernateServerActionCode),
+		RestoreEnabled:   HasServerActionCode(enabledActions, RestoreServerActionCode),
+		AccessEnabled:    HasServerActionCode(enabledActions, AccessServerActionCode),
+		MigrateEnabled:   HasServerActionCode(enabledActions, MigrateServerActionCode),
 	}
 }
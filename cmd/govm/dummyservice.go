@@ -4,17 +4,36 @@ package main
 
 import (
 	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"io"
 	"log"
+	"net"
 	"time"
 )
 
 type DummyService struct {
 	servers        []*ServerModel
+	networks       NetworkModelList
+	firewalls      map[string]*FirewallModel
 	enabledActions []ServerActionCode
+	operations     *OperationManager
+	freezes        *FreezeTimers
+	started        time.Time
+	overcommit     OvercommitRatios
 }
 
-func NewDummyService() *DummyService {
-	return &DummyService{}
+func NewDummyService(enabledActions []ServerActionCode, overcommit OvercommitRatios) *DummyService {
+	return &DummyService{
+		networks:       NetworkModelList{NewNetworkModel(NetworkInterfaceType, "default", "virbr0", true)},
+		firewalls:      make(map[string]*FirewallModel),
+		enabledActions: enabledActions,
+		operations:     NewOperationManager(),
+		freezes:        NewFreezeTimers(),
+		started:        time.Now(),
+		overcommit:     overcommit,
+	}
 }
 
 func (s *DummyService) Start() error {
@@ -29,33 +48,274 @@ func (s *DummyService) Stop() error {
 
 func (s *DummyService) AddServer(
 	name string,
+	options *ServerOptions,
 ) (*ServerModel, error) {
+	host, err := s.GetHost()
+	if err != nil {
+		return nil, fmt.Errorf("AddServer: %v", err)
+	}
+	err = host.CheckCapacity(DefaultServerCPUs, DefaultServerMemory)
+	if err != nil {
+		return nil, fmt.Errorf("AddServer: %w", err)
+	}
 	item := NewServerModel(name, UninitializedServerStatusCode, s.enabledActions)
+	item.CPUs = DefaultServerCPUs
+	item.Memory = DefaultServerMemory
+	item.Volumes = VolumeModelList{NewVolumeModel(RootDiskDevice, RootDiskDevice, dummyImageSize, true)}
+	item.Interfaces = options.Interfaces
+	item.Console = options.Console
+	if item.Console == "" {
+		item.Console = VncConsoleType
+	}
+	if len(item.Interfaces) == 0 {
+		item.Interfaces = NetworkInterfaceModelList{NewNetworkInterfaceModel(NetworkInterfaceType, "default", "")}
+	}
+	for _, nic := range item.Interfaces {
+		if nic.MAC == "" {
+			mac, err := generateRandomMAC()
+			if err != nil {
+				return nil, fmt.Errorf("AddServer: failed to generate new mac: %v", err)
+			}
+			nic.MAC = mac
+		}
+	}
 	s.servers = append(s.servers, item)
 	return item, nil
 }
 
 func (s *DummyService) GetServerList() ([]*ServerModel, error) {
+	for _, server := range s.servers {
+		server.Operation = s.operations.Find(server.Name)
+		server.GuestAgent = server.Status == StartedServerStatusCode
+		server.Frozen = s.freezes.IsFrozen(server.Name)
+	}
 	return s.servers, nil
 }
 
+// GetServerStats returns usage which grows while the servers are running
+func (s *DummyService) GetServerStats() ([]*ServerStatsModel, error) {
+	uptime := uint64(time.Since(s.started).Seconds())
+	list := make([]*ServerStatsModel, 0, len(s.servers))
+	for _, server := range s.servers {
+		item := &ServerStatsModel{
+			Name:          server.Name,
+			Status:        server.Status,
+			MemoryMaximum: 2 * 1024 * 1024 * 1024,
+		}
+		if server.Status == StartedServerStatusCode {
+			item.CPUTime = uptime * 50 * 1000 * 1000
+			item.MemoryCurrent = item.MemoryMaximum
+			item.MemoryUsable = item.MemoryMaximum / 2
+			item.MemoryRSS = item.MemoryMaximum / 4
+			item.Disks = []DiskStatsModel{{
+				Device:        RootDiskDevice,
+				ReadBytes:     uptime * 64 * 1024,
+				WriteBytes:    uptime * 16 * 1024,
+				ReadRequests:  uptime * 16,
+				WriteRequests: uptime * 4,
+			}}
+			for i := range server.Interfaces {
+				item.Interfaces = append(item.Interfaces, InterfaceStatsModel{
+					Device:    fmt.Sprintf("vnet%d", i),
+					RxBytes:   uptime * 2048,
+					TxBytes:   uptime * 1024,
+					RxPackets: uptime * 4,
+					TxPackets: uptime * 2,
+				})
+			}
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
 func (s *DummyService) FindServer(name string) (*ServerModel, error) {
 	for _, state := range s.servers {
 		if state.Name == name {
+			state.Operation = s.operations.Find(name)
+			state.GuestAgent = state.Status == StartedServerStatusCode
+			state.Frozen = s.freezes.IsFrozen(name)
 			return state, nil
 		}
 	}
 	return nil, nil
 }
 
-func (s *DummyService) GetVNC(name string) (string, error) {
-	return "127.0.0.1:5900", nil
+func (s *DummyService) GetConsole(name string) (*ConsoleModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("GetConsole: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("GetConsole: failed to find the server: %s", name)
+	}
+	return NewConsoleModel(server.Console, "tcp", "127.0.0.1:5900"), nil
+}
+
+func (s *DummyService) SetConsolePassword(name, password string) error {
+	return nil
+}
+
+// GetScreenshot returns a screen filled with a color derived from the name
+func (s *DummyService) GetScreenshot(name string) (image.Image, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("GetScreenshot: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("GetScreenshot: failed to find the server: %s", name)
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	sum := hash.Sum32()
+	fill := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 0xff}
+	img := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = fill.R, fill.G, fill.B, fill.A
+	}
+	return img, nil
 }
 
-func (s *DummyService) SetVNCPassword(name, password string) error {
+// GetGuestInfo returns made up information of a running server
+func (s *DummyService) GetGuestInfo(name string) (*GuestInfoModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("GetGuestInfo: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("GetGuestInfo: failed to find the server: %s", name)
+	}
+	if server.Status != StartedServerStatusCode {
+		return nil, fmt.Errorf("GetGuestInfo: %w", ErrGuestAgentUnavailable)
+	}
+	item := &GuestInfoModel{
+		Hostname: name,
+		OS: GuestOSModel{
+			ID:            "debian",
+			Name:          "Debian GNU/Linux",
+			PrettyName:    "Debian GNU/Linux 12 (bookworm)",
+			Version:       "12 (bookworm)",
+			KernelRelease: "6.1.0-28-cloud-amd64",
+			Machine:       "x86_64",
+		},
+		Timezone: "UTC",
+		FileSystems: []GuestFileSystemModel{{
+			MountPoint: "/",
+			Device:     "vda1",
+			Type:       "ext4",
+			TotalBytes: 10 * 1024 * 1024 * 1024,
+			UsedBytes:  1536 * 1024 * 1024,
+		}},
+		Updated: time.Now(),
+	}
+	for i, nic := range server.Interfaces {
+		address := nic.Address
+		if address == "" {
+			address = fmt.Sprintf("192.168.122.%d/24", 10+i)
+		}
+		item.Interfaces = append(item.Interfaces, GuestInterfaceModel{
+			Name:      fmt.Sprintf("enp%ds0", i+1),
+			MAC:       nic.MAC,
+			Addresses: []string{address},
+		})
+	}
+	return item, nil
+}
+
+// ResetPassword uses the guest agent of a running server, otherwise cloud-init
+func (s *DummyService) ResetPassword(name, password string) (string, error) {
+	return s.getAccessMethod("ResetPassword", name)
+}
+
+// SetAuthorizedKeys uses the guest agent of a running server, otherwise cloud-init
+func (s *DummyService) SetAuthorizedKeys(name string, keys []string, appendKeys bool) (string, error) {
+	return s.getAccessMethod("SetAuthorizedKeys", name)
+}
+
+func (s *DummyService) getAccessMethod(method, name string) (string, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to find the server: error: %v", method, err)
+	}
+	if server == nil {
+		return "", fmt.Errorf("%s: failed to find the server: %s", method, name)
+	}
+	if server.Status == StartedServerStatusCode {
+		return GuestAgentAccessMethod, nil
+	}
+	return CloudInitAccessMethod, nil
+}
+
+// FreezeFileSystems marks a running server frozen until thawed or the time is up
+func (s *DummyService) FreezeFileSystems(name string, duration time.Duration) error {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return fmt.Errorf("FreezeFileSystems: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return fmt.Errorf("FreezeFileSystems: failed to find the server: %s", name)
+	}
+	if !server.GuestAgent {
+		return fmt.Errorf("FreezeFileSystems: %w", ErrGuestAgentUnavailable)
+	}
+	started := s.freezes.StartUnlessFrozen(name, duration, func() {
+		log.Printf("FreezeFileSystems: Thawing %s after %v", name, duration)
+	})
+	if !started {
+		return fmt.Errorf("FreezeFileSystems: %w", ErrAlreadyFrozen)
+	}
 	return nil
 }
 
+func (s *DummyService) ThawFileSystems(name string) error {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return fmt.Errorf("ThawFileSystems: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return fmt.Errorf("ThawFileSystems: failed to find the server: %s", name)
+	}
+	s.freezes.Stop(name)
+	return nil
+}
+
+// OpenSerialConsole returns a console which echoes the input back
+func (s *DummyService) OpenSerialConsole(name string) (io.ReadWriteCloser, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("OpenSerialConsole: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("OpenSerialConsole: failed to find the server: not found")
+	}
+	client, console := net.Pipe()
+	go func() {
+		defer console.Close()
+		console.Write([]byte("Dummy serial console of " + name + "\r\n"))
+		io.Copy(console, console)
+	}()
+	return client, nil
+}
+
+func (s *DummyService) GetConsoleLog(name string, tail int) ([]string, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("GetConsoleLog: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("GetConsoleLog: failed to find the server: not found")
+	}
+	lines := []string{
+		"Booting dummy server " + name,
+		"cloud-init: modules:final finished",
+		name + " login:",
+	}
+	if len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return lines, nil
+}
+
 func (s *DummyService) DeployServer(name string) (*ServerModel, error) {
 	server, err := s.FindServer(name)
 	if err != nil {
@@ -90,7 +350,7 @@ func (s *DummyService) StartServer(name string) (*ServerModel, error) {
 	return server, nil
 }
 
-func (s *DummyService) StopServer(name string) (*ServerModel, error) {
+func (s *DummyService) StopServer(name string, options StopOptions) (*ServerModel, error) {
 	server, err := s.FindServer(name)
 	if err != nil {
 		return nil, fmt.Errorf("StopServer: failed to find the server: error: %v", err)
@@ -100,14 +360,20 @@ func (s *DummyService) StopServer(name string) (*ServerModel, error) {
 	}
 	if server.Status == StartedServerStatusCode {
 		server.Status = StoppingServerStatusCode
-		time.AfterFunc(3*time.Second, func() {
+		_, err = s.operations.Start(name, StopServerActionCode, StoppingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+			time.Sleep(3 * time.Second)
 			server.Status = StoppedServerStatusCode
+			return nil
 		})
+		if err != nil {
+			server.Status = StartedServerStatusCode
+			return nil, fmt.Errorf("StopServer: %w", err)
+		}
 	}
-	return server, nil
+	return s.FindServer(name)
 }
 
-func (s *DummyService) RestartServer(name string) (*ServerModel, error) {
+func (s *DummyService) RestartServer(name string, options StopOptions) (*ServerModel, error) {
 	server, err := s.FindServer(name)
 	if err != nil {
 		return nil, fmt.Errorf("RestartServer: failed to find the server: error: %v", err)
@@ -115,21 +381,142 @@ func (s *DummyService) RestartServer(name string) (*ServerModel, error) {
 	if server == nil {
 		return nil, fmt.Errorf("RestartServer: failed to find the server: not found")
 	}
-	if server.Status == StoppedServerStatusCode {
-		server.Status = StartingServerStatusCode
-		time.AfterFunc(3*time.Second, func() {
+	if server.Status == StoppedServerStatusCode || server.Status == StartedServerStatusCode {
+		previous := server.Status
+		if previous == StartedServerStatusCode {
+			server.Status = StoppingServerStatusCode
+		}
+		_, err = s.operations.Start(name, RestartServerActionCode, server.Status, func(setStatus func(status ServerStatusCode)) error {
+			if previous == StartedServerStatusCode {
+				time.Sleep(3 * time.Second)
+			}
+			server.Status = StartingServerStatusCode
+			time.Sleep(3 * time.Second)
 			server.Status = StartedServerStatusCode
+			return nil
 		})
-	} else if server.Status == StartedServerStatusCode {
-		server.Status = StoppingServerStatusCode
-		time.AfterFunc(3*time.Second, func() {
-			server.Status = StartingServerStatusCode
-			time.AfterFunc(3*time.Second, func() {
-				server.Status = StartedServerStatusCode
-			})
+		if err != nil {
+			server.Status = previous
+			return nil, fmt.Errorf("RestartServer: %w", err)
+		}
+	}
+	return s.FindServer(name)
+}
+
+func (s *DummyService) ForceOffServer(name string) (*ServerModel, error) {
+	return s.changeServerStatus("ForceOffServer", name, StoppedServerStatusCode,
+		StartedServerStatusCode, StoppingServerStatusCode, BlockedServerStatusCode, PausedServerStatusCode,
+		SuspendedServerStatusCode, CrashedServerStatusCode)
+}
+
+func (s *DummyService) ResetServer(name string) (*ServerModel, error) {
+	return s.changeServerStatus("ResetServer", name, StartedServerStatusCode,
+		StartedServerStatusCode, BlockedServerStatusCode)
+}
+
+func (s *DummyService) PauseServer(name string) (*ServerModel, error) {
+	return s.changeServerStatus("PauseServer", name, PausedServerStatusCode, StartedServerStatusCode)
+}
+
+func (s *DummyService) ResumeServer(name string) (*ServerModel, error) {
+	return s.changeServerStatus("ResumeServer", name, StartedServerStatusCode,
+		PausedServerStatusCode, SuspendedServerStatusCode)
+}
+
+func (s *DummyService) SuspendServer(name string) (*ServerModel, error) {
+	return s.changeServerStatus("SuspendServer", name, SuspendedServerStatusCode, StartedServerStatusCode)
+}
+
+func (s *DummyService) HibernateServer(name string) (*ServerModel, error) {
+	return s.changeServerStatus("HibernateServer", name, HibernatedServerStatusCode,
+		StartedServerStatusCode, PausedServerStatusCode)
+}
+
+func (s *DummyService) RestoreServer(name string) (*ServerModel, error) {
+	return s.changeServerStatus("RestoreServer", name, StartedServerStatusCode, HibernatedServerStatusCode)
+}
+
+// MigrateServer pretends to copy the server to the destination and removes
+// it from this host. A server on another host stays there.
+func (s *DummyService) MigrateServer(name string, options MigrateOptions) (*ServerModel, error) {
+	if options.SourceURI != "" {
+		_, err := s.operations.Start(name, MigrateServerActionCode, MigratingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+			if options.Migrated != nil {
+				options.Migrated()
+			}
+			return nil
 		})
+		if err != nil {
+			return nil, fmt.Errorf("MigrateServer: %w", err)
+		}
+		model := NewServerModel(name, MovedServerStatusCode, s.enabledActions)
+		s.operations.Apply(model)
+		return model, nil
 	}
-	return server, nil
+
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("MigrateServer: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("MigrateServer: failed to find the server: not found")
+	}
+	previous := server.Status
+	server.Status = MigratingServerStatusCode
+	_, err = s.operations.Start(name, MigrateServerActionCode, MigratingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+		for progress := 10; progress < 100; progress += 10 {
+			time.Sleep(500 * time.Millisecond)
+			s.operations.SetProgress(name, progress)
+		}
+		s.operations.SetProgress(name, 100)
+		err := s.removeServer(name)
+		if err != nil {
+			return err
+		}
+		if options.Migrated != nil {
+			options.Migrated()
+		}
+		return nil
+	})
+	if err != nil {
+		server.Status = previous
+		return nil, fmt.Errorf("MigrateServer: %w", err)
+	}
+	return s.FindServer(name)
+}
+
+func (s *DummyService) SendKeys(name string, keys []uint) error {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return fmt.Errorf("SendKeys: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return fmt.Errorf("SendKeys: failed to find the server: not found")
+	}
+	if server.Status != StartedServerStatusCode {
+		return fmt.Errorf("SendKeys: server is not running")
+	}
+	log.Printf("SendKeys: Sent keys %v to %s", keys, name)
+	return nil
+}
+
+// changeServerStatus changes the status of the server immediately if it has
+// one of the allowed statuses
+func (s *DummyService) changeServerStatus(method, name string, status ServerStatusCode, allowed ...ServerStatusCode) (*ServerModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to find the server: error: %v", method, err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("%s: failed to find the server: not found", method)
+	}
+	for _, item := range allowed {
+		if server.Status == item {
+			server.Status = status
+			return server, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: not possible when the server is %s", method, server.Status)
 }
 
 func (s *DummyService) DeleteServer(name string) (*ServerModel, error) {
@@ -140,9 +527,10 @@ func (s *DummyService) DeleteServer(name string) (*ServerModel, error) {
 	if server == nil {
 		return nil, fmt.Errorf("DeleteServer: failed to find the server: not found")
 	}
-	if server.Status == StoppedServerStatusCode || server.Status == UninitializedServerStatusCode {
+	if server.Status == StoppedServerStatusCode || server.Status == UninitializedServerStatusCode || server.Status == HibernatedServerStatusCode {
 		server.Status = DeletingServerStatusCode
 		time.AfterFunc(3*time.Second, func() {
+			s.operations.Remove(name)
 			err := s.removeServer(name)
 			if err != nil {
 				log.Printf("ERROR: failed to remove server: %s: %v", name, err)
@@ -152,6 +540,257 @@ func (s *DummyService) DeleteServer(name string) (*ServerModel, error) {
 	return server, nil
 }
 
+func (s *DummyService) ResizeServer(name string, size uint64) (*ServerModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("ResizeServer: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("ResizeServer: failed to find the server: not found")
+	}
+	root := server.Volumes.findByName(RootDiskDevice)
+	if root == nil {
+		return nil, fmt.Errorf("ResizeServer: no root disk")
+	}
+	if err := checkDiskGrows(root.Size, size); err != nil {
+		return nil, fmt.Errorf("ResizeServer: %v", err)
+	}
+	root.Size = size
+	return server, nil
+}
+
+func (s *DummyService) AddVolume(name, volume string, size uint64) (*ServerModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("AddVolume: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("AddVolume: failed to find the server: not found")
+	}
+	if server.Volumes.findByName(volume) != nil {
+		return nil, fmt.Errorf("AddVolume: volume exists already: %s", volume)
+	}
+	server.Volumes = append(server.Volumes, NewVolumeModel(volume, "", size, false))
+	return s.AttachVolume(name, volume)
+}
+
+func (s *DummyService) AttachVolume(name, volume string) (*ServerModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("AttachVolume: failed to find the server: not found")
+	}
+	item := server.Volumes.findByName(volume)
+	if item == nil {
+		return nil, fmt.Errorf("AttachVolume: volume not found: %s", volume)
+	}
+	if item.IsAttached() {
+		return nil, fmt.Errorf("AttachVolume: volume is already attached: %s", volume)
+	}
+	var usedDevices []string
+	for _, v := range server.Volumes {
+		usedDevices = append(usedDevices, v.Device)
+	}
+	item.Device, err = findFreeDiskDevice(usedDevices)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: %v", err)
+	}
+	return server, nil
+}
+
+func (s *DummyService) DetachVolume(name, volume string) (*ServerModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("DetachVolume: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("DetachVolume: failed to find the server: not found")
+	}
+	item := server.Volumes.findByName(volume)
+	if item == nil || item.Root || !item.IsAttached() {
+		return nil, fmt.Errorf("DetachVolume: volume is not attached: %s", volume)
+	}
+	item.Device = ""
+	return server, nil
+}
+
+func (s *DummyService) DeleteVolume(name, volume string) (*ServerModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteVolume: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("DeleteVolume: failed to find the server: not found")
+	}
+	for i, item := range server.Volumes {
+		if item.Name == volume && !item.Root {
+			if item.IsAttached() {
+				return nil, fmt.Errorf("DeleteVolume: volume must be detached first: %s", volume)
+			}
+			server.Volumes = append(server.Volumes[:i], server.Volumes[i+1:]...)
+			return server, nil
+		}
+	}
+	return nil, fmt.Errorf("DeleteVolume: volume not found: %s", volume)
+}
+
+// dummyImageSize is the size of the root disk of new servers
+const dummyImageSize uint64 = 10 * 1024 * 1024 * 1024
+
+func (s *DummyService) GetImageSize() (uint64, error) {
+	return dummyImageSize, nil
+}
+
+func (s *DummyService) GetStorage() (*StorageModel, error) {
+	var allocation uint64
+	for _, server := range s.servers {
+		allocation += server.Volumes.TotalSize()
+	}
+	const capacity uint64 = 1024 * 1024 * 1024 * 1024
+	var available uint64
+	if allocation < capacity {
+		available = capacity - allocation
+	}
+	return &StorageModel{
+		Type:       DirectoryStorageType,
+		Name:       "dummy",
+		Capacity:   capacity,
+		Allocation: allocation,
+		Available:  available,
+	}, nil
+}
+
+func (s *DummyService) GetHost() (*HostModel, error) {
+	volumes, err := s.GetStorage()
+	if err != nil {
+		return nil, fmt.Errorf("GetHost: %v", err)
+	}
+	const memory uint64 = 8 * 1024 * 1024 * 1024
+	const imagesCapacity uint64 = 100 * 1024 * 1024 * 1024
+	const imagesAllocation uint64 = 2 * 1024 * 1024 * 1024
+	return &HostModel{
+		CPUModel:   "x86_64",
+		CPUs:       4,
+		CPUMhz:     2400,
+		Memory:     memory,
+		FreeMemory: memory / 2,
+		Images: &StorageModel{
+			Type:       DirectoryStorageType,
+			Name:       "dummy-images",
+			Capacity:   imagesCapacity,
+			Allocation: imagesAllocation,
+			Available:  imagesCapacity - imagesAllocation,
+		},
+		Volumes:         volumes,
+		Servers:         len(s.servers),
+		CommittedCPUs:   uint(len(s.servers)) * DefaultServerCPUs,
+		CommittedMemory: uint64(len(s.servers)) * DefaultServerMemory,
+		Overcommit:      s.overcommit,
+	}, nil
+}
+
+func (s *DummyService) GetNetworkList() (NetworkModelList, error) {
+	return s.networks, nil
+}
+
+func (s *DummyService) FindNetwork(name string) (*NetworkModel, error) {
+	return s.networks.find(NetworkInterfaceType, name), nil
+}
+
+func (s *DummyService) CreateNetwork(network *NetworkModel) (*NetworkModel, error) {
+	if s.networks.find(NetworkInterfaceType, network.Source) != nil {
+		return nil, fmt.Errorf("CreateNetwork: network exists: %s", network.Source)
+	}
+	network.Active = true
+	s.networks = append(s.networks, network)
+	return network, nil
+}
+
+func (s *DummyService) StartNetwork(name string) (*NetworkModel, error) {
+	network := s.networks.find(NetworkInterfaceType, name)
+	if network == nil {
+		return nil, fmt.Errorf("StartNetwork: network not found: %s", name)
+	}
+	network.Active = true
+	return network, nil
+}
+
+func (s *DummyService) StopNetwork(name string) (*NetworkModel, error) {
+	network := s.networks.find(NetworkInterfaceType, name)
+	if network == nil {
+		return nil, fmt.Errorf("StopNetwork: network not found: %s", name)
+	}
+	network.Active = false
+	return network, nil
+}
+
+func (s *DummyService) DeleteNetwork(name string) error {
+	for i, network := range s.networks {
+		if network.Type == NetworkInterfaceType && network.Source == name {
+			s.networks = append(s.networks[:i], s.networks[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("DeleteNetwork: network not found: %s", name)
+}
+
+func (s *DummyService) AttachInterface(name string, nic *NetworkInterfaceModel) (*ServerModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("AttachInterface: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("AttachInterface: failed to find the server: not found")
+	}
+	if nic.MAC == "" {
+		nic.MAC, err = generateRandomMAC()
+		if err != nil {
+			return nil, fmt.Errorf("AttachInterface: failed to generate new mac: %v", err)
+		}
+	}
+	server.Interfaces = append(server.Interfaces, nic)
+	return server, nil
+}
+
+func (s *DummyService) DetachInterface(name, mac string) (*ServerModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("DetachInterface: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("DetachInterface: failed to find the server: not found")
+	}
+	for i, nic := range server.Interfaces {
+		if nic.MAC == mac {
+			server.Interfaces = append(server.Interfaces[:i], server.Interfaces[i+1:]...)
+			return server, nil
+		}
+	}
+	return nil, fmt.Errorf("DetachInterface: interface not found: %s", mac)
+}
+
+func (s *DummyService) GetFirewall(name string) (*FirewallModel, error) {
+	firewall, ok := s.firewalls[name]
+	if !ok {
+		return &FirewallModel{}, nil
+	}
+	return firewall, nil
+}
+
+func (s *DummyService) SetFirewall(name string, firewall *FirewallModel, ruleSets []*FirewallRuleSetModel) (*FirewallModel, error) {
+	server, err := s.FindServer(name)
+	if err != nil {
+		return nil, fmt.Errorf("SetFirewall: failed to find the server: error: %v", err)
+	}
+	if server == nil {
+		return nil, fmt.Errorf("SetFirewall: failed to find the server: not found")
+	}
+	s.firewalls[name] = firewall
+	return firewall, nil
+}
+
 func (s *DummyService) removeServer(name string) error {
 	for i, server := range s.servers {
 		if server.Name == name {
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func parseIntEnv(key string, defaultValue int) int {
@@ -20,6 +21,18 @@ func parseIntEnv(key string, defaultValue int) int {
 	return result
 }
 
+func parseFloatEnv(key string, defaultValue float64) float64 {
+	str := os.Getenv(key)
+	if str == "" {
+		return defaultValue
+	}
+	result, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return result
+}
+
 func parseStringEnv(key string, defaultValue string) string {
 	str := os.Getenv(key)
 	if str == "" {
@@ -47,3 +60,15 @@ func parseBooleanEnv(key string, defaultValue bool) bool {
 		return false
 	}
 }
+
+func parseDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	str := os.Getenv(key)
+	if str == "" {
+		return defaultValue
+	}
+	result, err := time.ParseDuration(str)
+	if err != nil {
+		return defaultValue
+	}
+	return result
+}
@@ -24,4 +24,57 @@ const (
 	ServerExistsAlreadyInConfig     = "server-exists-already-in-config"
 	LimitParseError                 = "limit-parse-failed"
 	TypeParseError                  = "type-parse-failed"
+	IllegalVolumeNameError          = "illegal-volume-name-error"
+	IllegalSizeError                = "illegal-size-error"
+	VolumeExistsError               = "volume-exists"
+	VolumeNotFoundError             = "volume-not-found"
+	IllegalInterfaceError           = "illegal-interface-error"
+	NetworkNotAllowedError          = "network-not-allowed"
+	InterfaceNotFoundError          = "interface-not-found"
+	ForbiddenError                  = "forbidden"
+	IllegalNetworkError             = "illegal-network-error"
+	NetworkExistsError              = "network-exists"
+	NetworkNotFoundError            = "network-not-found"
+	PortForwardDisabledError        = "port-forward-disabled"
+	IllegalPortForwardError         = "illegal-port-forward-error"
+	PortForwardExistsError          = "port-forward-exists"
+	PortForwardNotFoundError        = "port-forward-not-found"
+	PortAllocationError             = "port-allocation-error"
+	GuestAddressNotFoundError       = "guest-address-not-found"
+	IllegalFirewallError            = "illegal-firewall-error"
+	RuleSetNotFoundError            = "rule-set-not-found"
+	IllegalRuleSetError             = "illegal-rule-set-error"
+	SerialConsoleDisabledError      = "serial-console-disabled"
+	SerialGenerateTokenError        = "serial-generate-token-error"
+	SerialConsoleOpenError          = "serial-console-open-error"
+	TailParseError                  = "tail-parse-failed"
+	VncSessionLimitError            = "vnc-session-limit"
+	VncSessionNotFoundError         = "vnc-session-not-found"
+	IllegalConsoleTypeError         = "illegal-console-type"
+	ScreenshotDisabledError         = "screenshot-disabled"
+	ScreenshotError                 = "screenshot-error"
+	ThumbnailNotFoundError          = "thumbnail-not-found"
+	ActionNotAvailableError         = "action-not-available"
+	IllegalKeysError                = "illegal-keys"
+	IllegalTimeoutError             = "illegal-timeout"
+	OperationInProgressError        = "operation-in-progress"
+	GuestAgentUnavailableError      = "guest-agent-unavailable"
+	IllegalPasswordError            = "illegal-password"
+	IllegalAuthorizedKeysError      = "illegal-authorized-keys"
+	FreezeError                     = "freeze-error"
+	AlreadyFrozenError              = "already-frozen"
+	IllegalRangeError               = "illegal-range"
+	IllegalStepError                = "illegal-step"
+	MetricsHistoryDisabledError     = "metrics-history-disabled"
+	InsufficientCapacityError       = "insufficient-capacity"
+	AddressInUseError               = "address-in-use"
+	QuotaExceededError              = "quota-exceeded"
+	ProjectNotFoundError            = "project-not-found"
+	IllegalEmailError               = "illegal-email"
+	IllegalRoleError                = "illegal-role"
+	LastOwnerError                  = "last-owner"
+	ProjectExistsError              = "project-exists"
+	ProjectNotEmptyError            = "project-not-empty"
+	HostNotFoundError               = "host-not-found"
+	IllegalMigrationModeError       = "illegal-migration-mode"
 )
@@ -0,0 +1,159 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	IngressDirection = "ingress"
+	EgressDirection  = "egress"
+	IcmpProtocol     = "icmp"
+	AllProtocol      = "all"
+)
+
+// FirewallRuleModel This is the data model of a rule allowing traffic to or from the server
+type FirewallRuleModel struct {
+
+	// Direction ingress for traffic to the server, egress for traffic from the server
+	Direction string
+
+	// Protocol one of tcp, udp, icmp or all
+	Protocol string
+
+	// PortStart the first allowed port on the server for ingress or on the remote for egress, 0 for all
+	PortStart int
+
+	// PortEnd the last allowed port, the same as PortStart for a single port
+	PortEnd int
+
+	// CIDR the remote IPv4 network
+	CIDR string
+}
+
+// FirewallModel This is the data model of the firewall policy of the server.
+// Without rules all traffic is allowed. With rules, ingress traffic is denied
+// unless a rule allows it, and egress traffic is denied too if there are any
+// egress rules.
+type FirewallModel struct {
+
+	// AllowSpoofing disables the built-in anti-spoofing (clean-traffic) filter
+	AllowSpoofing bool
+
+	// RuleSets the names of the shared rule sets from the config
+	RuleSets []string
+
+	// Rules the rules of the server
+	Rules FirewallRuleModelList
+}
+
+// FirewallRuleSetModel This is the data model of a named set of rules shared by servers
+type FirewallRuleSetModel struct {
+	Name  string
+	Rules FirewallRuleModelList
+}
+
+// NewFirewallRuleModel validates the rule and returns a new model
+func NewFirewallRuleModel(direction, protocol string, portStart, portEnd int, cidr string) (*FirewallRuleModel, error) {
+	if direction != IngressDirection && direction != EgressDirection {
+		return nil, fmt.Errorf("unknown direction: %s", direction)
+	}
+	if protocol == "" {
+		protocol = AllProtocol
+	}
+	switch protocol {
+	case TCPProtocol, UDPProtocol:
+		if portEnd == 0 {
+			portEnd = portStart
+		}
+		if portStart < 0 || portEnd > 65535 || portStart > portEnd {
+			return nil, fmt.Errorf("illegal port range: %d-%d", portStart, portEnd)
+		}
+	case IcmpProtocol, AllProtocol:
+		if portStart != 0 || portEnd != 0 {
+			return nil, fmt.Errorf("ports are not supported for %s", protocol)
+		}
+	default:
+		return nil, fmt.Errorf("unknown protocol: %s", protocol)
+	}
+	if cidr == "" {
+		cidr = "0.0.0.0/0"
+	}
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return nil, fmt.Errorf("CIDR must be an IPv4 network: %s", cidr)
+	}
+	return &FirewallRuleModel{
+		Direction: direction,
+		Protocol:  protocol,
+		PortStart: portStart,
+		PortEnd:   portEnd,
+		CIDR:      ipNet.String(),
+	}, nil
+}
+
+// NewFirewallModelFromDTO validates the DTO and returns a new model
+func NewFirewallModelFromDTO(dto FirewallDTO) (*FirewallModel, error) {
+	item := &FirewallModel{
+		AllowSpoofing: dto.AllowSpoofing,
+	}
+	for _, name := range dto.RuleSets {
+		if !ValidateName(name) {
+			return nil, fmt.Errorf("illegal rule set name: %s", name)
+		}
+		item.RuleSets = append(item.RuleSets, name)
+	}
+	for _, rule := range dto.Rules {
+		model, err := NewFirewallRuleModel(rule.Direction, rule.Protocol, rule.PortStart, rule.PortEnd, rule.CIDR)
+		if err != nil {
+			return nil, err
+		}
+		item.Rules = append(item.Rules, model)
+	}
+	return item, nil
+}
+
+func (item *FirewallRuleModel) ToDTO() FirewallRuleDTO {
+	return FirewallRuleDTO{
+		Direction: item.Direction,
+		Protocol:  item.Protocol,
+		PortStart: item.PortStart,
+		PortEnd:   item.PortEnd,
+		CIDR:      item.CIDR,
+	}
+}
+
+// IsRestricted returns true if the policy denies some traffic besides spoofing
+func (item *FirewallModel) IsRestricted() bool {
+	return len(item.Rules) > 0 || len(item.RuleSets) > 0
+}
+
+func (item *FirewallModel) ToDTO() FirewallDTO {
+	return FirewallDTO{
+		AllowSpoofing: item.AllowSpoofing,
+		RuleSets:      item.RuleSets,
+		Rules:         item.Rules.ToDTO(),
+	}
+}
+
+type FirewallRuleModelList []*FirewallRuleModel
+
+// hasDirection returns true if a rule has the direction
+func (list FirewallRuleModelList) hasDirection(direction string) bool {
+	for _, item := range list {
+		if item.Direction == direction {
+			return true
+		}
+	}
+	return false
+}
+
+func (list FirewallRuleModelList) ToDTO() []FirewallRuleDTO {
+	ret := make([]FirewallRuleDTO, len(list))
+	for i, item := range list {
+		ret[i] = item.ToDTO()
+	}
+	return ret
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFirewallFilterXML(t *testing.T) {
+	firewall, err := NewFirewallModelFromDTO(FirewallDTO{
+		RuleSets: []string{"web"},
+		Rules: []FirewallRuleDTO{
+			{Direction: IngressDirection, Protocol: TCPProtocol, PortStart: 22, CIDR: "10.1.2.3/8"},
+			{Direction: EgressDirection, Protocol: UDPProtocol, PortStart: 53},
+			{Direction: IngressDirection, Protocol: IcmpProtocol},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	filterXML := getServerFilterXML("test", firewall, true)
+	for _, expected := range []string{"govm-server-test", "clean-traffic", "govm-set-web", "srcipaddr='10.0.0.0' srcipmask='8'"} {
+		if !strings.Contains(filterXML, expected) {
+			t.Errorf("Expected %s in %s", expected, filterXML)
+		}
+	}
+	if strings.Contains(filterXML, "priority='600'") {
+		t.Errorf("Expected no default egress rule with egress rules")
+	}
+
+	parsed, err := parseFirewallFilterXML(filterXML)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if parsed.AllowSpoofing || len(parsed.RuleSets) != 1 || parsed.RuleSets[0] != "web" {
+		t.Errorf("Unexpected policy: %+v", parsed)
+	}
+	if len(parsed.Rules) != len(firewall.Rules) {
+		t.Fatalf("Expected %d rules, got %d", len(firewall.Rules), len(parsed.Rules))
+	}
+	for i, rule := range parsed.Rules {
+		if *rule != *firewall.Rules[i] {
+			t.Errorf("Expected %+v, got %+v", *firewall.Rules[i], *rule)
+		}
+	}
+
+	invalid := []FirewallRuleDTO{
+		{Direction: "sideways"},
+		{Direction: IngressDirection, Protocol: "gre"},
+		{Direction: IngressDirection, Protocol: IcmpProtocol, PortStart: 1},
+		{Direction: IngressDirection, Protocol: TCPProtocol, PortStart: 80, PortEnd: 20},
+		{Direction: IngressDirection, CIDR: "::/0"},
+	}
+	for _, rule := range invalid {
+		if _, err := NewFirewallModelFromDTO(FirewallDTO{Rules: []FirewallRuleDTO{rule}}); err == nil {
+			t.Errorf("Expected an error for %+v", rule)
+		}
+	}
+}
+
+func TestFilterNamesDoNotOverlap(t *testing.T) {
+	if getServerFilterName("set-web") == getRuleSetFilterName("web") {
+		t.Errorf("the filter of server set-web is the rule set web")
+	}
+	if strings.HasPrefix(getServerFilterName("set-web"), ruleSetFilterPrefix) {
+		t.Errorf("the filter of server set-web is parsed as a rule set")
+	}
+	if strings.HasPrefix(getRuleSetFilterName("web"), serverFilterPrefix) {
+		t.Errorf("the rule set web is parsed as a server filter")
+	}
+}
+
+func TestFilterUUIDPattern(t *testing.T) {
+	xmlDesc := `<filter name='govm-server-web' chain='root'>
+  <uuid>6d2b6b4a-4f2c-4a8e-9d8f-3c1a2b3c4d5e</uuid>
+  <filterref filter='clean-traffic'/>
+</filter>`
+	want := `<filter name='govm-server-web' chain='root'>
+  <filterref filter='clean-traffic'/>
+</filter>`
+	if got := filterUUIDPattern.ReplaceAllString(xmlDesc, ""); got != want {
+		t.Errorf("filter without UUID = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+)
+
+// FirewallRuleSetConfig represents a named set of firewall rules servers may use
+type FirewallRuleSetConfig struct {
+	Name  string                `yaml:"name"`
+	Rules []*FirewallRuleConfig `yaml:"rules"`
+}
+
+// FirewallRuleConfig represents a single rule of a firewall rule set
+type FirewallRuleConfig struct {
+	Direction string `yaml:"direction"`
+	Protocol  string `yaml:"protocol,omitempty"`
+	PortStart int    `yaml:"portStart,omitempty"`
+	PortEnd   int    `yaml:"portEnd,omitempty"`
+	CIDR      string `yaml:"cidr,omitempty"`
+}
+
+// ToModel validates the rules of the set and returns it as a model
+func (item *FirewallRuleSetConfig) ToModel() (*FirewallRuleSetModel, error) {
+	var list FirewallRuleModelList
+	for _, rule := range item.Rules {
+		model, err := NewFirewallRuleModel(rule.Direction, rule.Protocol, rule.PortStart, rule.PortEnd, rule.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("rule set %s: %v", item.Name, err)
+		}
+		list = append(list, model)
+	}
+	return &FirewallRuleSetModel{
+		Name:  item.Name,
+		Rules: list,
+	}, nil
+}
+
+type FirewallRuleSetConfigList []*FirewallRuleSetConfig
+
+// findByName finds a rule set by name and returns it, otherwise nil
+func (list FirewallRuleSetConfigList) findByName(name string) *FirewallRuleSetConfig {
+	for _, item := range list {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrAlreadyFrozen = errors.New("the filesystems of the server are frozen already")
+
+// FreezeTimers keeps track of the servers with frozen filesystems. Each
+// server is thawed when its time is up, so a forgotten or failed backup
+// never leaves the guest frozen.
+type FreezeTimers struct {
+	mutex  sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func NewFreezeTimers() *FreezeTimers {
+	return &FreezeTimers{
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// Start calls thaw after the duration unless stopped before. A previous
+// timer of the server is replaced.
+func (t *FreezeTimers) Start(name string, duration time.Duration, thaw func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.start(name, duration, thaw)
+}
+
+// StartUnlessFrozen starts the timer like Start and returns true, or returns
+// false if the server has a timer already
+func (t *FreezeTimers) StartUnlessFrozen(name string, duration time.Duration, thaw func()) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, exists := t.timers[name]; exists {
+		return false
+	}
+	t.start(name, duration, thaw)
+	return true
+}
+
+// start starts the timer while the mutex is locked
+func (t *FreezeTimers) start(name string, duration time.Duration, thaw func()) {
+	if timer, exists := t.timers[name]; exists {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(duration, func() {
+		t.mutex.Lock()
+		current := t.timers[name] == timer
+		if current {
+			delete(t.timers, name)
+		}
+		t.mutex.Unlock()
+		if current {
+			thaw()
+		}
+	})
+	t.timers[name] = timer
+}
+
+// Stop cancels the timer of the server and returns true if there was one
+func (t *FreezeTimers) Stop(name string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	timer, exists := t.timers[name]
+	if exists {
+		timer.Stop()
+		delete(t.timers, name)
+	}
+	return exists
+}
+
+// IsFrozen returns true if the server has been frozen and not yet thawed
+func (t *FreezeTimers) IsFrozen(name string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, exists := t.timers[name]
+	return exists
+}
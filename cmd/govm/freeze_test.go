@@ -0,0 +1,63 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreezeTimersThawAfterDuration(t *testing.T) {
+	timers := NewFreezeTimers()
+	thawed := make(chan string, 1)
+	timers.Start("server1", 10*time.Millisecond, func() { thawed <- "server1" })
+	if !timers.IsFrozen("server1") {
+		t.Fatalf("server should be frozen")
+	}
+	select {
+	case <-thawed:
+	case <-time.After(time.Second):
+		t.Fatalf("server was not thawed")
+	}
+	if timers.IsFrozen("server1") {
+		t.Errorf("server should not be frozen after thawing")
+	}
+}
+
+func TestFreezeTimersStopAndReplace(t *testing.T) {
+	timers := NewFreezeTimers()
+	thawed := make(chan int, 2)
+	timers.Start("server1", 20*time.Millisecond, func() { thawed <- 1 })
+	timers.Start("server1", time.Hour, func() { thawed <- 2 })
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case n := <-thawed:
+		t.Fatalf("replaced timer %d fired", n)
+	default:
+	}
+	if !timers.Stop("server1") {
+		t.Errorf("Stop should report the frozen server")
+	}
+	if timers.Stop("server1") || timers.IsFrozen("server1") {
+		t.Errorf("server should not be frozen after Stop")
+	}
+}
+
+func TestFreezeTimersStartUnlessFrozen(t *testing.T) {
+	timers := NewFreezeTimers()
+	thawed := make(chan int, 2)
+	if !timers.StartUnlessFrozen("server1", 20*time.Millisecond, func() { thawed <- 1 }) {
+		t.Fatalf("first freeze was refused")
+	}
+	if timers.StartUnlessFrozen("server1", time.Hour, func() { thawed <- 2 }) {
+		t.Fatalf("second freeze replaced the first one")
+	}
+	select {
+	case n := <-thawed:
+		if n != 1 {
+			t.Errorf("timer %d fired, want the first one", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("the first freeze was not thawed")
+	}
+}
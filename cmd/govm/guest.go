@@ -0,0 +1,217 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrGuestAgentUnavailable = errors.New("the guest agent is not available")
+
+// GuestInfoModel is the information the guest agent reports from inside the server
+type GuestInfoModel struct {
+
+	// Hostname is the hostname of the guest
+	Hostname string
+
+	// OS is the operating system of the guest
+	OS GuestOSModel
+
+	// Timezone is the name of the timezone of the guest
+	Timezone string
+
+	// Users are the logged in users
+	Users []GuestUserModel
+
+	// FileSystems are the mounted filesystems
+	FileSystems []GuestFileSystemModel
+
+	// Interfaces are the network interfaces with their addresses
+	Interfaces []GuestInterfaceModel
+
+	// Updated is the time the information was read
+	Updated time.Time
+}
+
+// GuestOSModel is the operating system of the guest
+type GuestOSModel struct {
+	ID            string
+	Name          string
+	PrettyName    string
+	Version       string
+	KernelRelease string
+	Machine       string
+}
+
+// GuestUserModel is a user logged in to the guest
+type GuestUserModel struct {
+	Name      string
+	Domain    string
+	LoginTime time.Time
+}
+
+// GuestFileSystemModel is a filesystem mounted in the guest
+type GuestFileSystemModel struct {
+	MountPoint string
+	Device     string
+	Type       string
+	TotalBytes uint64
+	UsedBytes  uint64
+}
+
+// GuestInterfaceModel is a network interface of the guest
+type GuestInterfaceModel struct {
+	Name string
+	MAC  string
+
+	// Addresses are the IP addresses with the prefix, e.g. 10.0.0.2/24
+	Addresses []string
+}
+
+// GetAddresses returns the addresses of all interfaces
+func (item *GuestInfoModel) GetAddresses() []string {
+	var list []string
+	for _, nic := range item.Interfaces {
+		list = append(list, nic.Addresses...)
+	}
+	return list
+}
+
+func (item *GuestInfoModel) ToDTO() GuestInfoDTO {
+	users := make([]GuestUserDTO, len(item.Users))
+	for i, user := range item.Users {
+		users[i] = GuestUserDTO{
+			Name:   user.Name,
+			Domain: user.Domain,
+		}
+		if !user.LoginTime.IsZero() {
+			users[i].LoginTime = user.LoginTime.UTC().Format(time.RFC3339)
+		}
+	}
+	fileSystems := make([]GuestFileSystemDTO, len(item.FileSystems))
+	for i, fs := range item.FileSystems {
+		fileSystems[i] = GuestFileSystemDTO{
+			MountPoint: fs.MountPoint,
+			Device:     fs.Device,
+			Type:       fs.Type,
+			TotalBytes: fs.TotalBytes,
+			UsedBytes:  fs.UsedBytes,
+		}
+	}
+	interfaces := make([]GuestInterfaceDTO, len(item.Interfaces))
+	for i, nic := range item.Interfaces {
+		interfaces[i] = GuestInterfaceDTO{
+			Name:      nic.Name,
+			MAC:       nic.MAC,
+			Addresses: nic.Addresses,
+		}
+	}
+	return GuestInfoDTO{
+		Hostname: item.Hostname,
+		OS: GuestOSDTO{
+			ID:            item.OS.ID,
+			Name:          item.OS.Name,
+			PrettyName:    item.OS.PrettyName,
+			Version:       item.OS.Version,
+			KernelRelease: item.OS.KernelRelease,
+			Machine:       item.OS.Machine,
+		},
+		Timezone:    item.Timezone,
+		Users:       users,
+		FileSystems: fileSystems,
+		Interfaces:  interfaces,
+		Updated:     item.Updated.UTC().Format(time.RFC3339),
+	}
+}
+
+// GuestInfoCache keeps the guest information of the running servers for the
+// server list, so listing does not wait for the agents
+type GuestInfoCache struct {
+	mutex   sync.Mutex
+	items   map[string]*GuestInfoModel
+	service ServerService
+	stop    chan struct{}
+}
+
+func NewGuestInfoCache(service ServerService) *GuestInfoCache {
+	return &GuestInfoCache{
+		items:   make(map[string]*GuestInfoModel),
+		service: service,
+	}
+}
+
+// Start starts refreshing the information in the background
+func (c *GuestInfoCache) Start(interval time.Duration) {
+	c.stop = make(chan struct{})
+	go func() {
+		c.refresh()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.refresh()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing the information
+func (c *GuestInfoCache) Stop() {
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+// Get returns the cached information of the server, otherwise nil
+func (c *GuestInfoCache) Get(name string) *GuestInfoModel {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.items[name]
+}
+
+// Set caches the information read on request
+func (c *GuestInfoCache) Set(name string, item *GuestInfoModel) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.items[name] = item
+}
+
+// refresh reads the information of the running servers and forgets the
+// servers which are not running
+func (c *GuestInfoCache) refresh() {
+	list, err := c.service.GetServerList()
+	if err != nil {
+		log.Printf("GuestInfoCache: Failed to get server list: %v", err)
+		return
+	}
+	running := make(map[string]bool)
+	for _, server := range list {
+		if server.Status != StartedServerStatusCode {
+			continue
+		}
+		running[server.Name] = true
+		item, err := c.service.GetGuestInfo(server.Name)
+		if errors.Is(err, ErrGuestAgentUnavailable) {
+			continue
+		}
+		if err != nil {
+			log.Printf("GuestInfoCache: Failed to get guest info of %s: %v", server.Name, err)
+			continue
+		}
+		c.Set(server.Name, item)
+	}
+	c.mutex.Lock()
+	for name := range c.items {
+		if !running[name] {
+			delete(c.items, name)
+		}
+	}
+	c.mutex.Unlock()
+}
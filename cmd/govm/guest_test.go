@@ -0,0 +1,65 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"libvirt.org/go/libvirt"
+)
+
+func TestNewGuestInfoModel(t *testing.T) {
+	info := &libvirt.DomainGuestInfo{
+		Hostname: "server1",
+		OS:       &libvirt.DomainGuestInfoOS{ID: "debian", PrettyName: "Debian GNU/Linux 12 (bookworm)"},
+		Users:    []libvirt.DomainGuestInfoUser{{Name: "debian", LoginTimeSet: true, LoginTime: 1700000000123}},
+		FileSystems: []libvirt.DomainGuestInfoFileSystem{
+			{MountPoint: "/", Name: "vda1", FSType: "ext4", TotalBytes: 1000, UsedBytes: 250},
+		},
+	}
+	interfaces := []libvirt.DomainInterface{
+		{Name: "lo", Addrs: []libvirt.DomainIPAddress{{Addr: "127.0.0.1", Prefix: 8}}},
+		{Name: "enp1s0", Hwaddr: "52:54:00:12:34:56", Addrs: []libvirt.DomainIPAddress{
+			{Type: libvirt.IP_ADDR_TYPE_IPV4, Addr: "192.168.122.10", Prefix: 24},
+			{Type: libvirt.IP_ADDR_TYPE_IPV6, Addr: "fe80::1", Prefix: 64},
+		}},
+	}
+
+	model := newGuestInfoModel(info, interfaces)
+	if model.Hostname != "server1" || model.OS.PrettyName != "Debian GNU/Linux 12 (bookworm)" {
+		t.Errorf("unexpected hostname or OS: %+v", model)
+	}
+	if len(model.Users) != 1 || model.Users[0].LoginTime.UnixMilli() != 1700000000123 {
+		t.Errorf("unexpected users: %+v", model.Users)
+	}
+	if len(model.FileSystems) != 1 || model.FileSystems[0].Device != "vda1" || model.FileSystems[0].UsedBytes != 250 {
+		t.Errorf("unexpected filesystems: %+v", model.FileSystems)
+	}
+	want := []string{"192.168.122.10/24", "fe80::1/64"}
+	if !reflect.DeepEqual(model.GetAddresses(), want) {
+		t.Errorf("addresses = %v, want %v", model.GetAddresses(), want)
+	}
+}
+
+func TestGuestInfoCacheRefresh(t *testing.T) {
+	service := NewDummyService(AllServerActionCodes(), OvercommitRatios{})
+	for _, name := range []string{"started", "stopped"} {
+		if _, err := service.AddServer(name, &ServerOptions{}); err != nil {
+			t.Fatalf("AddServer failed: %v", err)
+		}
+	}
+	started, _ := service.FindServer("started")
+	started.Status = StartedServerStatusCode
+
+	cache := NewGuestInfoCache(service)
+	cache.Set("stopped", &GuestInfoModel{Hostname: "old"})
+	cache.refresh()
+
+	if item := cache.Get("started"); item == nil || item.Hostname != "started" {
+		t.Errorf("running server not cached: %+v", item)
+	}
+	if cache.Get("stopped") != nil {
+		t.Errorf("stopped server should be forgotten")
+	}
+}
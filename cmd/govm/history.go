@@ -0,0 +1,301 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// MetricsSample is the resource usage of a server at one point of time. The
+// rates are per second since the previous sample.
+type MetricsSample struct {
+
+	// Time is the time the sample was taken
+	Time time.Time
+
+	// CPU is the number of CPU cores used, e.g. 0.5 is half of one core
+	CPU float64
+
+	// MemoryUsed is the memory used by the guest in bytes
+	MemoryUsed float64
+
+	// MemoryTotal is the memory of the server in bytes
+	MemoryTotal float64
+
+	// DiskRead is the rate of bytes read from all disks
+	DiskRead float64
+
+	// DiskWrite is the rate of bytes written to all disks
+	DiskWrite float64
+
+	// NetworkReceive is the rate of bytes received by all interfaces
+	NetworkReceive float64
+
+	// NetworkTransmit is the rate of bytes transmitted by all interfaces
+	NetworkTransmit float64
+}
+
+// metricsSeries is a ring buffer of the samples of one server
+type metricsSeries struct {
+	samples []MetricsSample
+	next    int
+	count   int
+
+	// previous are the counters the next rates are calculated from
+	previous *ServerStatsModel
+
+	// previousTime is the time the previous counters were read
+	previousTime time.Time
+}
+
+// add stores the sample, replacing the oldest one when the buffer is full
+func (series *metricsSeries) add(sample MetricsSample) {
+	series.samples[series.next] = sample
+	series.next = (series.next + 1) % len(series.samples)
+	if series.count < len(series.samples) {
+		series.count++
+	}
+}
+
+// since returns the samples taken after the time, oldest first
+func (series *metricsSeries) since(start time.Time) []MetricsSample {
+	var list []MetricsSample
+	first := series.next - series.count
+	for i := 0; i < series.count; i++ {
+		sample := series.samples[(first+i+len(series.samples))%len(series.samples)]
+		if sample.Time.After(start) {
+			list = append(list, sample)
+		}
+	}
+	return list
+}
+
+// MetricsHistory samples the resource usage of the running servers in the
+// background and keeps the samples in memory for the retention time, so usage
+// charts work without an external time series database
+type MetricsHistory struct {
+	mutex     sync.Mutex
+	series    map[string]*metricsSeries
+	service   ServerService
+	interval  time.Duration
+	retention time.Duration
+	stop      chan struct{}
+}
+
+func NewMetricsHistory(service ServerService, interval, retention time.Duration) *MetricsHistory {
+	return &MetricsHistory{
+		series:    make(map[string]*metricsSeries),
+		service:   service,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Interval returns the time between the samples
+func (h *MetricsHistory) Interval() time.Duration {
+	return h.interval
+}
+
+// Retention returns how long the samples are kept
+func (h *MetricsHistory) Retention() time.Duration {
+	return h.retention
+}
+
+// Start starts sampling in the background
+func (h *MetricsHistory) Start() {
+	h.stop = make(chan struct{})
+	go func() {
+		h.sample()
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				h.sample()
+			case <-h.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops sampling
+func (h *MetricsHistory) Stop() {
+	if h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+	}
+}
+
+// Query returns the samples of the server in the range ending now, averaged
+// over each step. The time of a point is the start of its step. Steps without
+// samples are left out.
+func (h *MetricsHistory) Query(name string, timeRange, step time.Duration) []MetricsSample {
+	now := time.Now()
+	start := now.Add(-timeRange)
+
+	h.mutex.Lock()
+	series, exists := h.series[name]
+	var samples []MetricsSample
+	if exists {
+		samples = series.since(start)
+	}
+	h.mutex.Unlock()
+
+	var list []MetricsSample
+	var sum MetricsSample
+	count := 0
+	bucket := int64(-1)
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		n := float64(count)
+		list = append(list, MetricsSample{
+			Time:            start.Add(time.Duration(bucket) * step),
+			CPU:             sum.CPU / n,
+			MemoryUsed:      sum.MemoryUsed / n,
+			MemoryTotal:     sum.MemoryTotal / n,
+			DiskRead:        sum.DiskRead / n,
+			DiskWrite:       sum.DiskWrite / n,
+			NetworkReceive:  sum.NetworkReceive / n,
+			NetworkTransmit: sum.NetworkTransmit / n,
+		})
+		sum = MetricsSample{}
+		count = 0
+	}
+	for _, sample := range samples {
+		index := int64(sample.Time.Sub(start) / step)
+		if index != bucket {
+			flush()
+			bucket = index
+		}
+		sum.CPU += sample.CPU
+		sum.MemoryUsed += sample.MemoryUsed
+		sum.MemoryTotal += sample.MemoryTotal
+		sum.DiskRead += sample.DiskRead
+		sum.DiskWrite += sample.DiskWrite
+		sum.NetworkReceive += sample.NetworkReceive
+		sum.NetworkTransmit += sample.NetworkTransmit
+		count++
+	}
+	flush()
+	return list
+}
+
+// sample reads the usage of the servers and forgets the deleted servers
+func (h *MetricsHistory) sample() {
+	stats, err := h.service.GetServerStats()
+	if err != nil {
+		log.Printf("MetricsHistory: Failed to get server stats: %v", err)
+		return
+	}
+	h.Add(time.Now(), stats)
+}
+
+// Add records the counters read at the time. Rates are calculated from the
+// previous counters of the server, so the first sample after a server has
+// been started only sets the starting point.
+func (h *MetricsHistory) Add(now time.Time, stats []*ServerStatsModel) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	found := make(map[string]bool)
+	for _, item := range stats {
+		found[item.Name] = true
+		series, exists := h.series[item.Name]
+		if !exists {
+			size := int(h.retention / h.interval)
+			if size < 1 {
+				size = 1
+			}
+			series = &metricsSeries{
+				samples: make([]MetricsSample, size),
+			}
+			h.series[item.Name] = series
+		}
+		if item.Status != StartedServerStatusCode {
+			series.previous = nil
+			continue
+		}
+		if series.previous != nil {
+			if sample, ok := newMetricsSample(series.previous, item, now.Sub(series.previousTime)); ok {
+				sample.Time = now
+				series.add(sample)
+			}
+		}
+		series.previous = item
+		series.previousTime = now
+	}
+	for name := range h.series {
+		if !found[name] {
+			delete(h.series, name)
+		}
+	}
+}
+
+// newMetricsSample calculates the rates between two reads of the counters.
+// It returns false if the counters went backwards, e.g. after a reset.
+func newMetricsSample(previous, current *ServerStatsModel, elapsed time.Duration) (MetricsSample, bool) {
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		return MetricsSample{}, false
+	}
+	previousRead, previousWrite := sumDiskStats(previous.Disks)
+	currentRead, currentWrite := sumDiskStats(current.Disks)
+	previousRx, previousTx := sumInterfaceStats(previous.Interfaces)
+	currentRx, currentTx := sumInterfaceStats(current.Interfaces)
+	if current.CPUTime < previous.CPUTime ||
+		currentRead < previousRead || currentWrite < previousWrite ||
+		currentRx < previousRx || currentTx < previousTx {
+		return MetricsSample{}, false
+	}
+	sample := MetricsSample{
+		CPU:             float64(current.CPUTime-previous.CPUTime) / float64(time.Second) / seconds,
+		MemoryTotal:     float64(current.MemoryCurrent),
+		DiskRead:        float64(currentRead-previousRead) / seconds,
+		DiskWrite:       float64(currentWrite-previousWrite) / seconds,
+		NetworkReceive:  float64(currentRx-previousRx) / seconds,
+		NetworkTransmit: float64(currentTx-previousTx) / seconds,
+	}
+	// Without the balloon driver in the guest only the host memory is known
+	if current.MemoryUsable > 0 && current.MemoryUsable <= current.MemoryCurrent {
+		sample.MemoryUsed = float64(current.MemoryCurrent - current.MemoryUsable)
+	} else {
+		sample.MemoryUsed = float64(current.MemoryRSS)
+	}
+	return sample, true
+}
+
+func sumDiskStats(disks []DiskStatsModel) (uint64, uint64) {
+	var read, write uint64
+	for _, disk := range disks {
+		read += disk.ReadBytes
+		write += disk.WriteBytes
+	}
+	return read, write
+}
+
+func sumInterfaceStats(interfaces []InterfaceStatsModel) (uint64, uint64) {
+	var rx, tx uint64
+	for _, nic := range interfaces {
+		rx += nic.RxBytes
+		tx += nic.TxBytes
+	}
+	return rx, tx
+}
+
+func (item *MetricsSample) ToDTO() ServerMetricsPointDTO {
+	return ServerMetricsPointDTO{
+		Time:            item.Time.UTC().Format(time.RFC3339),
+		CPU:             item.CPU,
+		MemoryUsed:      item.MemoryUsed,
+		MemoryTotal:     item.MemoryTotal,
+		DiskRead:        item.DiskRead,
+		DiskWrite:       item.DiskWrite,
+		NetworkReceive:  item.NetworkReceive,
+		NetworkTransmit: item.NetworkTransmit,
+	}
+}
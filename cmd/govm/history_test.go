@@ -0,0 +1,82 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestStats returns the counters of a running server
+func newTestStats(name string, cpuSeconds, readBytes uint64) *ServerStatsModel {
+	return &ServerStatsModel{
+		Name:          name,
+		Status:        StartedServerStatusCode,
+		CPUTime:       cpuSeconds * uint64(time.Second),
+		MemoryCurrent: 1024,
+		MemoryUsable:  256,
+		Disks: []DiskStatsModel{{
+			Device:    "vda",
+			ReadBytes: readBytes,
+		}},
+	}
+}
+
+func TestMetricsHistoryRates(t *testing.T) {
+	h := NewMetricsHistory(nil, 10*time.Second, time.Hour)
+	now := time.Now().Add(-time.Minute)
+
+	h.Add(now, []*ServerStatsModel{newTestStats("server1", 100, 0)})
+	h.Add(now.Add(10*time.Second), []*ServerStatsModel{newTestStats("server1", 105, 10000)})
+
+	list := h.Query("server1", time.Hour, 10*time.Second)
+	if len(list) != 1 {
+		t.Fatalf("Query returned %d points, want 1", len(list))
+	}
+	if list[0].CPU != 0.5 {
+		t.Errorf("CPU = %v, want 0.5", list[0].CPU)
+	}
+	if list[0].DiskRead != 1000 {
+		t.Errorf("DiskRead = %v, want 1000", list[0].DiskRead)
+	}
+	if list[0].MemoryUsed != 768 {
+		t.Errorf("MemoryUsed = %v, want 768", list[0].MemoryUsed)
+	}
+
+	// A restarted server starts its counters again from zero
+	h.Add(now.Add(20*time.Second), []*ServerStatsModel{newTestStats("server1", 1, 0)})
+	if got := len(h.Query("server1", time.Hour, 10*time.Second)); got != 1 {
+		t.Errorf("Query after reset returned %d points, want 1", got)
+	}
+
+	// A deleted server is forgotten
+	h.Add(now.Add(30*time.Second), nil)
+	if got := len(h.Query("server1", time.Hour, 10*time.Second)); got != 0 {
+		t.Errorf("Query after delete returned %d points, want 0", got)
+	}
+}
+
+func TestMetricsHistoryRetention(t *testing.T) {
+	h := NewMetricsHistory(nil, 10*time.Second, 30*time.Second)
+	now := time.Now().Add(-time.Minute)
+	for i := 0; i < 6; i++ {
+		h.Add(now.Add(time.Duration(i)*10*time.Second), []*ServerStatsModel{newTestStats("server1", uint64(i*10), 0)})
+	}
+
+	// The buffer keeps the three latest of the five samples
+	list := h.Query("server1", time.Hour, 10*time.Second)
+	if len(list) != 3 {
+		t.Fatalf("Query returned %d points, want 3", len(list))
+	}
+	for i := 1; i < len(list); i++ {
+		if !list[i].Time.After(list[i-1].Time) {
+			t.Errorf("points are not in order: %v", list)
+		}
+	}
+
+	// A step longer than the samples averages them into one point
+	list = h.Query("server1", time.Hour, time.Hour)
+	if len(list) != 1 || list[0].CPU != 1 {
+		t.Errorf("Query with a long step = %v, want one point with CPU 1", list)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInsufficientCapacity = errors.New("the host does not have enough capacity")
+
+// OvercommitRatios are how many times the physical CPUs and memory of the
+// host may be committed to the servers. A ratio of zero disables the limit.
+type OvercommitRatios struct {
+	CPU    float64
+	Memory float64
+}
+
+// HostModel is the capacity of the hypervisor and the resources committed to
+// the defined servers
+type HostModel struct {
+
+	// CPUModel is the model of the CPUs
+	CPUModel string
+
+	// CPUs is the number of active CPUs
+	CPUs uint
+
+	// CPUMhz is the frequency of the CPUs in MHz
+	CPUMhz uint
+
+	// Memory is the memory of the host in bytes
+	Memory uint64
+
+	// FreeMemory is the memory not in use on the host in bytes
+	FreeMemory uint64
+
+	// Images is the space of the images directory
+	Images *StorageModel
+
+	// Volumes is the space of the volume storage
+	Volumes *StorageModel
+
+	// Servers is the number of defined servers
+	Servers int
+
+	// CommittedCPUs is the sum of the virtual CPUs of the defined servers
+	CommittedCPUs uint
+
+	// CommittedMemory is the sum of the maximum memory of the defined servers in bytes
+	CommittedMemory uint64
+
+	// Overcommit are the ratios new servers are limited by
+	Overcommit OvercommitRatios
+}
+
+// MaxCPUs returns the number of virtual CPUs the servers may have in total,
+// or zero without a limit
+func (item *HostModel) MaxCPUs() uint {
+	return uint(float64(item.CPUs) * item.Overcommit.CPU)
+}
+
+// MaxMemory returns the memory the servers may have in total in bytes, or
+// zero without a limit
+func (item *HostModel) MaxMemory() uint64 {
+	return uint64(float64(item.Memory) * item.Overcommit.Memory)
+}
+
+// CheckCapacity returns ErrInsufficientCapacity if a new server with the
+// resources would go over the overcommit limits
+func (item *HostModel) CheckCapacity(cpus uint, memory uint64) error {
+	if item.Overcommit.CPU > 0 && item.CommittedCPUs+cpus > item.MaxCPUs() {
+		return fmt.Errorf("%w: %d of %d virtual CPUs committed", ErrInsufficientCapacity, item.CommittedCPUs, item.MaxCPUs())
+	}
+	if item.Overcommit.Memory > 0 && item.CommittedMemory+memory > item.MaxMemory() {
+		return fmt.Errorf("%w: %d of %d bytes of memory committed", ErrInsufficientCapacity, item.CommittedMemory, item.MaxMemory())
+	}
+	return nil
+}
+
+func (item *HostModel) ToDTO() HostDTO {
+	dto := HostDTO{
+		CPU: HostCPUDTO{
+			Model:      item.CPUModel,
+			Count:      item.CPUs,
+			Mhz:        item.CPUMhz,
+			Committed:  item.CommittedCPUs,
+			Overcommit: item.Overcommit.CPU,
+			Limit:      item.MaxCPUs(),
+		},
+		Memory: HostMemoryDTO{
+			Total:      item.Memory,
+			Free:       item.FreeMemory,
+			Committed:  item.CommittedMemory,
+			Overcommit: item.Overcommit.Memory,
+			Limit:      item.MaxMemory(),
+		},
+		Servers: item.Servers,
+	}
+	if item.Images != nil {
+		images := item.Images.ToDTO()
+		dto.Images = &images
+	}
+	if item.Volumes != nil {
+		volumes := item.Volumes.ToDTO()
+		dto.Volumes = &volumes
+	}
+	return dto
+}
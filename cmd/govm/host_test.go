@@ -0,0 +1,37 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHostModelCheckCapacity(t *testing.T) {
+	const gib uint64 = 1024 * 1024 * 1024
+	host := &HostModel{
+		CPUs:            2,
+		Memory:          4 * gib,
+		CommittedCPUs:   7,
+		CommittedMemory: 5 * gib,
+		Overcommit: OvercommitRatios{
+			CPU:    4,
+			Memory: 1.5,
+		},
+	}
+	if err := host.CheckCapacity(1, gib); err != nil {
+		t.Errorf("CheckCapacity within the limits: %v", err)
+	}
+	if err := host.CheckCapacity(2, gib); !errors.Is(err, ErrInsufficientCapacity) {
+		t.Errorf("CheckCapacity over the CPU limit = %v, want ErrInsufficientCapacity", err)
+	}
+	if err := host.CheckCapacity(1, 2*gib); !errors.Is(err, ErrInsufficientCapacity) {
+		t.Errorf("CheckCapacity over the memory limit = %v, want ErrInsufficientCapacity", err)
+	}
+
+	// Zero ratios disable the limits
+	host.Overcommit = OvercommitRatios{}
+	if err := host.CheckCapacity(100, 100*gib); err != nil {
+		t.Errorf("CheckCapacity without limits: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+// HostConfig represents another hypervisor servers can be migrated to
+type HostConfig struct {
+	Name string `yaml:"name"`
+	URI  string `yaml:"uri"`
+}
+
+type HostConfigList []*HostConfig
+
+// findByName finds a host by name and returns it, otherwise nil
+func (list HostConfigList) findByName(name string) *HostConfig {
+	for _, item := range list {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// SetServerHost records the host the server runs on and returns a new config object
+func (c *Config) SetServerHost(name, host string) *Config {
+	newList := make(ServerConfigList, len(c.Servers))
+	for i, item := range c.Servers {
+		if item.Name == name {
+			updated := *item
+			updated.Host = host
+			newList[i] = &updated
+		} else {
+			newList[i] = item
+		}
+	}
+	newConfig := *c
+	newConfig.Servers = newList
+	return &newConfig
+}
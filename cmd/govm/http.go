@@ -9,6 +9,8 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -25,11 +27,27 @@ type ApiServer struct {
 	authorization              AuthorizationService
 	session                    SessionService
 	service                    ServerService
-	vncSessions                map[string]string
+	vncSessions                *VncSessionManager
+	vncProxy                   *VncProxy
+	thumbnails                 *ThumbnailCache
+	thumbnailInterval          time.Duration
+	guestInfo                  *GuestInfoCache
+	guestInfoInterval          time.Duration
+	history                    *MetricsHistory
+	serialSessions             map[string]*serialToken
+	serialMutex                sync.Mutex
 	enabledActions             []ServerActionCode
 	permissions                ServerPermissionDTO
 	unauthenticatedPermissions ServerPermissionDTO
 	config                     *ConfigManager
+	adminEmail                 string
+	forwarder                  *PortForwarder
+
+	// quotaMutex keeps the quota checks and the creation of the resources together
+	quotaMutex sync.Mutex
+
+	// forwardMutex keeps the allocation and the creation of a port forward together
+	forwardMutex sync.Mutex
 }
 
 func NewApiServer(
@@ -42,8 +60,16 @@ func NewApiServer(
 	authorization AuthorizationService,
 	enabledActions []ServerActionCode,
 	config *ConfigManager,
+	adminEmail string,
+	forwarder *PortForwarder,
+	vncSessionTTL time.Duration,
+	vncSessionLimit int,
+	thumbnailInterval time.Duration,
+	guestInfoInterval time.Duration,
+	metricsInterval time.Duration,
+	metricsRetention time.Duration,
 ) *ApiServer {
-	return &ApiServer{
+	api := &ApiServer{
 		listen:                     listen,
 		tlsEnabled:                 tlsEnabled,
 		tlsCertFile:                tlsCertFile,
@@ -51,12 +77,28 @@ func NewApiServer(
 		session:                    sessionService,
 		service:                    service,
 		authorization:              authorization,
-		vncSessions:                make(map[string]string),
+		serialSessions:             make(map[string]*serialToken),
 		enabledActions:             enabledActions,
 		permissions:                NewServerPermissionDTOFromServerActionCodeList(enabledActions),
 		unauthenticatedPermissions: NewServerPermissionDTOFromServerActionCodeList(nil),
 		config:                     config,
+		adminEmail:                 adminEmail,
+		forwarder:                  forwarder,
+	}
+	api.vncSessions = NewVncSessionManager(vncSessionTTL, vncSessionLimit, api.rotateVNCPassword)
+	api.vncProxy = NewVncProxy()
+	if thumbnailInterval > 0 && api.permissions.ConsoleEnabled {
+		api.thumbnails = NewThumbnailCache(service, ThumbnailWidth)
+		api.thumbnailInterval = thumbnailInterval
+	}
+	if guestInfoInterval > 0 {
+		api.guestInfo = NewGuestInfoCache(service)
+		api.guestInfoInterval = guestInfoInterval
+	}
+	if metricsInterval > 0 {
+		api.history = NewMetricsHistory(service, metricsInterval, metricsRetention)
 	}
+	return api
 }
 
 func (api *ApiServer) onIndexRequest(w http.ResponseWriter, r *http.Request) {
@@ -64,10 +106,15 @@ func (api *ApiServer) onIndexRequest(w http.ResponseWriter, r *http.Request) {
 	session := api.authenticateSession(r)
 	var response IndexDTO
 	if session != nil {
+		quotas, err := api.getQuotaList(session.Email)
+		if err != nil {
+			log.Printf("onIndexRequest: failed to get quotas: %v", err)
+		}
 		response = IndexDTO{
 			Email:           session.Email,
 			IsAuthenticated: true,
 			Permissions:     api.permissions,
+			Quotas:          quotas,
 		}
 	} else {
 		response = IndexDTO{
@@ -111,13 +158,79 @@ func (api *ApiServer) onAddServerRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = api.service.AddServer(name)
+	if requestBody.Console != "" && !IsConsoleType(requestBody.Console) {
+		sendJsonError("onAddServerRequest", w, IllegalConsoleTypeError, http.StatusBadRequest)
+		return
+	}
+
+	project, ok := api.getNewServerProject("onAddServerRequest", w, config, session.Email, requestBody.Project)
+	if !ok {
+		return
+	}
+
+	options := &ServerOptions{
+		Console: requestBody.Console,
+	}
+	if len(requestBody.Interfaces) > 0 {
+		networks, err := api.getAllowedNetworks()
+		if err != nil {
+			logAndSendJsonError(err, "onAddServerRequest", w, InternalServerError, http.StatusInternalServerError)
+			return
+		}
+		for _, dto := range requestBody.Interfaces {
+			nic, err := NewNetworkInterfaceModelFromDTO(dto)
+			if err != nil {
+				logAndSendJsonError(err, "onAddServerRequest", w, IllegalInterfaceError, http.StatusBadRequest)
+				return
+			}
+			if networks.find(nic.Type, nic.Source) == nil {
+				sendJsonError("onAddServerRequest", w, NetworkNotAllowedError, http.StatusBadRequest)
+				return
+			}
+			options.Interfaces = append(options.Interfaces, nic)
+		}
+	}
+
+	unlock := api.lockQuotas(session.Email)
+	defer unlock()
+	request := QuotaUsageModel{
+		Servers: 1,
+		CPUs:    DefaultServerCPUs,
+		Memory:  DefaultServerMemory,
+	}
+
+	// The root disk is a copy of the image
+	if api.config.GetConfig().HasQuotas(session.Email) {
+		request.Disk, err = api.service.GetImageSize()
+		if err != nil {
+			logAndSendJsonError(err, "onAddServerRequest", w, InternalServerError, http.StatusInternalServerError)
+			return
+		}
+	}
+	if !api.checkQuotas("onAddServerRequest", w, session.Email, request) {
+		return
+	}
+
+	_, err = api.service.AddServer(name, options)
+	if errors.Is(err, ErrInsufficientCapacity) {
+		logAndSendJsonError(err, "onAddServerRequest", w, InsufficientCapacityError, http.StatusConflict)
+		return
+	}
+	if errors.Is(err, ErrAddressInUse) {
+		logAndSendJsonError(err, "onAddServerRequest", w, AddressInUseError, http.StatusConflict)
+		return
+	}
 	if err != nil {
 		logAndSendJsonError(err, "onAddServerRequest", w, InternalServerError, http.StatusInternalServerError)
 		return
 	}
 
-	api.config.AddServerConfig(name, []string{session.Email})
+	// The members of the project have access, otherwise only the creator
+	users := UserEmailList{}
+	if project == "" {
+		users = UserEmailList{session.Email}
+	}
+	api.config.AddServerConfig(name, project, users)
 
 	serverList, err := api.service.GetServerList()
 	if err != nil {
@@ -149,13 +262,29 @@ func (api *ApiServer) onServerListRequest(w http.ResponseWriter, r *http.Request
 
 	config := api.config.GetConfig()
 
+	// ?project=name lists only the servers of the project
+	project := r.URL.Query().Get("project")
+
+	// Servers migrated to other hosts are listed too, so they can be found
+	// and moved back
+	serverList = append(serverList, api.getMovedServers(config, serverList)...)
+
 	var result []*ServerModel
 	for _, item := range serverList {
-		if config.ServerHasAccessToEmail(item.Name, session.Email) {
-			result = append(result, item)
+		if !config.ServerHasAccessToEmail(item.Name, session.Email) {
+			continue
+		}
+		if project != "" && config.Servers.findByName(item.Name).Project != project {
+			continue
 		}
+		result = append(result, item)
 	}
 	response := ToServerListDTO(result, permissions)
+	for i := range response.Payload {
+		api.setProject(config, &response.Payload[i])
+		api.setThumbnailURL(&response.Payload[i])
+		api.setGuestSummary(&response.Payload[i])
+	}
 	sendJsonData("onServerListRequest", w, response)
 
 }
@@ -189,10 +318,16 @@ func (api *ApiServer) onServerRequest(w http.ResponseWriter, r *http.Request) {
 		logAndSendJsonError(err, "onServerListRequest", w, InternalServerError, http.StatusInternalServerError)
 		return
 	}
+	if item == nil {
+		item = api.getMovedServer(config, name)
+	}
 	if item == nil {
 		sendJsonError("onServerListRequest", w, NotFoundError, http.StatusNotFound)
 	} else {
 		response := item.ToDTO()
+		api.setProject(config, &response)
+		api.setThumbnailURL(&response)
+		api.setGuestSummary(&response)
 		sendJsonData("onServerListRequest", w, response)
 	}
 
@@ -269,74 +404,38 @@ func (api *ApiServer) onServerStartRequest(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// onServerStopRequest asks the guest to shut down. The optional body sets
+// the timeout and whether the server is forced off after it.
 func (api *ApiServer) onServerStopRequest(w http.ResponseWriter, r *http.Request) {
 	logRequest("onServerStopRequest", r)
 	if r.Method != "POST" {
 		sendJsonError("onServerStopRequest", w, InvalidMethod, http.StatusMethodNotAllowed)
 		return
 	}
-	vars := mux.Vars(r)
-	name := vars["name"]
-	if !ValidateName(name) {
-		sendJsonError("onServerStopRequest", w, NotFoundError, http.StatusNotFound)
+	options, ok := parseStopOptions("onServerStopRequest", w, r)
+	if !ok {
 		return
 	}
-	session := api.authenticateSession(r)
-	if session == nil {
-		sendJsonError("onServerStopRequest", w, UnauthorizedError, http.StatusUnauthorized)
-		return
-	}
-	config := api.config.GetConfig()
-	if !config.ServerHasAccessToEmail(name, session.Email) {
-		sendJsonError("onServerStopRequest", w, NotFoundError, http.StatusNotFound)
-		return
-	}
-	item, err := api.service.StopServer(name)
-	if err != nil {
-		logAndSendJsonError(err, "onServerStopRequest", w, InternalServerError, http.StatusInternalServerError)
-		return
-	}
-	if item == nil {
-		sendJsonError("onServerStopRequest", w, NotFoundError, http.StatusNotFound)
-	} else {
-		response := item.ToDTO()
-		sendJsonData("onServerStopRequest", w, response)
-	}
+	api.handleServerAction("onServerStopRequest", w, r, StopServerActionCode, func(name string) (*ServerModel, error) {
+		return api.service.StopServer(name, options)
+	})
 }
 
+// onServerRestartRequest shuts down and starts the server again. The
+// optional body is the same as when stopping.
 func (api *ApiServer) onServerRestartRequest(w http.ResponseWriter, r *http.Request) {
 	logRequest("onServerRestartRequest", r)
 	if r.Method != "POST" {
 		sendJsonError("onServerRestartRequest", w, InvalidMethod, http.StatusMethodNotAllowed)
 		return
 	}
-	vars := mux.Vars(r)
-	name := vars["name"]
-	if !ValidateName(name) {
-		sendJsonError("onServerRestartRequest", w, NotFoundError, http.StatusNotFound)
-		return
-	}
-	session := api.authenticateSession(r)
-	if session == nil {
-		sendJsonError("onServerRestartRequest", w, UnauthorizedError, http.StatusUnauthorized)
+	options, ok := parseStopOptions("onServerRestartRequest", w, r)
+	if !ok {
 		return
 	}
-	config := api.config.GetConfig()
-	if !config.ServerHasAccessToEmail(name, session.Email) {
-		sendJsonError("onServerRestartRequest", w, NotFoundError, http.StatusNotFound)
-		return
-	}
-	item, err := api.service.RestartServer(name)
-	if err != nil {
-		logAndSendJsonError(err, "onServerRestartRequest", w, InternalServerError, http.StatusInternalServerError)
-		return
-	}
-	if item == nil {
-		sendJsonError("onServerRestartRequest", w, NotFoundError, http.StatusNotFound)
-	} else {
-		response := item.ToDTO()
-		sendJsonData("onServerRestartRequest", w, response)
-	}
+	api.handleServerAction("onServerRestartRequest", w, r, RestartServerActionCode, func(name string) (*ServerModel, error) {
+		return api.service.RestartServer(name, options)
+	})
 }
 
 func (api *ApiServer) onServerDeleteRequest(w http.ResponseWriter, r *http.Request) {
@@ -366,6 +465,7 @@ func (api *ApiServer) onServerDeleteRequest(w http.ResponseWriter, r *http.Reque
 		logAndSendJsonError(err, "onServerDeleteRequest", w, InternalServerError, http.StatusInternalServerError)
 		return
 	}
+	api.removeServerPortForwards(name)
 	if item == nil {
 		sendJsonError("onServerDeleteRequest", w, NotFoundError, http.StatusNotFound)
 	} else {
@@ -374,6 +474,38 @@ func (api *ApiServer) onServerDeleteRequest(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+func (api *ApiServer) onStorageRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onStorageRequest", r)
+	session := api.authenticateSession(r)
+	if session == nil {
+		sendJsonError("onStorageRequest", w, UnauthorizedError, http.StatusUnauthorized)
+		return
+	}
+	item, err := api.service.GetStorage()
+	if err != nil {
+		logAndSendJsonError(err, "onStorageRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onStorageRequest", w, item.ToDTO())
+}
+
+// onHostRequest returns the capacity of the host, so users can see how much
+// is left before creating servers
+func (api *ApiServer) onHostRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onHostRequest", r)
+	session := api.authenticateSession(r)
+	if session == nil {
+		sendJsonError("onHostRequest", w, UnauthorizedError, http.StatusUnauthorized)
+		return
+	}
+	item, err := api.service.GetHost()
+	if err != nil {
+		logAndSendJsonError(err, "onHostRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onHostRequest", w, item.ToDTO())
+}
+
 func (api *ApiServer) onAuthRequest(w http.ResponseWriter, r *http.Request) {
 
 	logRequest("onAuthRequest", r)
@@ -458,9 +590,36 @@ func (api *ApiServer) startApiServer() error {
 		novncFileServerHandler.ServeHTTP(w, r)
 	})
 
+	// Wrap the file server for the SPICE console page
+	spiceFileServerHandler := http.FileServer(http.FS(frontend.BuildSpice))
+	spiceWrappedFileServerHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logRequest("spiceWrappedFileServerHandler", r)
+		spiceFileServerHandler.ServeHTTP(w, r)
+	})
+
+	// Wrap the file server for the serial console page
+	serialFileServerHandler := http.FileServer(http.FS(frontend.BuildSerial))
+	serialWrappedFileServerHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logRequest("serialWrappedFileServerHandler", r)
+		serialFileServerHandler.ServeHTTP(w, r)
+	})
+
 	api.r.HandleFunc("/api/v1", api.onIndexRequest).Methods("GET")
 	api.r.HandleFunc("/api/v1/auth", api.onAuthRequest).Methods("GET", "POST")
 	api.r.HandleFunc("/api/v1/auth/logout", api.onAuthLogoutRequest).Methods("GET", "POST", "DELETE")
+	api.r.HandleFunc("/api/v1/storage", api.onStorageRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/host", api.onHostRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/networks", api.onNetworkListRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/networks", api.onNetworkCreateRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/networks/{network}", api.onNetworkRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/networks/{network}/start", api.onNetworkStartRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/networks/{network}/stop", api.onNetworkStopRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/networks/{network}/delete", api.onNetworkDeleteRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/projects", api.onProjectListRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/projects", api.onProjectCreateRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/projects/{project}/delete", api.onProjectDeleteRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/projects/{project}/members", api.onProjectMemberSetRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/projects/{project}/members/{email}/delete", api.onProjectMemberDeleteRequest).Methods("POST")
 	api.r.HandleFunc("/api/v1/servers", api.onServerListRequest).Methods("GET")
 	api.r.HandleFunc("/api/v1/servers", api.onAddServerRequest).Methods("POST")
 	api.r.HandleFunc("/api/v1/servers/{name}", api.onServerRequest).Methods("GET")
@@ -469,11 +628,49 @@ func (api *ApiServer) startApiServer() error {
 	api.r.HandleFunc("/api/v1/servers/{name}/stop", api.onServerStopRequest).Methods("GET", "POST")
 	api.r.HandleFunc("/api/v1/servers/{name}/restart", api.onServerRestartRequest).Methods("GET", "POST")
 	api.r.HandleFunc("/api/v1/servers/{name}/delete", api.onServerDeleteRequest).Methods("GET", "POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/resize", api.onServerResizeRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/force-off", api.onServerForceOffRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/reset", api.onServerResetRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/pause", api.onServerPauseRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/resume", api.onServerResumeRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/suspend", api.onServerSuspendRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/hibernate", api.onServerHibernateRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/restore", api.onServerRestoreRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/migrate", api.onServerMigrateRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/keys", api.onServerKeysRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/guest", api.onGuestInfoRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/servers/{name}/metrics", api.onServerMetricsRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/servers/{name}/reset-password", api.onResetPasswordRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/authorized-keys", api.onAuthorizedKeysRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/freeze", api.onFreezeRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/thaw", api.onThawRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/volumes", api.onVolumeAddRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/volumes/{volume}/attach", api.onVolumeAttachRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/volumes/{volume}/detach", api.onVolumeDetachRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/volumes/{volume}/delete", api.onVolumeDeleteRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/interfaces", api.onInterfaceAttachRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/interfaces/{mac}/detach", api.onInterfaceDetachRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/firewall", api.onFirewallRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/servers/{name}/firewall", api.onFirewallUpdateRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/forwards", api.onPortForwardListRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/servers/{name}/forwards", api.onPortForwardAddRequest).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/forwards/{protocol}/{port}/delete", api.onPortForwardDeleteRequest).Methods("POST")
 	api.r.HandleFunc("/api/v1/servers/{name}/vnc", api.onVncOpen).Methods("GET", "POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/console", api.onVncOpen).Methods("POST")
+	api.r.HandleFunc("/api/v1/consoles", api.onVncSessionListRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/consoles/{id}/delete", api.onVncSessionDeleteRequest).Methods("POST")
 	api.r.HandleFunc("/api/vnc/{token}", api.onVncClose).Methods("DELETE")
 	api.r.HandleFunc("/api/vnc/{token}", api.onVncWebSocket)
+	api.r.HandleFunc("/api/spice/{token}", api.onVncClose).Methods("DELETE")
+	api.r.HandleFunc("/api/spice/{token}", api.onVncWebSocket)
+	api.r.HandleFunc("/api/v1/servers/{name}/serial", api.onSerialOpen).Methods("POST")
+	api.r.HandleFunc("/api/v1/servers/{name}/serial", api.onSerialWebSocket).Methods("GET")
+	api.r.HandleFunc("/api/v1/servers/{name}/console-log", api.onConsoleLogRequest).Methods("GET")
+	api.r.HandleFunc("/api/v1/servers/{name}/screenshot", api.onScreenshotRequest).Methods("GET")
 	api.r.Handle("/metrics", promhttp.Handler())
 	api.r.PathPrefix("/api/novnc/").Handler(http.StripPrefix("/api/novnc/", novncWrappedFileServerHandler))
+	api.r.PathPrefix("/api/spice-html5/").Handler(http.StripPrefix("/api/spice-html5/", spiceWrappedFileServerHandler))
+	api.r.PathPrefix("/api/serial/").Handler(http.StripPrefix("/api/serial/", serialWrappedFileServerHandler))
 
 	// Catch-all routes for frontend client-side routing
 	api.r.PathPrefix("/login").Handler(http.StripPrefix("/", frontendAppHandler))
@@ -481,6 +678,24 @@ func (api *ApiServer) startApiServer() error {
 
 	api.r.PathPrefix("/").Handler(http.StripPrefix("/", wrappedFileServerHandler))
 
+	api.vncSessions.Start(VncSessionExpireInterval)
+	defer api.vncSessions.Stop()
+
+	if api.thumbnails != nil {
+		api.thumbnails.Start(api.thumbnailInterval)
+		defer api.thumbnails.Stop()
+	}
+
+	if api.guestInfo != nil {
+		api.guestInfo.Start(api.guestInfoInterval)
+		defer api.guestInfo.Stop()
+	}
+
+	if api.history != nil {
+		api.history.Start()
+		defer api.history.Stop()
+	}
+
 	if api.tlsEnabled {
 		err := http.ListenAndServeTLS(api.listen, api.tlsCertFile, api.tlsKeyFile, api.r)
 		if err != nil {
@@ -495,6 +710,58 @@ func (api *ApiServer) startApiServer() error {
 	return nil
 }
 
+// authorizeServerRequest validates the server name from the path and checks the
+// session has access to the server. If it returns false, an error has been sent.
+func (api *ApiServer) authorizeServerRequest(method string, w http.ResponseWriter, r *http.Request) (*Session, string, bool) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if !ValidateName(name) {
+		sendJsonError(method, w, NotFoundError, http.StatusNotFound)
+		return nil, "", false
+	}
+	session := api.authenticateSession(r)
+	if session == nil {
+		sendJsonError(method, w, UnauthorizedError, http.StatusUnauthorized)
+		return nil, "", false
+	}
+	config := api.config.GetConfig()
+	if !config.ServerHasAccessToEmail(name, session.Email) {
+		sendJsonError(method, w, NotFoundError, http.StatusNotFound)
+		return nil, "", false
+	}
+	return session, name, true
+}
+
+// authorizeAdminRequest checks the session belongs to the admin user. If it
+// returns false, an error has been sent.
+func (api *ApiServer) authorizeAdminRequest(method string, w http.ResponseWriter, r *http.Request) (*Session, bool) {
+	session := api.authenticateSession(r)
+	if session == nil {
+		sendJsonError(method, w, UnauthorizedError, http.StatusUnauthorized)
+		return nil, false
+	}
+	if !api.isAdminSession(session) {
+		sendJsonError(method, w, ForbiddenError, http.StatusForbidden)
+		return nil, false
+	}
+	return session, true
+}
+
+// isAdminSession returns true if the session belongs to the admin user
+func (api *ApiServer) isAdminSession(session *Session) bool {
+	return session != nil && session.Email == api.adminEmail
+}
+
+// sendServerData sends the server as a response, or not found if it is nil
+func sendServerData(method string, w http.ResponseWriter, item *ServerModel) {
+	if item == nil {
+		sendJsonError(method, w, NotFoundError, http.StatusNotFound)
+	} else {
+		response := item.ToDTO()
+		sendJsonData(method, w, response)
+	}
+}
+
 func (api *ApiServer) authenticateSession(r *http.Request) *Session {
 	authorization := r.Header.Get("Authorization")
 	token, err := parseBearerToken(authorization)
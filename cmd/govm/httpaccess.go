@@ -0,0 +1,82 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// onResetPasswordRequest sets a new password for the user in the server. The
+// password is generated unless the optional body has one, and it is only
+// sent in the response.
+func (api *ApiServer) onResetPasswordRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onResetPasswordRequest", r)
+	name, ok := api.authorizeServerAction("onResetPasswordRequest", w, r, AccessServerActionCode)
+	if !ok {
+		return
+	}
+
+	var requestBody ResetPasswordDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil && err != io.EOF {
+		logAndSendJsonError(err, "onResetPasswordRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	password := requestBody.Password
+	if password == "" {
+		password, err = generatePassword(GeneratedPasswordLength)
+		if err != nil {
+			logAndSendJsonError(err, "onResetPasswordRequest", w, InternalServerError, http.StatusInternalServerError)
+			return
+		}
+	} else if len(password) < MinServerPasswordLength || len(password) > MaxServerPasswordLength {
+		sendJsonError("onResetPasswordRequest", w, IllegalPasswordError, http.StatusBadRequest)
+		return
+	}
+
+	method, err := api.service.ResetPassword(name, password)
+	if err != nil {
+		logAndSendJsonError(err, "onResetPasswordRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onResetPasswordRequest", w, ResetPasswordDTO{
+		User:     serverUsername,
+		Password: password,
+		Method:   method,
+	})
+}
+
+// onAuthorizedKeysRequest sets the SSH keys of the user in the server
+func (api *ApiServer) onAuthorizedKeysRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onAuthorizedKeysRequest", r)
+	name, ok := api.authorizeServerAction("onAuthorizedKeysRequest", w, r, AccessServerActionCode)
+	if !ok {
+		return
+	}
+
+	var requestBody AuthorizedKeysDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onAuthorizedKeysRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	keys, err := ParseAuthorizedKeys(requestBody.Keys)
+	if err != nil {
+		logAndSendJsonError(err, "onAuthorizedKeysRequest", w, IllegalAuthorizedKeysError, http.StatusBadRequest)
+		return
+	}
+
+	method, err := api.service.SetAuthorizedKeys(name, keys, requestBody.Append)
+	if err != nil {
+		logAndSendJsonError(err, "onAuthorizedKeysRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onAuthorizedKeysRequest", w, AuthorizedKeysDTO{
+		Keys:   keys,
+		Append: requestBody.Append,
+		User:   serverUsername,
+		Method: method,
+	})
+}
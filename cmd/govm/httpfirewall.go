@@ -0,0 +1,80 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func (api *ApiServer) onFirewallRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onFirewallRequest", r)
+	_, name, ok := api.authorizeServerRequest("onFirewallRequest", w, r)
+	if !ok {
+		return
+	}
+	item, err := api.service.GetFirewall(name)
+	if err != nil {
+		logAndSendJsonError(err, "onFirewallRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onFirewallRequest", w, item.ToDTO())
+}
+
+func (api *ApiServer) onFirewallUpdateRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onFirewallUpdateRequest", r)
+	session, name, ok := api.authorizeServerRequest("onFirewallUpdateRequest", w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody FirewallDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onFirewallUpdateRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+
+	firewall, err := NewFirewallModelFromDTO(requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onFirewallUpdateRequest", w, IllegalFirewallError, http.StatusBadRequest)
+		return
+	}
+
+	// Spoofing on the shared network affects other servers, so only the
+	// admin may turn the anti-spoofing filter on or off
+	if !api.isAdminSession(session) {
+		current, err := api.service.GetFirewall(name)
+		if err != nil {
+			logAndSendJsonError(err, "onFirewallUpdateRequest", w, InternalServerError, http.StatusInternalServerError)
+			return
+		}
+		if firewall.AllowSpoofing != current.AllowSpoofing {
+			sendJsonError("onFirewallUpdateRequest", w, ForbiddenError, http.StatusForbidden)
+			return
+		}
+	}
+
+	config := api.config.GetConfig()
+	var ruleSets []*FirewallRuleSetModel
+	for _, ruleSetName := range firewall.RuleSets {
+		ruleSetConfig := config.Firewalls.findByName(ruleSetName)
+		if ruleSetConfig == nil {
+			sendJsonError("onFirewallUpdateRequest", w, RuleSetNotFoundError, http.StatusBadRequest)
+			return
+		}
+		ruleSet, err := ruleSetConfig.ToModel()
+		if err != nil {
+			logAndSendJsonError(err, "onFirewallUpdateRequest", w, IllegalRuleSetError, http.StatusInternalServerError)
+			return
+		}
+		ruleSets = append(ruleSets, ruleSet)
+	}
+
+	item, err := api.service.SetFirewall(name, firewall, ruleSets)
+	if err != nil {
+		logAndSendJsonError(err, "onFirewallUpdateRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onFirewallUpdateRequest", w, item.ToDTO())
+}
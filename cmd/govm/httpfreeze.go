@@ -0,0 +1,79 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// onFreezeRequest freezes the filesystems of a running server before a
+// snapshot or a backup of its disks. The filesystems are thawed by the thaw
+// request or after the timeout.
+func (api *ApiServer) onFreezeRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onFreezeRequest", r)
+	name, ok := api.authorizeServerAction("onFreezeRequest", w, r, VolumeServerActionCode)
+	if !ok {
+		return
+	}
+
+	var requestBody FreezeDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil && err != io.EOF {
+		logAndSendJsonError(err, "onFreezeRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	timeout := DefaultFreezeTimeout
+	// The timeout is compared in seconds, because a huge value would
+	// overflow the duration
+	if requestBody.Timeout < 0 || requestBody.Timeout > int(MaxFreezeTimeout/time.Second) {
+		sendJsonError("onFreezeRequest", w, IllegalTimeoutError, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Timeout > 0 {
+		timeout = time.Duration(requestBody.Timeout) * time.Second
+	}
+
+	err = api.service.FreezeFileSystems(name, timeout)
+	if !sendFreezeError("onFreezeRequest", w, err) {
+		return
+	}
+	sendJsonData("onFreezeRequest", w, FreezeDTO{
+		Timeout: int(timeout / time.Second),
+		Frozen:  true,
+	})
+}
+
+// onThawRequest thaws the filesystems of the server
+func (api *ApiServer) onThawRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onThawRequest", r)
+	name, ok := api.authorizeServerAction("onThawRequest", w, r, VolumeServerActionCode)
+	if !ok {
+		return
+	}
+	err := api.service.ThawFileSystems(name)
+	if !sendFreezeError("onThawRequest", w, err) {
+		return
+	}
+	sendJsonData("onThawRequest", w, FreezeDTO{Frozen: false})
+}
+
+// sendFreezeError sends the error if there is one and returns true if there was none
+func sendFreezeError(method string, w http.ResponseWriter, err error) bool {
+	if errors.Is(err, ErrGuestAgentUnavailable) {
+		logAndSendJsonError(err, method, w, GuestAgentUnavailableError, http.StatusServiceUnavailable)
+		return false
+	}
+	if errors.Is(err, ErrAlreadyFrozen) {
+		sendJsonError(method, w, AlreadyFrozenError, http.StatusConflict)
+		return false
+	}
+	if err != nil {
+		logAndSendJsonError(err, method, w, FreezeError, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
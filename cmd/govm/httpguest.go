@@ -0,0 +1,45 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// onGuestInfoRequest asks the guest agent of the server for its information
+func (api *ApiServer) onGuestInfoRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onGuestInfoRequest", r)
+	_, name, ok := api.authorizeServerRequest("onGuestInfoRequest", w, r)
+	if !ok {
+		return
+	}
+
+	item, err := api.service.GetGuestInfo(name)
+	if errors.Is(err, ErrGuestAgentUnavailable) {
+		logAndSendJsonError(err, "onGuestInfoRequest", w, GuestAgentUnavailableError, http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		logAndSendJsonError(err, "onGuestInfoRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if api.guestInfo != nil {
+		api.guestInfo.Set(name, item)
+	}
+	sendJsonData("onGuestInfoRequest", w, item.ToDTO())
+}
+
+// setGuestSummary adds the hostname, the operating system and the addresses
+// to a running server if the guest agent has reported them
+func (api *ApiServer) setGuestSummary(item *ServerDTO) {
+	if api.guestInfo == nil || item.Status != StartedServerStatus {
+		return
+	}
+	info := api.guestInfo.Get(item.Name)
+	if info != nil {
+		item.Hostname = info.Hostname
+		item.OS = info.OS.PrettyName
+		item.Addresses = info.GetAddresses()
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// onServerMetricsRequest returns the usage history of the server, e.g.
+// ?range=1h&step=30s. The step defaults to the sampling interval, or longer if
+// the range would have too many points.
+func (api *ApiServer) onServerMetricsRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerMetricsRequest", r)
+	_, name, ok := api.authorizeServerRequest("onServerMetricsRequest", w, r)
+	if !ok {
+		return
+	}
+
+	if api.history == nil {
+		sendJsonError("onServerMetricsRequest", w, MetricsHistoryDisabledError, http.StatusNotFound)
+		return
+	}
+
+	timeRange := DefaultMetricsRange
+	if value := r.URL.Query().Get("range"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < time.Second || parsed > api.history.Retention() {
+			sendJsonError("onServerMetricsRequest", w, IllegalRangeError, http.StatusBadRequest)
+			return
+		}
+		timeRange = parsed
+	}
+
+	step := api.history.Interval()
+	if value := r.URL.Query().Get("step"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < time.Second || parsed > timeRange || timeRange/parsed > MaxMetricsPoints {
+			sendJsonError("onServerMetricsRequest", w, IllegalStepError, http.StatusBadRequest)
+			return
+		}
+		step = parsed
+	} else if timeRange/step > MaxMetricsPoints {
+		step = (timeRange/MaxMetricsPoints + time.Second - 1).Truncate(time.Second)
+	}
+
+	samples := api.history.Query(name, timeRange, step)
+	points := make([]ServerMetricsPointDTO, len(samples))
+	for i, sample := range samples {
+		points[i] = sample.ToDTO()
+	}
+	response := ServerMetricsDTO{
+		Name:   name,
+		Range:  int(timeRange / time.Second),
+		Step:   int(step / time.Second),
+		Points: points,
+	}
+	sendJsonData("onServerMetricsRequest", w, response)
+}
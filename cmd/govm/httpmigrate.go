@@ -0,0 +1,152 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// onServerMigrateRequest moves the server to another configured host. A
+// running server is migrated live and a stopped server offline. A server
+// migrated before is moved from the host it is on, and the mode must be
+// given since its status is not known here. Only the admin user may move
+// servers between hosts.
+func (api *ApiServer) onServerMigrateRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerMigrateRequest", r)
+	_, ok := api.authorizeAdminRequest("onServerMigrateRequest", w, r)
+	if !ok {
+		return
+	}
+	name := mux.Vars(r)["name"]
+	if !ValidateName(name) {
+		sendJsonError("onServerMigrateRequest", w, NotFoundError, http.StatusNotFound)
+		return
+	}
+	if !HasServerActionCode(api.enabledActions, MigrateServerActionCode) {
+		sendJsonError("onServerMigrateRequest", w, ForbiddenError, http.StatusForbidden)
+		return
+	}
+
+	var requestBody MigrateServerDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onServerMigrateRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Mode != "" && requestBody.Mode != LiveMigrationMode && requestBody.Mode != OfflineMigrationMode {
+		sendJsonError("onServerMigrateRequest", w, IllegalMigrationModeError, http.StatusBadRequest)
+		return
+	}
+	config := api.config.GetConfig()
+	host := config.Hosts.findByName(requestBody.Host)
+	if host == nil {
+		sendJsonError("onServerMigrateRequest", w, HostNotFoundError, http.StatusNotFound)
+		return
+	}
+
+	item, err := api.service.FindServer(name)
+	if err != nil {
+		logAndSendJsonError(err, "onServerMigrateRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sourceURI := ""
+	mode := requestBody.Mode
+	if item != nil {
+		// Libvirt moves only the definition of a stopped server and the
+		// memory of a running server is copied only in a live migration
+		mode = OfflineMigrationMode
+		if item.Status != StoppedServerStatusCode {
+			mode = LiveMigrationMode
+		}
+		if requestBody.Mode != "" && requestBody.Mode != mode {
+			sendJsonError("onServerMigrateRequest", w, ActionNotAvailableError, http.StatusConflict)
+			return
+		}
+	} else {
+		item = api.getMovedServer(config, name)
+		if item == nil {
+			sendJsonError("onServerMigrateRequest", w, NotFoundError, http.StatusNotFound)
+			return
+		}
+		source := config.Hosts.findByName(config.Servers.findByName(name).Host)
+		if source.Name == host.Name {
+			sendJsonError("onServerMigrateRequest", w, ActionNotAvailableError, http.StatusConflict)
+			return
+		}
+		if mode == "" {
+			sendJsonError("onServerMigrateRequest", w, IllegalMigrationModeError, http.StatusBadRequest)
+			return
+		}
+		sourceURI = source.URI
+	}
+	if !HasServerActionCode(item.Status.GetAvailableActions(item.EnabledActions), MigrateServerActionCode) {
+		sendJsonError("onServerMigrateRequest", w, ActionNotAvailableError, http.StatusConflict)
+		return
+	}
+	if requestBody.CopyStorage && mode != LiveMigrationMode {
+		sendJsonError("onServerMigrateRequest", w, IllegalMigrationModeError, http.StatusBadRequest)
+		return
+	}
+
+	options := MigrateOptions{
+		URI:         host.URI,
+		SourceURI:   sourceURI,
+		Live:        mode == LiveMigrationMode,
+		CopyStorage: requestBody.CopyStorage,
+		Migrated: func() {
+			log.Printf("onServerMigrateRequest: Server %s is now on host %s", name, host.Name)
+			api.config.SetServerHostConfig(name, host.Name)
+
+			// The port forwards of this host cannot reach the server anymore
+			api.removeServerPortForwards(name)
+		},
+	}
+	item, err = api.service.MigrateServer(name, options)
+	if errors.Is(err, ErrOperationInProgress) {
+		sendJsonError("onServerMigrateRequest", w, OperationInProgressError, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logAndSendJsonError(err, "onServerMigrateRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	response := item.ToDTO()
+	api.setProject(api.config.GetConfig(), &response)
+	sendJsonData("onServerMigrateRequest", w, response)
+}
+
+// getMovedServer returns the server if it has been migrated to another
+// configured host and is not on this host, otherwise nil
+func (api *ApiServer) getMovedServer(config *Config, name string) *ServerModel {
+	server := config.Servers.findByName(name)
+	if server == nil || server.Host == "" || config.Hosts.findByName(server.Host) == nil {
+		return nil
+	}
+	return NewServerModel(name, MovedServerStatusCode, api.enabledActions)
+}
+
+// getMovedServers returns the servers migrated to other hosts which are not
+// in the list of the servers on this host
+func (api *ApiServer) getMovedServers(config *Config, local []*ServerModel) []*ServerModel {
+	var list []*ServerModel
+	for _, server := range config.Servers {
+		found := false
+		for _, item := range local {
+			if item.Name == server.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if item := api.getMovedServer(config, server.Name); item != nil {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
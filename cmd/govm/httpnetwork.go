@@ -0,0 +1,275 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func (api *ApiServer) onNetworkListRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onNetworkListRequest", r)
+	session := api.authenticateSession(r)
+	if session == nil {
+		sendJsonError("onNetworkListRequest", w, UnauthorizedError, http.StatusUnauthorized)
+		return
+	}
+	var networks NetworkModelList
+	var err error
+	isAdmin := api.isAdminSession(session)
+	if isAdmin {
+		networks, err = api.service.GetNetworkList()
+	} else {
+		networks, err = api.getAllowedNetworks()
+	}
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkListRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	payload := networks.ToDTO()
+	if !isAdmin {
+		// The leases and the hosts are the addresses of other users' servers
+		for i := range payload {
+			payload[i].Hosts = nil
+			payload[i].Leases = nil
+		}
+	}
+	response := NetworkListDTO{
+		Payload: payload,
+	}
+	sendJsonData("onNetworkListRequest", w, response)
+}
+
+func (api *ApiServer) onNetworkCreateRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onNetworkCreateRequest", r)
+	_, ok := api.authorizeAdminRequest("onNetworkCreateRequest", w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody CreateNetworkDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkCreateRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+
+	network, err := NewNetworkModelFromDTO(requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkCreateRequest", w, IllegalNetworkError, http.StatusBadRequest)
+		return
+	}
+
+	existing, err := api.service.FindNetwork(network.Source)
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkCreateRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		sendJsonError("onNetworkCreateRequest", w, NetworkExistsError, http.StatusConflict)
+		return
+	}
+
+	item, err := api.service.CreateNetwork(network)
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkCreateRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onNetworkCreateRequest", w, item.ToDTO())
+}
+
+func (api *ApiServer) onNetworkRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onNetworkRequest", r)
+	name, ok := api.authorizeNetworkRequest("onNetworkRequest", w, r)
+	if !ok {
+		return
+	}
+	item, err := api.service.FindNetwork(name)
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onNetworkRequest", w, item.ToDTO())
+}
+
+func (api *ApiServer) onNetworkStartRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onNetworkStartRequest", r)
+	name, ok := api.authorizeNetworkRequest("onNetworkStartRequest", w, r)
+	if !ok {
+		return
+	}
+	item, err := api.service.StartNetwork(name)
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkStartRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onNetworkStartRequest", w, item.ToDTO())
+}
+
+func (api *ApiServer) onNetworkStopRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onNetworkStopRequest", r)
+	name, ok := api.authorizeNetworkRequest("onNetworkStopRequest", w, r)
+	if !ok {
+		return
+	}
+	item, err := api.service.StopNetwork(name)
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkStopRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onNetworkStopRequest", w, item.ToDTO())
+}
+
+func (api *ApiServer) onNetworkDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onNetworkDeleteRequest", r)
+	name, ok := api.authorizeNetworkRequest("onNetworkDeleteRequest", w, r)
+	if !ok {
+		return
+	}
+	item, err := api.service.FindNetwork(name)
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkDeleteRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	err = api.service.DeleteNetwork(name)
+	if err != nil {
+		logAndSendJsonError(err, "onNetworkDeleteRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	item.Active = false
+	sendJsonData("onNetworkDeleteRequest", w, item.ToDTO())
+}
+
+func (api *ApiServer) onInterfaceAttachRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onInterfaceAttachRequest", r)
+	_, name, ok := api.authorizeServerRequest("onInterfaceAttachRequest", w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody NetworkInterfaceDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onInterfaceAttachRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+
+	// Static addresses are configured by cloud-init on the first boot only
+	if requestBody.Address != "" || requestBody.Gateway != "" || requestBody.MAC != "" {
+		sendJsonError("onInterfaceAttachRequest", w, IllegalInterfaceError, http.StatusBadRequest)
+		return
+	}
+
+	nic, err := NewNetworkInterfaceModelFromDTO(requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onInterfaceAttachRequest", w, IllegalInterfaceError, http.StatusBadRequest)
+		return
+	}
+
+	networks, err := api.getAllowedNetworks()
+	if err != nil {
+		logAndSendJsonError(err, "onInterfaceAttachRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if networks.find(nic.Type, nic.Source) == nil {
+		sendJsonError("onInterfaceAttachRequest", w, NetworkNotAllowedError, http.StatusBadRequest)
+		return
+	}
+
+	item, err := api.service.AttachInterface(name, nic)
+	if errors.Is(err, ErrAddressInUse) {
+		logAndSendJsonError(err, "onInterfaceAttachRequest", w, AddressInUseError, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logAndSendJsonError(err, "onInterfaceAttachRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendServerData("onInterfaceAttachRequest", w, item)
+}
+
+func (api *ApiServer) onInterfaceDetachRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onInterfaceDetachRequest", r)
+	_, name, ok := api.authorizeServerRequest("onInterfaceDetachRequest", w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	mac := strings.ToLower(vars["mac"])
+
+	server, err := api.service.FindServer(name)
+	if err != nil {
+		logAndSendJsonError(err, "onInterfaceDetachRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if server == nil {
+		sendJsonError("onInterfaceDetachRequest", w, NotFoundError, http.StatusNotFound)
+		return
+	}
+	if server.Interfaces.findByMAC(mac) == nil {
+		sendJsonError("onInterfaceDetachRequest", w, InterfaceNotFoundError, http.StatusNotFound)
+		return
+	}
+
+	item, err := api.service.DetachInterface(name, mac)
+	if err != nil {
+		logAndSendJsonError(err, "onInterfaceDetachRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendServerData("onInterfaceDetachRequest", w, item)
+}
+
+// getAllowedNetworks returns the networks servers may be connected to. These
+// are the networks declared in the config, or if none are declared, all
+// libvirt networks of the host.
+func (api *ApiServer) getAllowedNetworks() (NetworkModelList, error) {
+	discovered, err := api.service.GetNetworkList()
+	if err != nil {
+		return nil, err
+	}
+	config := api.config.GetConfig()
+	if len(config.Networks) == 0 {
+		return discovered, nil
+	}
+	var networks NetworkModelList
+	for _, item := range config.Networks {
+		found := discovered.find(item.Type, item.Source)
+		if found != nil {
+			networks = append(networks, found)
+		} else if item.Type != NetworkInterfaceType {
+			networks = append(networks, NewNetworkModel(item.Type, item.Source, item.Source, true))
+		}
+	}
+	return networks, nil
+}
+
+// authorizeNetworkRequest checks the session belongs to the admin user and the
+// libvirt network from the path exists. If it returns false, an error has been
+// sent.
+func (api *ApiServer) authorizeNetworkRequest(method string, w http.ResponseWriter, r *http.Request) (string, bool) {
+	_, ok := api.authorizeAdminRequest(method, w, r)
+	if !ok {
+		return "", false
+	}
+	vars := mux.Vars(r)
+	name := vars["network"]
+	if !ValidateName(name) {
+		sendJsonError(method, w, IllegalNameError, http.StatusBadRequest)
+		return "", false
+	}
+	item, err := api.service.FindNetwork(name)
+	if err != nil {
+		logAndSendJsonError(err, method, w, InternalServerError, http.StatusInternalServerError)
+		return "", false
+	}
+	if item == nil {
+		sendJsonError(method, w, NetworkNotFoundError, http.StatusNotFound)
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (api *ApiServer) onPortForwardListRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onPortForwardListRequest", r)
+	_, name, ok := api.authorizeServerRequest("onPortForwardListRequest", w, r)
+	if !ok {
+		return
+	}
+	config := api.config.GetConfig()
+	response := PortForwardListDTO{
+		Payload: config.Forwards.filterByServer(name).ToDTO(),
+	}
+	sendJsonData("onPortForwardListRequest", w, response)
+}
+
+func (api *ApiServer) onPortForwardAddRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onPortForwardAddRequest", r)
+	_, name, ok := api.authorizeServerRequest("onPortForwardAddRequest", w, r)
+	if !ok {
+		return
+	}
+	if !api.permissions.NetworkEnabled {
+		sendJsonError("onPortForwardAddRequest", w, PortForwardDisabledError, http.StatusForbidden)
+		return
+	}
+
+	var requestBody CreatePortForwardDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onPortForwardAddRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Protocol == "" {
+		requestBody.Protocol = TCPProtocol
+	}
+	if requestBody.Protocol != TCPProtocol && requestBody.Protocol != UDPProtocol ||
+		requestBody.GuestPort < 1 || requestBody.GuestPort > 65535 ||
+		requestBody.HostPort != 0 && !api.forwarder.InRange(requestBody.HostPort) {
+		sendJsonError("onPortForwardAddRequest", w, IllegalPortForwardError, http.StatusBadRequest)
+		return
+	}
+
+	server, err := api.service.FindServer(name)
+	if err != nil {
+		logAndSendJsonError(err, "onPortForwardAddRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if server == nil {
+		sendJsonError("onPortForwardAddRequest", w, NotFoundError, http.StatusNotFound)
+		return
+	}
+
+	// The forward may only target the addresses of this server, otherwise it
+	// would relay traffic to the host or to other servers
+	addresses, err := api.getServerGuestAddresses(server)
+	if err != nil {
+		logAndSendJsonError(err, "onPortForwardAddRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	guestAddress := requestBody.GuestAddress
+	if guestAddress == "" {
+		if len(addresses) == 0 {
+			sendJsonError("onPortForwardAddRequest", w, GuestAddressNotFoundError, http.StatusBadRequest)
+			return
+		}
+		guestAddress = addresses[0]
+	} else if !containsAddress(addresses, guestAddress) {
+		sendJsonError("onPortForwardAddRequest", w, IllegalPortForwardError, http.StatusBadRequest)
+		return
+	}
+
+	api.forwardMutex.Lock()
+	defer api.forwardMutex.Unlock()
+
+	config := api.config.GetConfig()
+	hostPort := requestBody.HostPort
+	if hostPort == 0 {
+		hostPort, err = api.forwarder.AllocatePort(requestBody.Protocol, config.Forwards)
+		if err != nil {
+			logAndSendJsonError(err, "onPortForwardAddRequest", w, PortAllocationError, http.StatusConflict)
+			return
+		}
+	} else if config.Forwards.find(requestBody.Protocol, hostPort) != nil {
+		sendJsonError("onPortForwardAddRequest", w, PortForwardExistsError, http.StatusConflict)
+		return
+	}
+
+	forward := &PortForwardConfig{
+		Server:       name,
+		Protocol:     requestBody.Protocol,
+		HostPort:     hostPort,
+		GuestAddress: guestAddress,
+		GuestPort:    requestBody.GuestPort,
+	}
+	err = api.forwarder.Add(forward)
+	if err != nil {
+		logAndSendJsonError(err, "onPortForwardAddRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	api.config.AddPortForwardConfig(forward)
+	sendJsonData("onPortForwardAddRequest", w, forward.ToDTO())
+}
+
+func (api *ApiServer) onPortForwardDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onPortForwardDeleteRequest", r)
+	_, name, ok := api.authorizeServerRequest("onPortForwardDeleteRequest", w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	hostPort, err := strconv.Atoi(vars["port"])
+	if err != nil {
+		logAndSendJsonError(err, "onPortForwardDeleteRequest", w, IllegalPortForwardError, http.StatusBadRequest)
+		return
+	}
+
+	config := api.config.GetConfig()
+	forward := config.Forwards.find(vars["protocol"], hostPort)
+	if forward == nil || forward.Server != name {
+		sendJsonError("onPortForwardDeleteRequest", w, PortForwardNotFoundError, http.StatusNotFound)
+		return
+	}
+
+	api.removePortForward(forward)
+	sendJsonData("onPortForwardDeleteRequest", w, forward.ToDTO())
+}
+
+// removeServerPortForwards stops and forgets all port forwards of the server
+func (api *ApiServer) removeServerPortForwards(name string) {
+	config := api.config.GetConfig()
+	forwards := config.Forwards.filterByServer(name)
+	if len(forwards) == 0 {
+		return
+	}
+	for _, forward := range forwards {
+		err := api.forwarder.Remove(forward.Protocol, forward.HostPort)
+		if err != nil {
+			log.Printf("removeServerPortForwards: Warning! %v", err)
+		}
+	}
+	api.config.RemoveServerPortForwardConfigs(name)
+}
+
+// removePortForward stops and forgets a port forward
+func (api *ApiServer) removePortForward(forward *PortForwardConfig) {
+	err := api.forwarder.Remove(forward.Protocol, forward.HostPort)
+	if err != nil {
+		log.Printf("removePortForward: Warning! %v", err)
+	}
+	api.config.RemovePortForwardConfig(forward)
+}
+
+// getServerGuestAddresses returns the known IPv4 addresses of the interfaces
+// of the server. Each is either the static address of the interface or the
+// static DHCP lease reserved for its MAC.
+func (api *ApiServer) getServerGuestAddresses(server *ServerModel) ([]string, error) {
+	var addresses []string
+	for _, nic := range server.Interfaces {
+		if nic.HasStaticAddress() {
+			addresses = append(addresses, nic.Address)
+			continue
+		}
+		if nic.Type != NetworkInterfaceType {
+			continue
+		}
+		network, err := api.service.FindNetwork(nic.Source)
+		if err != nil {
+			return nil, err
+		}
+		if network == nil {
+			continue
+		}
+		lease := network.Leases.findByMAC(nic.MAC)
+		if lease != nil {
+			addresses = append(addresses, lease.IP)
+		}
+	}
+	return addresses, nil
+}
+
+// containsAddress returns true if the IP address is in the list
+func containsAddress(addresses []string, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, item := range addresses {
+		if ip.Equal(net.ParseIP(item)) {
+			return true
+		}
+	}
+	return false
+}
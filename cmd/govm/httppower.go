@@ -0,0 +1,156 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+func (api *ApiServer) onServerForceOffRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerForceOffRequest", r)
+	api.handleServerAction("onServerForceOffRequest", w, r, ForceOffServerActionCode, api.service.ForceOffServer)
+}
+
+func (api *ApiServer) onServerResetRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerResetRequest", r)
+	api.handleServerAction("onServerResetRequest", w, r, ResetServerActionCode, api.service.ResetServer)
+}
+
+func (api *ApiServer) onServerPauseRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerPauseRequest", r)
+	api.handleServerAction("onServerPauseRequest", w, r, PauseServerActionCode, api.service.PauseServer)
+}
+
+func (api *ApiServer) onServerResumeRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerResumeRequest", r)
+	api.handleServerAction("onServerResumeRequest", w, r, ResumeServerActionCode, api.service.ResumeServer)
+}
+
+func (api *ApiServer) onServerSuspendRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerSuspendRequest", r)
+	api.handleServerAction("onServerSuspendRequest", w, r, SuspendServerActionCode, api.service.SuspendServer)
+}
+
+func (api *ApiServer) onServerHibernateRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerHibernateRequest", r)
+	api.handleServerAction("onServerHibernateRequest", w, r, HibernateServerActionCode, api.service.HibernateServer)
+}
+
+func (api *ApiServer) onServerRestoreRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerRestoreRequest", r)
+	api.handleServerAction("onServerRestoreRequest", w, r, RestoreServerActionCode, api.service.RestoreServer)
+}
+
+// onServerKeysRequest presses a key combination on the keyboard of the server
+func (api *ApiServer) onServerKeysRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerKeysRequest", r)
+	_, name, ok := api.authorizeServerRequest("onServerKeysRequest", w, r)
+	if !ok {
+		return
+	}
+	if !api.permissions.ConsoleEnabled {
+		sendJsonError("onServerKeysRequest", w, ForbiddenError, http.StatusForbidden)
+		return
+	}
+
+	var requestBody SendKeysDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onServerKeysRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	keys, err := ParseKeyCombo(requestBody.Keys)
+	if err != nil {
+		logAndSendJsonError(err, "onServerKeysRequest", w, IllegalKeysError, http.StatusBadRequest)
+		return
+	}
+
+	err = api.service.SendKeys(name, keys)
+	if err != nil {
+		logAndSendJsonError(err, "onServerKeysRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendJsonData("onServerKeysRequest", w, requestBody)
+}
+
+// authorizeServerAction checks the user may access the server and the action
+// is enabled and available in the current status of the server
+func (api *ApiServer) authorizeServerAction(method string, w http.ResponseWriter, r *http.Request, code ServerActionCode) (string, bool) {
+	_, name, ok := api.authorizeServerRequest(method, w, r)
+	if !ok {
+		return "", false
+	}
+	if !api.checkServerAction(method, w, name, code) {
+		return "", false
+	}
+	return name, true
+}
+
+// checkServerAction checks the action is enabled and available in the
+// current status of the server. If it returns false, an error has been sent.
+func (api *ApiServer) checkServerAction(method string, w http.ResponseWriter, name string, code ServerActionCode) bool {
+	if !HasServerActionCode(api.enabledActions, code) {
+		sendJsonError(method, w, ForbiddenError, http.StatusForbidden)
+		return false
+	}
+
+	item, err := api.service.FindServer(name)
+	if err != nil {
+		logAndSendJsonError(err, method, w, InternalServerError, http.StatusInternalServerError)
+		return false
+	}
+	if item == nil {
+		sendJsonError(method, w, NotFoundError, http.StatusNotFound)
+		return false
+	}
+	if !HasServerActionCode(item.Status.GetAvailableActions(item.EnabledActions), code) {
+		sendJsonError(method, w, ActionNotAvailableError, http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// parseStopOptions reads the optional body of a stop or restart request
+func parseStopOptions(method string, w http.ResponseWriter, r *http.Request) (StopOptions, bool) {
+	options := StopOptions{Timeout: DefaultStopTimeout}
+	var requestBody StopServerDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil && err != io.EOF {
+		logAndSendJsonError(err, method, w, BadBodyError, http.StatusBadRequest)
+		return options, false
+	}
+	// Compare in seconds, since a large timeout would overflow the duration
+	if requestBody.Timeout < 0 || requestBody.Timeout > int(MaxStopTimeout/time.Second) {
+		sendJsonError(method, w, IllegalTimeoutError, http.StatusBadRequest)
+		return options, false
+	}
+	if requestBody.Timeout > 0 {
+		options.Timeout = time.Duration(requestBody.Timeout) * time.Second
+	}
+	options.Force = requestBody.Force
+	return options, true
+}
+
+// handleServerAction performs the action if it is available in the current
+// status of the server and sends the server as a response
+func (api *ApiServer) handleServerAction(method string, w http.ResponseWriter, r *http.Request, code ServerActionCode, action func(name string) (*ServerModel, error)) {
+	name, ok := api.authorizeServerAction(method, w, r, code)
+	if !ok {
+		return
+	}
+
+	item, err := action(name)
+	if errors.Is(err, ErrOperationInProgress) {
+		sendJsonError(method, w, OperationInProgressError, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logAndSendJsonError(err, method, w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendServerData(method, w, item)
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseStopOptionsTimeout(t *testing.T) {
+	// 9223372037 seconds overflows time.Duration into a negative value
+	for _, body := range []string{`{"timeout":9223372037,"force":true}`, `{"timeout":3601}`, `{"timeout":-1}`} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/servers/web/stop", strings.NewReader(body))
+		if _, ok := parseStopOptions("test", w, r); ok || w.Code != http.StatusBadRequest {
+			t.Errorf("parseStopOptions(%s) accepted the timeout", body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/servers/web/stop", strings.NewReader(`{"timeout":3600}`))
+	options, ok := parseStopOptions("test", w, r)
+	if !ok || options.Timeout != MaxStopTimeout {
+		t.Errorf("parseStopOptions = %+v, %v, want the maximum timeout", options, ok)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// onProjectListRequest returns the projects the user is a member of
+func (api *ApiServer) onProjectListRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onProjectListRequest", r)
+	session := api.authenticateSession(r)
+	if session == nil {
+		sendJsonError("onProjectListRequest", w, UnauthorizedError, http.StatusUnauthorized)
+		return
+	}
+	config := api.config.GetConfig()
+	projects := config.GetProjectsForEmail(session.Email)
+	response := ProjectListDTO{
+		Payload: make([]ProjectDTO, len(projects)),
+	}
+	for i, item := range projects {
+		response.Payload[i] = item.ToDTO(session.Email, config.CountProjectServers(item.Name))
+	}
+	sendJsonData("onProjectListRequest", w, response)
+}
+
+// onProjectCreateRequest creates a project owned by the user
+func (api *ApiServer) onProjectCreateRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onProjectCreateRequest", r)
+	session := api.authenticateSession(r)
+	if session == nil {
+		sendJsonError("onProjectCreateRequest", w, UnauthorizedError, http.StatusUnauthorized)
+		return
+	}
+
+	var requestBody CreateProjectDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onProjectCreateRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	if !ValidateName(requestBody.Name) {
+		sendJsonError("onProjectCreateRequest", w, IllegalNameError, http.StatusBadRequest)
+		return
+	}
+
+	err = api.config.AddProjectConfig(requestBody.Name, session.Email)
+	if !sendProjectError("onProjectCreateRequest", w, err) {
+		return
+	}
+	api.sendProject("onProjectCreateRequest", w, session, requestBody.Name)
+}
+
+// onProjectDeleteRequest deletes a project which has no servers
+func (api *ApiServer) onProjectDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onProjectDeleteRequest", r)
+	session, project, ok := api.authorizeProjectOwnerRequest("onProjectDeleteRequest", w, r)
+	if !ok {
+		return
+	}
+
+	err := api.config.RemoveProjectConfig(project.Name)
+	if !sendProjectError("onProjectDeleteRequest", w, err) {
+		return
+	}
+	sendJsonData("onProjectDeleteRequest", w, project.ToDTO(session.Email, 0))
+}
+
+// onProjectMemberSetRequest adds a member to the project or changes the role
+// of the member
+func (api *ApiServer) onProjectMemberSetRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onProjectMemberSetRequest", r)
+	session, project, ok := api.authorizeProjectOwnerRequest("onProjectMemberSetRequest", w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody ProjectMemberDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onProjectMemberSetRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	if !ValidateEmail(requestBody.Email) {
+		sendJsonError("onProjectMemberSetRequest", w, IllegalEmailError, http.StatusBadRequest)
+		return
+	}
+	if !IsProjectRole(requestBody.Role) {
+		sendJsonError("onProjectMemberSetRequest", w, IllegalRoleError, http.StatusBadRequest)
+		return
+	}
+
+	err = api.config.SetProjectMemberConfig(project.Name, requestBody.Email, requestBody.Role)
+	if !sendProjectError("onProjectMemberSetRequest", w, err) {
+		return
+	}
+	api.sendProject("onProjectMemberSetRequest", w, session, project.Name)
+}
+
+// onProjectMemberDeleteRequest removes a member from the project
+func (api *ApiServer) onProjectMemberDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onProjectMemberDeleteRequest", r)
+	session, project, ok := api.authorizeProjectOwnerRequest("onProjectMemberDeleteRequest", w, r)
+	if !ok {
+		return
+	}
+
+	err := api.config.RemoveProjectMemberConfig(project.Name, mux.Vars(r)["email"])
+	if !sendProjectError("onProjectMemberDeleteRequest", w, err) {
+		return
+	}
+	api.sendProject("onProjectMemberDeleteRequest", w, session, project.Name)
+}
+
+// authorizeProjectOwnerRequest finds the project from the path and checks the
+// session belongs to an owner of the project or to the admin user. If it
+// returns false, an error has been sent.
+func (api *ApiServer) authorizeProjectOwnerRequest(method string, w http.ResponseWriter, r *http.Request) (*Session, *ProjectConfig, bool) {
+	session := api.authenticateSession(r)
+	if session == nil {
+		sendJsonError(method, w, UnauthorizedError, http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	config := api.config.GetConfig()
+	project := config.Projects.findByName(mux.Vars(r)["project"])
+	isAdmin := api.isAdminSession(session)
+	if project == nil || (project.GetRole(session.Email) == "" && !isAdmin) {
+		sendJsonError(method, w, ProjectNotFoundError, http.StatusNotFound)
+		return nil, nil, false
+	}
+	if project.GetRole(session.Email) != OwnerProjectRole && !isAdmin {
+		sendJsonError(method, w, ForbiddenError, http.StatusForbidden)
+		return nil, nil, false
+	}
+	return session, project, true
+}
+
+// sendProjectError sends the error of a project change if there is one and
+// returns true if there was none
+func sendProjectError(method string, w http.ResponseWriter, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, ErrProjectNotFound) {
+		sendJsonError(method, w, ProjectNotFoundError, http.StatusNotFound)
+	} else if errors.Is(err, ErrProjectMemberNotFound) {
+		sendJsonError(method, w, NotFoundError, http.StatusNotFound)
+	} else if errors.Is(err, ErrProjectExists) {
+		sendJsonError(method, w, ProjectExistsError, http.StatusConflict)
+	} else if errors.Is(err, ErrProjectNotEmpty) {
+		sendJsonError(method, w, ProjectNotEmptyError, http.StatusConflict)
+	} else if errors.Is(err, ErrLastOwner) {
+		sendJsonError(method, w, LastOwnerError, http.StatusConflict)
+	} else {
+		logAndSendJsonError(err, method, w, InternalServerError, http.StatusInternalServerError)
+	}
+	return false
+}
+
+// sendProject sends the project from the current config
+func (api *ApiServer) sendProject(method string, w http.ResponseWriter, session *Session, name string) {
+	config := api.config.GetConfig()
+	project := config.Projects.findByName(name)
+	if project == nil {
+		// Removed by another request after the change
+		sendJsonError(method, w, ProjectNotFoundError, http.StatusNotFound)
+		return
+	}
+	sendJsonData(method, w, project.ToDTO(session.Email, config.CountProjectServers(name)))
+}
+
+// getNewServerProject returns the project a new server is created in: the
+// requested project, or the default project of the user. The project is empty
+// if the user is not in any project. If it returns false, an error has been
+// sent.
+func (api *ApiServer) getNewServerProject(method string, w http.ResponseWriter, config *Config, email, requested string) (string, bool) {
+	if requested == "" {
+		project := config.GetDefaultProjectForEmail(email)
+		if project == nil {
+			return "", true
+		}
+		return project.Name, true
+	}
+	project := config.Projects.findByName(requested)
+	if project == nil || project.GetRole(email) == "" {
+		sendJsonError(method, w, ProjectNotFoundError, http.StatusNotFound)
+		return "", false
+	}
+	return project.Name, true
+}
+
+// setProject adds the project and the host of the server from the config
+func (api *ApiServer) setProject(config *Config, item *ServerDTO) {
+	server := config.Servers.findByName(item.Name)
+	if server != nil {
+		item.Project = server.Project
+		item.Host = server.Host
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// lockQuotas locks quotaMutex if any quota applies to the user and returns
+// the function which unlocks it. The lock is held from checkQuotas until the
+// resources have been created, so users without quotas do not wait for it.
+func (api *ApiServer) lockQuotas(email string) func() {
+	if !api.config.GetConfig().HasQuotas(email) {
+		return func() {}
+	}
+	api.quotaMutex.Lock()
+	return api.quotaMutex.Unlock
+}
+
+// checkQuotas checks the requested resources fit in the quotas of the user.
+// The caller holds the lock from lockQuotas until the resources have been
+// created. If it returns false, an error has been sent.
+func (api *ApiServer) checkQuotas(method string, w http.ResponseWriter, email string, request QuotaUsageModel) bool {
+	config := api.config.GetConfig()
+	if !config.HasQuotas(email) {
+		return true
+	}
+	servers, err := api.service.GetServerList()
+	if err != nil {
+		logAndSendJsonError(err, method, w, InternalServerError, http.StatusInternalServerError)
+		return false
+	}
+	err = CheckQuotas(config.GetQuotas(email, servers), request)
+	if errors.Is(err, ErrQuotaExceeded) {
+		logAndSendJsonError(err, method, w, QuotaExceededError, http.StatusForbidden)
+		return false
+	}
+	if err != nil {
+		logAndSendJsonError(err, method, w, InternalServerError, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// getQuotaList returns the quotas of the user with their usage, or nil if
+// no quota applies to the user
+func (api *ApiServer) getQuotaList(email string) ([]QuotaDTO, error) {
+	config := api.config.GetConfig()
+	if !config.HasQuotas(email) {
+		return nil, nil
+	}
+	servers, err := api.service.GetServerList()
+	if err != nil {
+		return nil, err
+	}
+	quotas := config.GetQuotas(email, servers)
+	list := make([]QuotaDTO, len(quotas))
+	for i, quota := range quotas {
+		list[i] = quota.ToDTO()
+	}
+	return list, nil
+}
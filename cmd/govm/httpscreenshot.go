@@ -0,0 +1,72 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// onScreenshotRequest sends a PNG screenshot of the server. With
+// ?thumbnail=true the cached thumbnail is sent instead.
+func (api *ApiServer) onScreenshotRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onScreenshotRequest", r)
+	_, name, ok := api.authorizeServerRequest("onScreenshotRequest", w, r)
+	if !ok {
+		return
+	}
+	if !api.permissions.ConsoleEnabled {
+		sendJsonError("onScreenshotRequest", w, ScreenshotDisabledError, http.StatusForbidden)
+		return
+	}
+
+	if r.URL.Query().Get("thumbnail") == "true" {
+		var thumbnail *Thumbnail
+		if api.thumbnails != nil {
+			thumbnail = api.thumbnails.Get(name)
+		}
+		if thumbnail == nil {
+			sendJsonError("onScreenshotRequest", w, ThumbnailNotFoundError, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Last-Modified", thumbnail.Updated.UTC().Format(http.TimeFormat))
+		sendPNGData("onScreenshotRequest", w, thumbnail.Data)
+		return
+	}
+
+	img, err := api.service.GetScreenshot(name)
+	if err != nil {
+		logAndSendJsonError(err, "onScreenshotRequest", w, ScreenshotError, http.StatusInternalServerError)
+		return
+	}
+	data, err := encodePNG(img)
+	if err != nil {
+		logAndSendJsonError(err, "onScreenshotRequest", w, ScreenshotError, http.StatusInternalServerError)
+		return
+	}
+	sendPNGData("onScreenshotRequest", w, data)
+}
+
+func sendPNGData(method string, w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "no-store")
+	_, err := w.Write(data)
+	if err != nil {
+		log.Printf("%s: writing: error: %v", method, err)
+	}
+}
+
+// setThumbnailURL adds the thumbnail URL to the server if one is cached. The
+// time in the URL changes when the thumbnail is refreshed.
+func (api *ApiServer) setThumbnailURL(item *ServerDTO) {
+	if api.thumbnails == nil {
+		return
+	}
+	thumbnail := api.thumbnails.Get(item.Name)
+	if thumbnail != nil {
+		item.Thumbnail = fmt.Sprintf("/api/v1/servers/%s/screenshot?thumbnail=true&t=%d", item.Name, thumbnail.Updated.Unix())
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// serialToken is a single use token for a serial console WebSocket
+type serialToken struct {
+	Server  string
+	Expires time.Time
+}
+
+// onSerialOpen creates a single use token for the serial console WebSocket,
+// since browsers cannot send the Authorization header with WebSockets. Unused
+// tokens expire after SerialTokenTTL.
+func (api *ApiServer) onSerialOpen(w http.ResponseWriter, r *http.Request) {
+	logRequest("onSerialOpen", r)
+	_, name, ok := api.authorizeServerRequest("onSerialOpen", w, r)
+	if !ok {
+		return
+	}
+	if !api.permissions.ConsoleEnabled {
+		sendJsonError("onSerialOpen", w, SerialConsoleDisabledError, http.StatusForbidden)
+		return
+	}
+
+	token, err := generatePassword(32)
+	if err != nil {
+		logAndSendJsonError(err, "onSerialOpen", w, SerialGenerateTokenError, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	api.serialMutex.Lock()
+	for key, item := range api.serialSessions {
+		if !now.Before(item.Expires) {
+			delete(api.serialSessions, key)
+		}
+	}
+	api.serialSessions[token] = &serialToken{Server: name, Expires: now.Add(SerialTokenTTL)}
+	api.serialMutex.Unlock()
+
+	path := fmt.Sprintf("api/v1/servers/%s/serial?token=%s", name, token)
+	response := ServerSerialDTO{
+		URL:   "/api/serial/serial.html?path=" + url.QueryEscape(path),
+		WS:    "/" + path,
+		Token: token,
+	}
+	sendJsonData("onSerialOpen", w, response)
+}
+
+// onSerialWebSocket connects the WebSocket to the serial console of the server
+func (api *ApiServer) onSerialWebSocket(w http.ResponseWriter, r *http.Request) {
+	logRequest("onSerialWebSocket", r)
+
+	token := r.URL.Query().Get("token")
+	api.serialMutex.Lock()
+	item, exists := api.serialSessions[token]
+	delete(api.serialSessions, token)
+	api.serialMutex.Unlock()
+	if !exists || !time.Now().Before(item.Expires) {
+		sendJsonError("onSerialWebSocket", w, UnauthorizedError, http.StatusUnauthorized)
+		return
+	}
+	name := item.Server
+
+	console, err := api.service.OpenSerialConsole(name)
+	if err != nil {
+		logAndSendJsonError(err, "onSerialWebSocket", w, SerialConsoleOpenError, http.StatusInternalServerError)
+		return
+	}
+	defer console.Close()
+
+	wsConn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("onSerialWebSocket: Upgrade error: %v", err)
+		return
+	}
+	defer wsConn.Close()
+	log.Printf("onSerialWebSocket: Serial console of %s opened", name)
+
+	// Forward keystrokes and pasted text to the console
+	go func() {
+		defer console.Close()
+		for {
+			_, message, err := wsConn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_, err = console.Write(message)
+			if err != nil {
+				log.Printf("onSerialWebSocket: Write error: %v", err)
+				return
+			}
+		}
+	}()
+
+	// Forward the console output to the WebSocket
+	buffer := make([]byte, 4096)
+	for {
+		n, err := console.Read(buffer)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("onSerialWebSocket: Read error: %v", err)
+			}
+			break
+		}
+		err = wsConn.WriteMessage(websocket.BinaryMessage, buffer[:n])
+		if err != nil {
+			log.Printf("onSerialWebSocket: Write error: %v", err)
+			break
+		}
+	}
+	log.Printf("onSerialWebSocket: Serial console of %s closed", name)
+}
+
+// onConsoleLogRequest returns the last lines of the serial output of the
+// server, e.g. the boot and cloud-init messages
+func (api *ApiServer) onConsoleLogRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onConsoleLogRequest", r)
+	_, name, ok := api.authorizeServerRequest("onConsoleLogRequest", w, r)
+	if !ok {
+		return
+	}
+
+	tail := DefaultConsoleLogTail
+	if value := r.URL.Query().Get("tail"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > MaxConsoleLogTail {
+			sendJsonError("onConsoleLogRequest", w, TailParseError, http.StatusBadRequest)
+			return
+		}
+		tail = parsed
+	}
+
+	lines, err := api.service.GetConsoleLog(name, tail)
+	if err != nil {
+		logAndSendJsonError(err, "onConsoleLogRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if lines == nil {
+		lines = []string{}
+	}
+	response := ConsoleLogDTO{
+		Payload: lines,
+	}
+	sendJsonData("onConsoleLogRequest", w, response)
+}
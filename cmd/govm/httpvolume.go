@@ -0,0 +1,185 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (api *ApiServer) onServerResizeRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onServerResizeRequest", r)
+	session, name, ok := api.authorizeServerRequest("onServerResizeRequest", w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody ResizeServerDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onServerResizeRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Size == 0 {
+		sendJsonError("onServerResizeRequest", w, IllegalSizeError, http.StatusBadRequest)
+		return
+	}
+
+	server, err := api.service.FindServer(name)
+	if err != nil {
+		logAndSendJsonError(err, "onServerResizeRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if server == nil {
+		sendJsonError("onServerResizeRequest", w, NotFoundError, http.StatusNotFound)
+		return
+	}
+
+	unlock := api.lockQuotas(session.Email)
+	defer unlock()
+	var request QuotaUsageModel
+	for _, volume := range server.Volumes {
+		if volume.Root && requestBody.Size > volume.Size {
+			request.Disk = requestBody.Size - volume.Size
+		}
+	}
+	if !api.checkQuotas("onServerResizeRequest", w, session.Email, request) {
+		return
+	}
+
+	item, err := api.service.ResizeServer(name, requestBody.Size)
+	if err != nil {
+		logAndSendJsonError(err, "onServerResizeRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendServerData("onServerResizeRequest", w, item)
+}
+
+func (api *ApiServer) onVolumeAddRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onVolumeAddRequest", r)
+	session, name, ok := api.authorizeServerRequest("onVolumeAddRequest", w, r)
+	if !ok {
+		return
+	}
+
+	var requestBody CreateVolumeDTO
+	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	if err != nil {
+		logAndSendJsonError(err, "onVolumeAddRequest", w, BadBodyError, http.StatusBadRequest)
+		return
+	}
+	if !ValidateName(requestBody.Name) {
+		sendJsonError("onVolumeAddRequest", w, IllegalVolumeNameError, http.StatusBadRequest)
+		return
+	}
+	if requestBody.Size == 0 {
+		sendJsonError("onVolumeAddRequest", w, IllegalSizeError, http.StatusBadRequest)
+		return
+	}
+
+	server, err := api.service.FindServer(name)
+	if err != nil {
+		logAndSendJsonError(err, "onVolumeAddRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	if server == nil {
+		sendJsonError("onVolumeAddRequest", w, NotFoundError, http.StatusNotFound)
+		return
+	}
+	if server.Volumes.findByName(requestBody.Name) != nil {
+		sendJsonError("onVolumeAddRequest", w, VolumeExistsError, http.StatusConflict)
+		return
+	}
+
+	unlock := api.lockQuotas(session.Email)
+	defer unlock()
+	request := QuotaUsageModel{
+		Disk: requestBody.Size,
+	}
+	if !api.checkQuotas("onVolumeAddRequest", w, session.Email, request) {
+		return
+	}
+
+	item, err := api.service.AddVolume(name, requestBody.Name, requestBody.Size)
+	if err != nil {
+		logAndSendJsonError(err, "onVolumeAddRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendServerData("onVolumeAddRequest", w, item)
+}
+
+func (api *ApiServer) onVolumeAttachRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onVolumeAttachRequest", r)
+	name, volume, ok := api.authorizeVolumeRequest("onVolumeAttachRequest", w, r)
+	if !ok {
+		return
+	}
+	item, err := api.service.AttachVolume(name, volume)
+	if err != nil {
+		logAndSendJsonError(err, "onVolumeAttachRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendServerData("onVolumeAttachRequest", w, item)
+}
+
+func (api *ApiServer) onVolumeDetachRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onVolumeDetachRequest", r)
+	name, volume, ok := api.authorizeVolumeRequest("onVolumeDetachRequest", w, r)
+	if !ok {
+		return
+	}
+	item, err := api.service.DetachVolume(name, volume)
+	if err != nil {
+		logAndSendJsonError(err, "onVolumeDetachRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendServerData("onVolumeDetachRequest", w, item)
+}
+
+func (api *ApiServer) onVolumeDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onVolumeDeleteRequest", r)
+	name, volume, ok := api.authorizeVolumeRequest("onVolumeDeleteRequest", w, r)
+	if !ok {
+		return
+	}
+	item, err := api.service.DeleteVolume(name, volume)
+	if err != nil {
+		logAndSendJsonError(err, "onVolumeDeleteRequest", w, InternalServerError, http.StatusInternalServerError)
+		return
+	}
+	sendServerData("onVolumeDeleteRequest", w, item)
+}
+
+// authorizeVolumeRequest checks access to the server and that the data volume
+// from the path exists. If it returns false, an error has been sent.
+func (api *ApiServer) authorizeVolumeRequest(method string, w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	_, name, ok := api.authorizeServerRequest(method, w, r)
+	if !ok {
+		return "", "", false
+	}
+
+	vars := mux.Vars(r)
+	volume := vars["volume"]
+	if !ValidateName(volume) {
+		sendJsonError(method, w, VolumeNotFoundError, http.StatusNotFound)
+		return "", "", false
+	}
+
+	server, err := api.service.FindServer(name)
+	if err != nil {
+		logAndSendJsonError(err, method, w, InternalServerError, http.StatusInternalServerError)
+		return "", "", false
+	}
+	if server == nil {
+		sendJsonError(method, w, NotFoundError, http.StatusNotFound)
+		return "", "", false
+	}
+	item := server.Volumes.findByName(volume)
+	if item == nil || item.Root {
+		sendJsonError(method, w, VolumeNotFoundError, http.StatusNotFound)
+		return "", "", false
+	}
+	return name, volume, true
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+var ErrAddressInUse = errors.New("the address is leased to another interface")
+
+// NetworkLocks serializes the allocation and the registration of the DHCP
+// leases of each network, so concurrent servers never get the same address
+type NetworkLocks struct {
+	mutex sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func NewNetworkLocks() *NetworkLocks {
+	return &NetworkLocks{
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+// Lock locks the network and returns the function to unlock it
+func (l *NetworkLocks) Lock(network string) func() {
+	l.mutex.Lock()
+	lock, exists := l.locks[network]
+	if !exists {
+		lock = &sync.Mutex{}
+		l.locks[network] = lock
+	}
+	l.mutex.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
+
+// getLeaseAddress returns the address for the static DHCP lease of the
+// interface: its static address if it is in the network and not leased to
+// another interface, otherwise the first free address
+func getLeaseAddress(network *NetworkModel, nic *NetworkInterfaceModel) (string, error) {
+	if !nic.HasStaticAddress() || !addressInNetwork(network, nic.Address) {
+		return allocateAddress(network)
+	}
+	for _, lease := range network.Leases {
+		if lease.MAC != nic.MAC && net.ParseIP(lease.IP).Equal(net.ParseIP(nic.Address)) {
+			return "", fmt.Errorf("getLeaseAddress: %w: %s", ErrAddressInUse, nic.Address)
+		}
+	}
+	return nic.Address, nil
+}
+
+// allocateAddress returns the first free address of the network for a static
+// DHCP lease. The host address, the dynamic DHCP range and the addresses of
+// existing leases are not used.
+func allocateAddress(network *NetworkModel) (string, error) {
+	_, ipNet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", network.Address, network.Prefix))
+	if err != nil {
+		return "", fmt.Errorf("allocateAddress: illegal network address: %v", err)
+	}
+
+	used := []uint32{ipToUint32(net.ParseIP(network.Address))}
+	for _, lease := range network.Leases {
+		if ip := net.ParseIP(lease.IP); ip != nil {
+			used = append(used, ipToUint32(ip))
+		}
+	}
+
+	var rangeStart, rangeEnd uint32
+	if network.HasDHCP() {
+		rangeStart = ipToUint32(net.ParseIP(network.DHCPStart))
+		rangeEnd = ipToUint32(net.ParseIP(network.DHCPEnd))
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	first := ipToUint32(ipNet.IP) + 1
+	last := ipToUint32(ipNet.IP) + uint32(1)<<uint(bits-ones) - 2
+	for candidate := first; candidate <= last && candidate >= first; candidate++ {
+		if network.HasDHCP() && candidate >= rangeStart && candidate <= rangeEnd {
+			continue
+		}
+		if contains(used, candidate) {
+			continue
+		}
+		return uint32ToIP(candidate).String(), nil
+	}
+	return "", fmt.Errorf("allocateAddress: no free addresses in %s", ipNet)
+}
+
+// addressInNetwork returns true if the address is inside the network
+func addressInNetwork(network *NetworkModel, address string) bool {
+	_, ipNet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", network.Address, network.Prefix))
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(address)
+	return ip != nil && ipNet.Contains(ip)
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(ip4)
+}
+
+func uint32ToIP(value uint32) net.IP {
+	ip := make(net.IP, 4)
+	binary.BigEndian.PutUint32(ip, value)
+	return ip
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAllocateAddress(t *testing.T) {
+	network := NewNetworkModel(NetworkInterfaceType, "test", "", true)
+	network.Address = "10.0.0.1"
+	network.Prefix = 29
+	network.DHCPStart = "10.0.0.4"
+	network.DHCPEnd = "10.0.0.5"
+	network.Leases = DHCPLeaseModelList{
+		{MAC: "02:00:00:00:00:01", IP: "10.0.0.2"},
+	}
+
+	ip, err := allocateAddress(network)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ip != "10.0.0.3" {
+		t.Errorf("Expected 10.0.0.3, got %v", ip)
+	}
+
+	network.Leases = append(network.Leases, &DHCPLeaseModel{MAC: "02:00:00:00:00:02", IP: "10.0.0.3"})
+	ip, err = allocateAddress(network)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ip != "10.0.0.6" {
+		t.Errorf("Expected 10.0.0.6, got %v", ip)
+	}
+
+	network.Leases = append(network.Leases, &DHCPLeaseModel{MAC: "02:00:00:00:00:03", IP: "10.0.0.6"})
+	if _, err := allocateAddress(network); err == nil {
+		t.Errorf("Expected an error when the network is full")
+	}
+}
+
+func TestGetLeaseAddress(t *testing.T) {
+	network := NewNetworkModel(NetworkInterfaceType, "test", "", true)
+	network.Address = "10.0.0.1"
+	network.Prefix = 24
+	network.DHCPStart = "10.0.0.100"
+	network.DHCPEnd = "10.0.0.200"
+	network.Leases = DHCPLeaseModelList{
+		{MAC: "02:00:00:00:00:01", IP: "10.0.0.2"},
+	}
+
+	nic := &NetworkInterfaceModel{Type: NetworkInterfaceType, MAC: "02:00:00:00:00:02", Address: "10.0.0.2", Prefix: 24}
+	if _, err := getLeaseAddress(network, nic); !errors.Is(err, ErrAddressInUse) {
+		t.Errorf("getLeaseAddress for a leased address = %v, want ErrAddressInUse", err)
+	}
+
+	nic.Address = "10.0.0.10"
+	if ip, err := getLeaseAddress(network, nic); err != nil || ip != "10.0.0.10" {
+		t.Errorf("getLeaseAddress = %v, %v, want 10.0.0.10", ip, err)
+	}
+}
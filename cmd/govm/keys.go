@@ -0,0 +1,57 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// linuxKeyCodes maps key names to Linux input event codes
+var linuxKeyCodes = map[string]uint{
+	"esc": 1, "1": 2, "2": 3, "3": 4, "4": 5, "5": 6, "6": 7, "7": 8, "8": 9, "9": 10, "0": 11,
+	"minus": 12, "equal": 13, "backspace": 14, "tab": 15,
+	"q": 16, "w": 17, "e": 18, "r": 19, "t": 20, "y": 21, "u": 22, "i": 23, "o": 24, "p": 25,
+	"enter": 28, "ctrl": 29,
+	"a": 30, "s": 31, "d": 32, "f": 33, "g": 34, "h": 35, "j": 36, "k": 37, "l": 38,
+	"shift": 42, "z": 44, "x": 45, "c": 46, "v": 47, "b": 48, "n": 49, "m": 50,
+	"alt": 56, "space": 57,
+	"f1": 59, "f2": 60, "f3": 61, "f4": 62, "f5": 63, "f6": 64, "f7": 65, "f8": 66, "f9": 67, "f10": 68,
+	"f11": 87, "f12": 88,
+	"sysrq": 99, "altgr": 100,
+	"home": 102, "up": 103, "pageup": 104, "left": 105, "right": 106, "end": 107, "down": 108, "pagedown": 109,
+	"insert": 110, "delete": 111, "meta": 125,
+}
+
+// keyAliases are the alternative names of the keys
+var keyAliases = map[string]string{
+	"escape":  "esc",
+	"return":  "enter",
+	"control": "ctrl",
+	"del":     "delete",
+	"ins":     "insert",
+	"super":   "meta",
+	"win":     "meta",
+	"print":   "sysrq",
+}
+
+// ParseKeyCombo parses keys pressed together, e.g. "ctrl+alt+delete" or
+// "alt+sysrq+b", to Linux key codes
+func ParseKeyCombo(combo string) ([]uint, error) {
+	var codes []uint
+	for _, name := range strings.Split(strings.ToLower(combo), "+") {
+		name = strings.TrimSpace(name)
+		if alias, ok := keyAliases[name]; ok {
+			name = alias
+		}
+		code, ok := linuxKeyCodes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown key: %q", name)
+		}
+		codes = append(codes, code)
+	}
+	if len(codes) > MaxKeyComboSize {
+		return nil, fmt.Errorf("too many keys: %d", len(codes))
+	}
+	return codes, nil
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyCombo(t *testing.T) {
+	tests := []struct {
+		combo    string
+		expected []uint
+	}{
+		{"ctrl+alt+delete", []uint{29, 56, 111}},
+		{"Ctrl + Alt + Del", []uint{29, 56, 111}},
+		{"alt+sysrq+b", []uint{56, 99, 48}},
+		{"f12", []uint{88}},
+	}
+	for _, test := range tests {
+		got, err := ParseKeyCombo(test.combo)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", test.combo, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.combo, test.expected, got)
+		}
+	}
+
+	for _, combo := range []string{"", "ctrl+", "ctrl+hyper"} {
+		if _, err := ParseKeyCombo(combo); err == nil {
+			t.Errorf("%q: expected an error", combo)
+		}
+	}
+}
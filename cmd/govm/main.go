@@ -22,13 +22,26 @@ func main() {
 	ifNetworkName := flag.String("default-if-network", parseStringEnv("GOVM_INTERFACE_NETWORK", "default"), "change default virtio network name (if network type)")
 	defaultBridge := flag.String("default-bridge", parseStringEnv("GOVM_BRIDGE", "br0"), "change default virtio network bridge interface")
 	volumesDir := flag.String("volumes", parseStringEnv("GOVM_VOLUMES", "./volumes"), "change default location for volumes")
+	storagePool := flag.String("storage-pool", parseStringEnv("GOVM_STORAGE_POOL", ""), "use a libvirt storage pool for volumes instead of the volumes directory")
 	imagesDir := flag.String("images", parseStringEnv("GOVM_IMAGES", "./images"), "change default location for images")
 	adminEmail := flag.String("admin-email", parseStringEnv("GOVM_ADMIN_EMAIL", ""), "change default admin email address")
 	adminPassword := flag.String("admin-password", parseStringEnv("GOVM_ADMIN_PASSWORD", ""), "change default admin password")
 	port := flag.Int("port", parseIntEnv("PORT", 3001), "change default port")
 	version := flag.Bool("version", false, "Show version information")
 	demo := flag.Bool("demo", false, "Use demo version of the service")
-	features := flag.String("features", parseStringEnv("GOVM_FEATURES", "start,stop,restart,console"), "Enable server actions. Available actions are none, all, create, deploy, start, stop, restart, delete, and console.")
+	features := flag.String("features", parseStringEnv("GOVM_FEATURES", "start,stop,restart,console"), "Enable server actions. Available actions are none, all, create, deploy, start, stop, restart, delete, console, resize, volume, network, force-off, reset, pause, resume, suspend, hibernate, restore, access, and migrate.")
+	vncSocketDir := flag.String("vnc-socket-dir", parseStringEnv("GOVM_VNC_SOCKET_DIR", ""), "define VNC and SPICE consoles of new servers on UNIX sockets in this directory instead of TCP on 127.0.0.1. The directory must be writable by the QEMU processes.")
+	forwardAddress := flag.String("forward-address", parseStringEnv("GOVM_FORWARD_ADDRESS", ""), "change default address to listen for port forwards")
+	forwardPorts := flag.String("forward-ports", parseStringEnv("GOVM_FORWARD_PORTS", DefaultPortForwardRange), "change default host port range for port forwards")
+	vncSessionTTL := flag.Duration("vnc-session-ttl", parseDurationEnv("GOVM_VNC_SESSION_TTL", DefaultVncSessionTTL), "change default maximum lifetime of a VNC console session")
+	guestInfoInterval := flag.Duration("guest-info-interval", parseDurationEnv("GOVM_GUEST_INFO_INTERVAL", DefaultGuestInfoInterval), "change default interval to refresh the guest agent information of running servers, 0 to disable")
+	thumbnailInterval := flag.Duration("thumbnail-interval", parseDurationEnv("GOVM_THUMBNAIL_INTERVAL", DefaultThumbnailInterval), "change default interval to refresh the screenshot thumbnails of running servers, 0 to disable")
+	metricsCacheTTL := flag.Duration("metrics-cache-ttl", parseDurationEnv("GOVM_METRICS_CACHE_TTL", DefaultMetricsCacheTTL), "change default time to reuse the resource usage of the servers between metrics scrapes")
+	metricsInterval := flag.Duration("metrics-interval", parseDurationEnv("GOVM_METRICS_INTERVAL", DefaultMetricsInterval), "change default interval to sample the resource usage history of running servers, 0 to disable")
+	metricsRetention := flag.Duration("metrics-retention", parseDurationEnv("GOVM_METRICS_RETENTION", DefaultMetricsRetention), "change default time to keep the resource usage history in memory")
+	cpuOvercommit := flag.Float64("cpu-overcommit", parseFloatEnv("GOVM_CPU_OVERCOMMIT", DefaultCPUOvercommit), "change default number of virtual CPUs new servers may have in total per host CPU, 0 to disable the limit")
+	memoryOvercommit := flag.Float64("memory-overcommit", parseFloatEnv("GOVM_MEMORY_OVERCOMMIT", DefaultMemoryOvercommit), "change default number of times the host memory may be committed to new servers, 0 to disable the limit")
+	vncSessionLimit := flag.Int("vnc-session-limit", parseIntEnv("GOVM_VNC_SESSION_LIMIT", DefaultVncSessionLimit), "change default maximum number of VNC console sessions per server")
 	configFile := flag.String("config", parseStringEnv("GOVM_CONFIG", "./config.yml"), "Configuration file")
 	https := flag.Bool("https", parseBooleanEnv("GOVM_HTTPS", false), "Enable HTTPS instead of HTTP")
 	certDir := flag.String("cert-dir", parseStringEnv("GOVM_CERT_DIR", "./certs"), "TLS files for HTTPS")
@@ -75,6 +88,7 @@ func main() {
 		log.Fatalf("Failed to read config file: %s: %v", *configFile, err)
 	}
 	configManager := NewConfigManager(*configFile, config)
+	configManager.MigrateProjects()
 
 	// Features
 	var enabledActions []ServerActionCode
@@ -92,10 +106,18 @@ func main() {
 	// SessionService
 	sessionService := NewMemorySessionService()
 
+	overcommit := OvercommitRatios{
+		CPU:    *cpuOvercommit,
+		Memory: *memoryOvercommit,
+	}
+	if overcommit.CPU < 0 || overcommit.Memory < 0 {
+		log.Fatalf("Overcommit ratios must not be negative: cpu %v, memory %v", overcommit.CPU, overcommit.Memory)
+	}
+
 	// Service
 	var service ServerService
 	if *demo {
-		service = NewDummyService()
+		service = NewDummyService(enabledActions, overcommit)
 		log.Printf("Starting dummy server at %s\n", listenTo)
 	} else {
 
@@ -109,7 +131,15 @@ func main() {
 			log.Fatalf("Failed to get absolute path for volumes directory: %s: %v", *volumesDir, err)
 		}
 
-		service = NewVirtioService(*system, absImagesDir, absVolumesDir, *ifType, *ifNetworkName, *defaultBridge, enabledActions)
+		absVncSocketDir := ""
+		if *vncSocketDir != "" {
+			absVncSocketDir, err = filepath.Abs(*vncSocketDir)
+			if err != nil {
+				log.Fatalf("Failed to get absolute path for VNC socket directory: %s: %v", *vncSocketDir, err)
+			}
+		}
+
+		service = NewVirtioService(*system, absImagesDir, absVolumesDir, *storagePool, *ifType, *ifNetworkName, *defaultBridge, absVncSocketDir, enabledActions, overcommit)
 		log.Printf("Starting virtio server at %s\n", listenTo)
 	}
 
@@ -118,6 +148,21 @@ func main() {
 		log.Fatalf("Failed to start the service: %v", err)
 	}
 
+	host, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("Failed to get the hostname: %v", err)
+	}
+	registerServerStatsMetrics(service, host, *metricsCacheTTL)
+
+	// Port forwards
+	forwardFirstPort, forwardLastPort, err := ParsePortRange(*forwardPorts)
+	if err != nil {
+		log.Fatalf("Failed to parse port forward range: %v", err)
+	}
+	forwarder := NewPortForwarder(*forwardAddress, forwardFirstPort, forwardLastPort)
+	forwarder.Restore(configManager.GetConfig().Forwards)
+	defer forwarder.Close()
+
 	tlsEnabled := *https
 	tlsDir := *certDir
 	tlsCertFile := filepath.Join(tlsDir, *certFile)
@@ -128,7 +173,7 @@ func main() {
 		log.Printf("Warning! Using unsecured HTTP")
 	}
 
-	server := NewApiServer(listenTo, tlsEnabled, tlsCertFile, tlsKeyFile, service, sessionService, authorizationService, enabledActions, configManager)
+	server := NewApiServer(listenTo, tlsEnabled, tlsCertFile, tlsKeyFile, service, sessionService, authorizationService, enabledActions, configManager, serverAdminEmail, forwarder, *vncSessionTTL, *vncSessionLimit, *thumbnailInterval, *guestInfoInterval, *metricsInterval, *metricsRetention)
 
 	err = server.startApiServer()
 	if err != nil {
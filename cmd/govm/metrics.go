@@ -3,6 +3,10 @@
 package main
 
 import (
+	"log"
+	"sync"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -23,16 +27,154 @@ var (
 		},
 		[]string{"operation"},
 	)
+
+	vncBytesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "govm_vnc_bytes_total",
+			Help: "Bytes proxied by the connected VNC console sessions",
+		},
+		[]string{"session", "server", "direction"},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(
 		httpRequestsTotal,
 		failedOperationsCounter,
+		vncBytesTotal,
 	)
 }
 
+// registerServerStatsMetrics exports the resource usage of the servers of the service
+func registerServerStatsMetrics(service ServerService, host string, ttl time.Duration) {
+	prometheus.MustRegister(NewServerStatsCollector(service, host, ttl))
+}
+
 func recordFailedOperationMetric(operationName string) {
 	// Increment the counter for the specific operation that failed
 	failedOperationsCounter.WithLabelValues(operationName).Inc()
 }
+
+var (
+	serverStatusDesc = prometheus.NewDesc(
+		"govm_server_status",
+		"Status of the server, 1 for the current status",
+		[]string{"host", "server", "status"}, nil,
+	)
+	serverCPUSecondsDesc = prometheus.NewDesc(
+		"govm_server_cpu_seconds_total",
+		"CPU time used by the server",
+		[]string{"host", "server"}, nil,
+	)
+	serverMemoryBytesDesc = prometheus.NewDesc(
+		"govm_server_memory_bytes",
+		"Memory of the server by type: current and maximum balloon size, usable in the guest and resident on the host",
+		[]string{"host", "server", "type"}, nil,
+	)
+	serverDiskBytesDesc = prometheus.NewDesc(
+		"govm_server_disk_bytes_total",
+		"Bytes read and written by the disks of the server",
+		[]string{"host", "server", "device", "direction"}, nil,
+	)
+	serverDiskRequestsDesc = prometheus.NewDesc(
+		"govm_server_disk_requests_total",
+		"Read and write requests of the disks of the server",
+		[]string{"host", "server", "device", "direction"}, nil,
+	)
+	serverNetworkBytesDesc = prometheus.NewDesc(
+		"govm_server_network_bytes_total",
+		"Bytes received and transmitted by the network interfaces of the server",
+		[]string{"host", "server", "device", "direction"}, nil,
+	)
+	serverNetworkPacketsDesc = prometheus.NewDesc(
+		"govm_server_network_packets_total",
+		"Packets received and transmitted by the network interfaces of the server",
+		[]string{"host", "server", "device", "direction"}, nil,
+	)
+)
+
+// ServerStatsCollector exports the resource usage of the servers. The stats
+// are cached, so frequent scrapes do not query libvirt each time.
+type ServerStatsCollector struct {
+	service ServerService
+	host    string
+	ttl     time.Duration
+
+	mutex   sync.Mutex
+	stats   []*ServerStatsModel
+	updated time.Time
+}
+
+func NewServerStatsCollector(service ServerService, host string, ttl time.Duration) *ServerStatsCollector {
+	return &ServerStatsCollector{
+		service: service,
+		host:    host,
+		ttl:     ttl,
+	}
+}
+
+func (c *ServerStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serverStatusDesc
+	ch <- serverCPUSecondsDesc
+	ch <- serverMemoryBytesDesc
+	ch <- serverDiskBytesDesc
+	ch <- serverDiskRequestsDesc
+	ch <- serverNetworkBytesDesc
+	ch <- serverNetworkPacketsDesc
+}
+
+func (c *ServerStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.getStats()
+	if err != nil {
+		log.Printf("ServerStatsCollector: %v", err)
+		return
+	}
+	counter := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, labels...)
+	}
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+	for _, item := range stats {
+		for _, status := range AllServerStatusCodes() {
+			value := 0.0
+			if status == item.Status {
+				value = 1
+			}
+			gauge(serverStatusDesc, value, c.host, item.Name, status.String())
+		}
+		counter(serverCPUSecondsDesc, float64(item.CPUTime)/1e9, c.host, item.Name)
+		gauge(serverMemoryBytesDesc, float64(item.MemoryCurrent), c.host, item.Name, "current")
+		gauge(serverMemoryBytesDesc, float64(item.MemoryMaximum), c.host, item.Name, "maximum")
+		gauge(serverMemoryBytesDesc, float64(item.MemoryUsable), c.host, item.Name, "usable")
+		gauge(serverMemoryBytesDesc, float64(item.MemoryRSS), c.host, item.Name, "rss")
+		for _, disk := range item.Disks {
+			counter(serverDiskBytesDesc, float64(disk.ReadBytes), c.host, item.Name, disk.Device, "read")
+			counter(serverDiskBytesDesc, float64(disk.WriteBytes), c.host, item.Name, disk.Device, "write")
+			counter(serverDiskRequestsDesc, float64(disk.ReadRequests), c.host, item.Name, disk.Device, "read")
+			counter(serverDiskRequestsDesc, float64(disk.WriteRequests), c.host, item.Name, disk.Device, "write")
+		}
+		for _, nic := range item.Interfaces {
+			counter(serverNetworkBytesDesc, float64(nic.RxBytes), c.host, item.Name, nic.Device, "receive")
+			counter(serverNetworkBytesDesc, float64(nic.TxBytes), c.host, item.Name, nic.Device, "transmit")
+			counter(serverNetworkPacketsDesc, float64(nic.RxPackets), c.host, item.Name, nic.Device, "receive")
+			counter(serverNetworkPacketsDesc, float64(nic.TxPackets), c.host, item.Name, nic.Device, "transmit")
+		}
+	}
+}
+
+// getStats returns the cached stats, or reads new stats if they are too old
+func (c *ServerStatsCollector) getStats() ([]*ServerStatsModel, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.stats != nil && time.Since(c.updated) < c.ttl {
+		return c.stats, nil
+	}
+	stats, err := c.service.GetServerStats()
+	if err != nil {
+		return nil, err
+	}
+	c.stats = stats
+	c.updated = time.Now()
+	return stats, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// countingStatsService counts the calls to read the stats
+type countingStatsService struct {
+	*DummyService
+	calls int
+}
+
+func (s *countingStatsService) GetServerStats() ([]*ServerStatsModel, error) {
+	s.calls++
+	return []*ServerStatsModel{{
+		Name:          "server1",
+		Status:        StartedServerStatusCode,
+		CPUTime:       2500 * 1000 * 1000,
+		MemoryCurrent: 1024,
+		Disks:         []DiskStatsModel{{Device: "vda", ReadBytes: 10, WriteBytes: 20}},
+		Interfaces:    []InterfaceStatsModel{{Device: "vnet0", RxBytes: 30, TxPackets: 4}},
+	}}, nil
+}
+
+func collectMetrics(c prometheus.Collector) map[string][]*dto.Metric {
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(ch)
+	close(ch)
+	metrics := make(map[string][]*dto.Metric)
+	for metric := range ch {
+		var item dto.Metric
+		if err := metric.Write(&item); err != nil {
+			panic(err)
+		}
+		name := metric.Desc().String()
+		metrics[name] = append(metrics[name], &item)
+	}
+	return metrics
+}
+
+func TestServerStatsCollector(t *testing.T) {
+	service := &countingStatsService{DummyService: NewDummyService(nil, OvercommitRatios{})}
+	collector := NewServerStatsCollector(service, "host1", time.Minute)
+
+	metrics := collectMetrics(collector)
+	cpu := metrics[serverCPUSecondsDesc.String()]
+	if len(cpu) != 1 || cpu[0].GetCounter().GetValue() != 2.5 {
+		t.Errorf("cpu seconds = %v, want 2.5", cpu)
+	}
+	started := 0
+	for _, item := range metrics[serverStatusDesc.String()] {
+		if item.GetGauge().GetValue() == 1 {
+			started++
+		}
+	}
+	if len(metrics[serverStatusDesc.String()]) != len(AllServerStatusCodes()) || started != 1 {
+		t.Errorf("status gauges should have one current status")
+	}
+	if len(metrics[serverDiskBytesDesc.String()]) != 2 || len(metrics[serverNetworkPacketsDesc.String()]) != 2 {
+		t.Errorf("disk or network counters missing")
+	}
+
+	collectMetrics(collector)
+	if service.calls != 1 {
+		t.Errorf("stats read %d times, want cached once", service.calls)
+	}
+}
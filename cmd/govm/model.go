@@ -27,6 +27,41 @@ type ServerModel struct {
 	EnabledActions ServerActionCodeList
 
 	Users UserEmailList
+
+	// CPUs the number of virtual CPUs
+	CPUs uint
+
+	// Memory the maximum memory in bytes
+	Memory uint64
+
+	// Volumes the disk volumes of the server, including the root disk
+	Volumes VolumeModelList
+
+	// Interfaces the network interfaces of the server
+	Interfaces NetworkInterfaceModelList
+
+	// Console the type of the graphical console, vnc or spice
+	Console string
+
+	// Operation the latest long running operation, or nil
+	Operation *Operation
+
+	// GuestAgent is true if the guest agent of the running server is
+	// connected, so the filesystems can be frozen for consistent snapshots
+	GuestAgent bool
+
+	// Frozen is true if the filesystems of the server are frozen
+	Frozen bool
+}
+
+// ServerOptions are the options to create a new server
+type ServerOptions struct {
+
+	// Interfaces the network interfaces, or the default interface if empty
+	Interfaces NetworkInterfaceModelList
+
+	// Console the type of the graphical console, or VNC if empty
+	Console string
 }
 
 func NewServerModel(
@@ -46,11 +81,24 @@ func NewServerModel(
 
 func (item *ServerModel) ToDTO() ServerDTO {
 	return ServerDTO{
-		Name:        item.Name,
-		Status:      item.Status.String(),
-		Actions:     ToStatusStringList(item.Status.GetAvailableActions(item.EnabledActions)),
-		Permissions: NewServerPermissionDTOFromServerActionCodeList(item.EnabledActions),
+		Name:                item.Name,
+		Status:              item.Status.String(),
+		Actions:             ToStatusStringList(item.Status.GetAvailableActions(item.EnabledActions)),
+		Permissions:         NewServerPermissionDTOFromServerActionCodeList(item.EnabledActions),
+		Volumes:             item.Volumes.ToDTO(),
+		Interfaces:          item.Interfaces.ToDTO(),
+		Console:             item.Console,
+		Operation:           item.getOperationDTO(),
+		ConsistentSnapshots: item.GuestAgent,
+		Frozen:              item.Frozen,
+	}
+}
+
+func (item *ServerModel) getOperationDTO() *OperationDTO {
+	if item.Operation == nil {
+		return nil
 	}
+	return item.Operation.ToDTO()
 }
 
 func ToServerListArray(
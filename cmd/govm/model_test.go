@@ -7,7 +7,7 @@ import (
 )
 
 func TestNewServerModel(t *testing.T) {
-	gs := NewServerModel("testname", StartedServerStatusCode)
+	gs := NewServerModel("testname", StartedServerStatusCode, nil)
 	if gs.Name != "testname" {
 		t.Errorf("Expected Name (%v) and (%v) to be equal", gs.Name, "testname")
 	}
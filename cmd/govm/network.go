@@ -0,0 +1,206 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	NatNetworkMode      = "nat"
+	IsolatedNetworkMode = "isolated"
+)
+
+// NetworkModel This is the data model of a network servers can be connected to
+type NetworkModel struct {
+
+	// Type the interface type used to connect to the network
+	Type string
+
+	// Source the name of the libvirt network or the bridge
+	Source string
+
+	// Bridge the host bridge interface of the network, if known
+	Bridge string
+
+	// Active is true if the network is running
+	Active bool
+
+	// Autostart is true if the libvirt network is started on boot
+	Autostart bool
+
+	// Mode the forward mode of a libvirt network, nat or isolated
+	Mode string
+
+	// Address the IPv4 address of the host on the network
+	Address string
+
+	// Prefix the network prefix length of the address
+	Prefix int
+
+	// DHCPStart the first address of the dynamic DHCP range
+	DHCPStart string
+
+	// DHCPEnd the last address of the dynamic DHCP range
+	DHCPEnd string
+
+	// Hosts the static DNS host entries
+	Hosts []*DNSHostModel
+
+	// Leases the static DHCP leases
+	Leases DHCPLeaseModelList
+}
+
+// DNSHostModel This is the data model of a static DNS host entry of a network
+type DNSHostModel struct {
+	IP        string
+	Hostnames []string
+}
+
+// DHCPLeaseModel This is the data model of a static DHCP lease of a network
+type DHCPLeaseModel struct {
+	MAC  string
+	Name string
+	IP   string
+}
+
+func NewNetworkModel(
+	interfaceType string,
+	source string,
+	bridge string,
+	active bool,
+) *NetworkModel {
+	return &NetworkModel{
+		Type:   interfaceType,
+		Source: source,
+		Bridge: bridge,
+		Active: active,
+	}
+}
+
+// NewNetworkModelFromDTO validates the DTO and returns a new libvirt network model
+func NewNetworkModelFromDTO(dto CreateNetworkDTO) (*NetworkModel, error) {
+	if !ValidateName(dto.Name) {
+		return nil, fmt.Errorf("illegal network name: %s", dto.Name)
+	}
+	if dto.Mode != NatNetworkMode && dto.Mode != IsolatedNetworkMode {
+		return nil, fmt.Errorf("unknown network mode: %s", dto.Mode)
+	}
+	ip, ipNet, err := net.ParseCIDR(dto.Address)
+	if err != nil || ip.To4() == nil {
+		return nil, fmt.Errorf("address must be an IPv4 address with prefix: %s", dto.Address)
+	}
+	item := NewNetworkModel(NetworkInterfaceType, dto.Name, "", false)
+	item.Mode = dto.Mode
+	item.Address = ip.String()
+	item.Prefix, _ = ipNet.Mask.Size()
+	item.Autostart = true
+
+	if dto.DHCPStart != "" || dto.DHCPEnd != "" {
+		start := net.ParseIP(dto.DHCPStart)
+		end := net.ParseIP(dto.DHCPEnd)
+		if start == nil || end == nil || !ipNet.Contains(start) || !ipNet.Contains(end) {
+			return nil, fmt.Errorf("DHCP range must be inside %s", ipNet)
+		}
+		if ipToUint32(start) > ipToUint32(end) {
+			return nil, fmt.Errorf("DHCP range start is after the end")
+		}
+		item.DHCPStart = start.String()
+		item.DHCPEnd = end.String()
+	}
+
+	for _, host := range dto.Hosts {
+		hostIP := net.ParseIP(host.IP)
+		if hostIP == nil || hostIP.To4() == nil || len(host.Hostnames) == 0 {
+			return nil, fmt.Errorf("illegal DNS host entry: %s", host.IP)
+		}
+		item.Hosts = append(item.Hosts, &DNSHostModel{
+			IP:        hostIP.String(),
+			Hostnames: host.Hostnames,
+		})
+	}
+	return item, nil
+}
+
+// HasDHCP returns true if the network serves DHCP
+func (item *NetworkModel) HasDHCP() bool {
+	return item.DHCPStart != ""
+}
+
+func (item *NetworkModel) ToDTO() NetworkDTO {
+	var address string
+	if item.Address != "" {
+		address = fmt.Sprintf("%s/%d", item.Address, item.Prefix)
+	}
+	var hosts []DNSHostDTO
+	for _, host := range item.Hosts {
+		hosts = append(hosts, DNSHostDTO{
+			IP:        host.IP,
+			Hostnames: host.Hostnames,
+		})
+	}
+	var leases []DHCPLeaseDTO
+	for _, lease := range item.Leases {
+		leases = append(leases, DHCPLeaseDTO{
+			MAC:  lease.MAC,
+			Name: lease.Name,
+			IP:   lease.IP,
+		})
+	}
+	return NetworkDTO{
+		Type:      item.Type,
+		Source:    item.Source,
+		Bridge:    item.Bridge,
+		Active:    item.Active,
+		Autostart: item.Autostart,
+		Mode:      item.Mode,
+		Address:   address,
+		DHCPStart: item.DHCPStart,
+		DHCPEnd:   item.DHCPEnd,
+		Hosts:     hosts,
+		Leases:    leases,
+	}
+}
+
+type NetworkModelList []*NetworkModel
+
+// find finds a network by the interface type and the source, otherwise nil
+func (list NetworkModelList) find(interfaceType, source string) *NetworkModel {
+	for _, item := range list {
+		if item.Type == interfaceType && item.Source == source {
+			return item
+		}
+	}
+	return nil
+}
+
+func (list NetworkModelList) ToDTO() []NetworkDTO {
+	ret := make([]NetworkDTO, len(list))
+	for i, item := range list {
+		ret[i] = item.ToDTO()
+	}
+	return ret
+}
+
+type DHCPLeaseModelList []*DHCPLeaseModel
+
+// findByMAC finds a lease by the MAC address and returns it, otherwise nil
+func (list DHCPLeaseModelList) findByMAC(mac string) *DHCPLeaseModel {
+	for _, item := range list {
+		if item.MAC == mac {
+			return item
+		}
+	}
+	return nil
+}
+
+// hasName returns true if a lease has the host name
+func (list DHCPLeaseModelList) hasName(name string) bool {
+	for _, item := range list {
+		if item.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+// NetworkConfig represents a network which servers are allowed to connect to
+type NetworkConfig struct {
+	Type   string `yaml:"type"`
+	Source string `yaml:"source,omitempty"`
+}
+
+type NetworkConfigList []*NetworkConfig
+
+// has returns true if the network is in the list
+func (list NetworkConfigList) has(interfaceType, source string) bool {
+	for _, item := range list {
+		if item.Type == interfaceType && item.Source == source {
+			return true
+		}
+	}
+	return false
+}
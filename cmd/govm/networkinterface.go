@@ -0,0 +1,136 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	NetworkInterfaceType = "network"
+	BridgeInterfaceType  = "bridge"
+	UserInterfaceType    = "user"
+)
+
+// NetworkInterfaceModel This is the data model of a network interface of the server
+type NetworkInterfaceModel struct {
+
+	// Type the interface type, one of network, bridge or user
+	Type string
+
+	// Source the name of the libvirt network or the bridge, empty for user type
+	Source string
+
+	// MAC the MAC address of the interface
+	MAC string
+
+	// Address the optional static IPv4 address configured with cloud-init
+	Address string
+
+	// Prefix the network prefix length of the static address
+	Prefix int
+
+	// Gateway the optional IPv4 gateway for the static address
+	Gateway string
+
+	// InboundAverage the optional inbound bandwidth limit in kilobytes per second
+	InboundAverage uint
+
+	// OutboundAverage the optional outbound bandwidth limit in kilobytes per second
+	OutboundAverage uint
+
+	// Filter the name of the libvirt nwfilter applied to the interface, if any
+	Filter string
+}
+
+func NewNetworkInterfaceModel(
+	interfaceType string,
+	source string,
+	mac string,
+) *NetworkInterfaceModel {
+	return &NetworkInterfaceModel{
+		Type:   interfaceType,
+		Source: source,
+		MAC:    mac,
+	}
+}
+
+// NewNetworkInterfaceModelFromDTO validates the DTO and returns a new model
+func NewNetworkInterfaceModelFromDTO(dto NetworkInterfaceDTO) (*NetworkInterfaceModel, error) {
+	item := NewNetworkInterfaceModel(dto.Type, dto.Source, "")
+	switch dto.Type {
+	case NetworkInterfaceType, BridgeInterfaceType:
+		if dto.Source == "" {
+			return nil, fmt.Errorf("source is required for type %s", dto.Type)
+		}
+	case UserInterfaceType:
+		if dto.Source != "" {
+			return nil, fmt.Errorf("source is not supported for type %s", dto.Type)
+		}
+	default:
+		return nil, fmt.Errorf("unknown interface type: %s", dto.Type)
+	}
+	if dto.Address != "" {
+		ip, ipNet, err := net.ParseCIDR(dto.Address)
+		if err != nil || ip.To4() == nil {
+			return nil, fmt.Errorf("address must be an IPv4 address with prefix: %s", dto.Address)
+		}
+		item.Address = ip.String()
+		item.Prefix, _ = ipNet.Mask.Size()
+	}
+	if dto.Gateway != "" {
+		if dto.Address == "" {
+			return nil, fmt.Errorf("gateway requires a static address")
+		}
+		gateway := net.ParseIP(dto.Gateway)
+		if gateway == nil || gateway.To4() == nil {
+			return nil, fmt.Errorf("gateway must be an IPv4 address: %s", dto.Gateway)
+		}
+		item.Gateway = gateway.String()
+	}
+	item.InboundAverage = dto.InboundAverage
+	item.OutboundAverage = dto.OutboundAverage
+	return item, nil
+}
+
+// HasStaticAddress returns true if the interface has a static address
+func (item *NetworkInterfaceModel) HasStaticAddress() bool {
+	return item.Address != ""
+}
+
+func (item *NetworkInterfaceModel) ToDTO() NetworkInterfaceDTO {
+	var address string
+	if item.HasStaticAddress() {
+		address = fmt.Sprintf("%s/%d", item.Address, item.Prefix)
+	}
+	return NetworkInterfaceDTO{
+		Type:            item.Type,
+		Source:          item.Source,
+		MAC:             item.MAC,
+		Address:         address,
+		Gateway:         item.Gateway,
+		InboundAverage:  item.InboundAverage,
+		OutboundAverage: item.OutboundAverage,
+	}
+}
+
+type NetworkInterfaceModelList []*NetworkInterfaceModel
+
+// findByMAC finds an interface by the MAC address and returns it, otherwise nil
+func (list NetworkInterfaceModelList) findByMAC(mac string) *NetworkInterfaceModel {
+	for _, item := range list {
+		if item.MAC == mac {
+			return item
+		}
+	}
+	return nil
+}
+
+func (list NetworkInterfaceModelList) ToDTO() []NetworkInterfaceDTO {
+	ret := make([]NetworkInterfaceDTO, len(list))
+	for i, item := range list {
+		ret[i] = item.ToDTO()
+	}
+	return ret
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestNewNetworkInterfaceModelFromDTO(t *testing.T) {
+	nic, err := NewNetworkInterfaceModelFromDTO(NetworkInterfaceDTO{
+		Type:    NetworkInterfaceType,
+		Source:  "default",
+		Address: "10.0.0.5/24",
+		Gateway: "10.0.0.1",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if nic.Address != "10.0.0.5" || nic.Prefix != 24 || nic.Gateway != "10.0.0.1" {
+		t.Errorf("Unexpected address: %v/%v via %v", nic.Address, nic.Prefix, nic.Gateway)
+	}
+
+	invalid := []NetworkInterfaceDTO{
+		{Type: "tap", Source: "default"},
+		{Type: NetworkInterfaceType},
+		{Type: UserInterfaceType, Source: "br0"},
+		{Type: BridgeInterfaceType, Source: "br0", Address: "10.0.0.5"},
+		{Type: BridgeInterfaceType, Source: "br0", Gateway: "10.0.0.1"},
+	}
+	for _, dto := range invalid {
+		if _, err := NewNetworkInterfaceModelFromDTO(dto); err == nil {
+			t.Errorf("Expected an error for %+v", dto)
+		}
+	}
+}
+
+func TestGetDefaultInterfaceAddress(t *testing.T) {
+	tests := []struct {
+		interfaceType string
+		address       string
+	}{
+		{NetworkInterfaceType, ""},
+		{BridgeInterfaceType, ""},
+		{UserInterfaceType, DefaultInterfaceAddress},
+	}
+	for _, test := range tests {
+		s := &VirtioService{interfaceType: test.interfaceType, defaultNetwork: "default", defaultBridge: "br0"}
+		nic := s.getDefaultInterface()
+		if nic.Type != test.interfaceType || nic.Address != test.address {
+			t.Errorf("%s: interface %s has address %q, want %q", test.interfaceType, nic.Type, nic.Address, test.address)
+		}
+	}
+}
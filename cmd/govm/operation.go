@@ -0,0 +1,212 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrOperationInProgress = errors.New("the server has an operation in progress")
+
+const RunningOperationState = "running"
+const SucceededOperationState = "succeeded"
+const FailedOperationState = "failed"
+
+// StopOptions are the options to stop or restart a server
+type StopOptions struct {
+
+	// Timeout is how long the guest is given to shut down
+	Timeout time.Duration
+
+	// Force powers the server off if it did not shut down in time
+	Force bool
+}
+
+// LiveMigrationMode moves a running server without stopping it
+const LiveMigrationMode = "live"
+
+// OfflineMigrationMode moves the definition of a stopped server
+const OfflineMigrationMode = "offline"
+
+// MigrateOptions are the options to move a server to another host
+type MigrateOptions struct {
+
+	// URI is the libvirt connection URI of the destination host
+	URI string
+
+	// SourceURI is the libvirt connection URI of the host the server is on,
+	// or empty for this host
+	SourceURI string
+
+	// Live moves the server while it runs, otherwise the server must be stopped
+	Live bool
+
+	// CopyStorage copies the volumes which are not on shared storage
+	CopyStorage bool
+
+	// Migrated is called once the server is on the destination host
+	Migrated func()
+}
+
+// Operation is a long running action on a server, like a graceful stop
+type Operation struct {
+
+	// ID identifies the operation
+	ID string
+
+	// Server is the name of the server
+	Server string
+
+	// Action is the action being performed
+	Action ServerActionCode
+
+	// Status is the status the server is in while the operation runs
+	Status ServerStatusCode
+
+	// State is running, succeeded or failed
+	State string
+
+	// Error is the reason the operation failed
+	Error string
+
+	// Progress is the percentage done of an operation which reports it
+	Progress int
+
+	// Started is the time the operation was started
+	Started time.Time
+
+	// Finished is the time the operation finished, or zero while running
+	Finished time.Time
+}
+
+// IsRunning returns true if the operation has not finished
+func (item *Operation) IsRunning() bool {
+	return item.State == RunningOperationState
+}
+
+func (item *Operation) ToDTO() *OperationDTO {
+	dto := &OperationDTO{
+		ID:       item.ID,
+		Action:   item.Action.String(),
+		State:    item.State,
+		Error:    item.Error,
+		Progress: item.Progress,
+		Started:  item.Started.UTC().Format(time.RFC3339),
+	}
+	if !item.Finished.IsZero() {
+		dto.Finished = item.Finished.UTC().Format(time.RFC3339)
+	}
+	return dto
+}
+
+// OperationManager runs the operations of the servers in the background and
+// remembers the latest operation of each server
+type OperationManager struct {
+	mutex      sync.Mutex
+	operations map[string]*Operation
+}
+
+func NewOperationManager() *OperationManager {
+	return &OperationManager{
+		operations: make(map[string]*Operation),
+	}
+}
+
+// Start runs the operation in the background unless the server has a running
+// operation already. The run function may change the status of the server
+// shown while the operation continues.
+func (m *OperationManager) Start(
+	server string,
+	action ServerActionCode,
+	status ServerStatusCode,
+	run func(setStatus func(status ServerStatusCode)) error,
+) (*Operation, error) {
+	id, err := generatePassword(12)
+	if err != nil {
+		return nil, fmt.Errorf("Start: failed to generate id: %v", err)
+	}
+
+	m.mutex.Lock()
+	if current, exists := m.operations[server]; exists && current.IsRunning() {
+		m.mutex.Unlock()
+		return nil, ErrOperationInProgress
+	}
+	item := &Operation{
+		ID:      id,
+		Server:  server,
+		Action:  action,
+		Status:  status,
+		State:   RunningOperationState,
+		Started: time.Now(),
+	}
+	m.operations[server] = item
+	started := *item
+	m.mutex.Unlock()
+	log.Printf("OperationManager: Operation %s started: %s %s", id, action, server)
+
+	go func() {
+		err := run(func(status ServerStatusCode) {
+			m.mutex.Lock()
+			item.Status = status
+			m.mutex.Unlock()
+		})
+		m.mutex.Lock()
+		item.Finished = time.Now()
+		if err != nil {
+			item.State = FailedOperationState
+			item.Error = err.Error()
+		} else {
+			item.State = SucceededOperationState
+		}
+		m.mutex.Unlock()
+		if err != nil {
+			log.Printf("OperationManager: Operation %s failed: %s %s: %v", id, action, server, err)
+			recordFailedOperationMetric(action.String())
+		} else {
+			log.Printf("OperationManager: Operation %s done: %s %s", id, action, server)
+		}
+	}()
+	return &started, nil
+}
+
+// Find returns a copy of the latest operation of the server, otherwise nil
+func (m *OperationManager) Find(server string) *Operation {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	item, exists := m.operations[server]
+	if !exists {
+		return nil
+	}
+	found := *item
+	return &found
+}
+
+// SetProgress updates the percentage done of the running operation of the server
+func (m *OperationManager) SetProgress(server string, progress int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	item, exists := m.operations[server]
+	if exists && item.IsRunning() {
+		item.Progress = progress
+	}
+}
+
+// Apply sets the latest operation to the server. While the operation runs
+// its status replaces the status of the server.
+func (m *OperationManager) Apply(model *ServerModel) {
+	model.Operation = m.Find(model.Name)
+	if model.Operation != nil && model.Operation.IsRunning() {
+		model.Status = model.Operation.Status
+	}
+}
+
+// Remove forgets the operations of a deleted server
+func (m *OperationManager) Remove(server string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.operations, server)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForOperation waits until the latest operation of the server has finished
+func waitForOperation(t *testing.T, m *OperationManager, server string) *Operation {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		item := m.Find(server)
+		if item != nil && !item.IsRunning() {
+			return item
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("operation of %s did not finish", server)
+	return nil
+}
+
+func TestOperationManagerStatus(t *testing.T) {
+	m := NewOperationManager()
+	stopping := make(chan struct{})
+	starting := make(chan struct{})
+	release := make(chan struct{})
+	_, err := m.Start("server1", RestartServerActionCode, StoppingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+		close(stopping)
+		<-release
+		setStatus(StartingServerStatusCode)
+		close(starting)
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	<-stopping
+	model := NewServerModel("server1", StartedServerStatusCode, nil)
+	m.Apply(model)
+	if model.Status != StoppingServerStatusCode {
+		t.Errorf("status = %v, want stopping", model.Status)
+	}
+
+	_, err = m.Start("server1", StopServerActionCode, StoppingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+		return nil
+	})
+	if !errors.Is(err, ErrOperationInProgress) {
+		t.Errorf("second Start error = %v, want ErrOperationInProgress", err)
+	}
+
+	release <- struct{}{}
+	<-starting
+	model = NewServerModel("server1", StoppedServerStatusCode, nil)
+	m.Apply(model)
+	if model.Status != StartingServerStatusCode {
+		t.Errorf("status = %v, want starting", model.Status)
+	}
+
+	close(release)
+	item := waitForOperation(t, m, "server1")
+	if item.State != SucceededOperationState {
+		t.Errorf("state = %s, want %s", item.State, SucceededOperationState)
+	}
+	model = NewServerModel("server1", StartedServerStatusCode, nil)
+	m.Apply(model)
+	if model.Status != StartedServerStatusCode || model.Operation == nil {
+		t.Errorf("finished operation should keep the status and be reported")
+	}
+}
+
+func TestOperationManagerFailure(t *testing.T) {
+	m := NewOperationManager()
+	_, err := m.Start("server1", StopServerActionCode, StoppingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+		return errors.New("the server did not stop in 1s")
+	})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	item := waitForOperation(t, m, "server1")
+	if item.State != FailedOperationState || item.Error != "the server did not stop in 1s" {
+		t.Errorf("operation = %+v, want failed with the error", item)
+	}
+	if item.ToDTO().Finished == "" {
+		t.Errorf("finished time missing")
+	}
+
+	m.Remove("server1")
+	if m.Find("server1") != nil {
+		t.Errorf("operation not removed")
+	}
+}
+
+func TestOperationManagerProgress(t *testing.T) {
+	m := NewOperationManager()
+	release := make(chan struct{})
+	_, err := m.Start("server1", MigrateServerActionCode, MigratingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	m.SetProgress("server1", 40)
+	if progress := m.Find("server1").ToDTO().Progress; progress != 40 {
+		t.Errorf("progress = %d, want 40", progress)
+	}
+
+	close(release)
+	waitForOperation(t, m, "server1")
+	m.SetProgress("server1", 60)
+	if progress := m.Find("server1").Progress; progress != 40 {
+		t.Errorf("progress of a finished operation changed to %d", progress)
+	}
+}
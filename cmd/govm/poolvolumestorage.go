@@ -0,0 +1,305 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"libvirt.org/go/libvirt"
+)
+
+// PoolVolumeStorage stores volumes in a libvirt storage pool, so the pool
+// manages permissions, labels and capacity of the volumes
+type PoolVolumeStorage struct {
+	pool string
+}
+
+func NewPoolVolumeStorage(pool string) *PoolVolumeStorage {
+	return &PoolVolumeStorage{
+		pool: pool,
+	}
+}
+
+var _ VolumeStorage = &PoolVolumeStorage{}
+
+func (s *PoolVolumeStorage) CreateVolume(conn *libvirt.Connect, server, key, format string, size uint64) error {
+	pool, err := s.lookupPool(conn)
+	if err != nil {
+		return fmt.Errorf("CreateVolume: %v", err)
+	}
+	defer pool.Free()
+
+	vol, err := pool.StorageVolCreateXML(getStorageVolumeXML(getPoolVolumeName(server, key), format, size), 0)
+	if err != nil {
+		return fmt.Errorf("CreateVolume: failed to create volume: %s: %v", key, err)
+	}
+	defer vol.Free()
+	return nil
+}
+
+func (s *PoolVolumeStorage) ImportVolume(conn *libvirt.Connect, server, key, format, sourceFile string) error {
+	sourceStat, err := os.Stat(sourceFile)
+	if err != nil {
+		return fmt.Errorf("ImportVolume: failed to stat source: %v", err)
+	}
+	size := uint64(sourceStat.Size())
+
+	// The virtual size of an image may be larger than the file
+	capacity := size
+	if format == "qcow2" {
+		info, err := qemuImgGetInfo(sourceFile)
+		if err != nil {
+			return fmt.Errorf("ImportVolume: %v", err)
+		}
+		capacity = info.VirtualSize
+	}
+
+	pool, err := s.lookupPool(conn)
+	if err != nil {
+		return fmt.Errorf("ImportVolume: %v", err)
+	}
+	defer pool.Free()
+
+	vol, err := pool.StorageVolCreateXML(getStorageVolumeXML(getPoolVolumeName(server, key), format, capacity), 0)
+	if err != nil {
+		return fmt.Errorf("ImportVolume: failed to create volume: %s: %v", key, err)
+	}
+	defer vol.Free()
+
+	err = uploadStorageVolume(conn, vol, sourceFile, size)
+	if err != nil {
+		if deleteErr := vol.Delete(libvirt.STORAGE_VOL_DELETE_NORMAL); deleteErr != nil {
+			return fmt.Errorf("ImportVolume: %v (and failed to delete the volume: %v)", err, deleteErr)
+		}
+		return fmt.Errorf("ImportVolume: %v", err)
+	}
+	return nil
+}
+
+func (s *PoolVolumeStorage) DeleteVolume(conn *libvirt.Connect, server, key string) error {
+	vol, err := s.lookupVolume(conn, server, key)
+	if err != nil {
+		return fmt.Errorf("DeleteVolume: %v", err)
+	}
+	defer vol.Free()
+	err = vol.Delete(libvirt.STORAGE_VOL_DELETE_NORMAL)
+	if err != nil {
+		return fmt.Errorf("DeleteVolume: failed to delete volume: %s: %v", key, err)
+	}
+	return nil
+}
+
+func (s *PoolVolumeStorage) HasVolume(conn *libvirt.Connect, server, key string) (bool, error) {
+	vol, err := s.lookupVolume(conn, server, key)
+	if err != nil {
+		libvirtError, ok := err.(libvirt.Error)
+		if ok && libvirtError.Code == libvirt.ERR_NO_STORAGE_VOL {
+			return false, nil
+		}
+		return false, fmt.Errorf("HasVolume: %v", err)
+	}
+	defer vol.Free()
+	return true, nil
+}
+
+func (s *PoolVolumeStorage) ListVolumes(conn *libvirt.Connect, server string) ([]string, error) {
+	volumes, err := s.ListAllVolumes(conn)
+	if err != nil {
+		return nil, err
+	}
+	return volumes[server], nil
+}
+
+func (s *PoolVolumeStorage) ListAllVolumes(conn *libvirt.Connect) (map[string][]string, error) {
+	pool, err := s.lookupPool(conn)
+	if err != nil {
+		return nil, fmt.Errorf("ListAllVolumes: %v", err)
+	}
+	defer pool.Free()
+
+	vols, err := pool.ListAllStorageVolumes(0)
+	if err != nil {
+		return nil, fmt.Errorf("ListAllVolumes: failed to list volumes: %v", err)
+	}
+	volumes := make(map[string][]string)
+	for _, vol := range vols {
+		name, err := vol.GetName()
+		vol.Free()
+		if err != nil {
+			return nil, fmt.Errorf("ListAllVolumes: failed to get volume name: %v", err)
+		}
+		if server, key, ok := parsePoolVolumeName(name); ok {
+			volumes[server] = append(volumes[server], key)
+		}
+	}
+	return volumes, nil
+}
+
+func (s *PoolVolumeStorage) GetVolumeSize(conn *libvirt.Connect, server, key string) (uint64, error) {
+	vol, err := s.lookupVolume(conn, server, key)
+	if err != nil {
+		return 0, fmt.Errorf("GetVolumeSize: %v", err)
+	}
+	defer vol.Free()
+	info, err := vol.GetInfo()
+	if err != nil {
+		return 0, fmt.Errorf("GetVolumeSize: failed to get volume info: %s: %v", key, err)
+	}
+	return info.Capacity, nil
+}
+
+func (s *PoolVolumeStorage) ResizeVolume(conn *libvirt.Connect, server, key string, size uint64) error {
+	vol, err := s.lookupVolume(conn, server, key)
+	if err != nil {
+		return fmt.Errorf("ResizeVolume: %v", err)
+	}
+	defer vol.Free()
+	err = vol.Resize(size, 0)
+	if err != nil {
+		return fmt.Errorf("ResizeVolume: failed to resize volume: %s: %v", key, err)
+	}
+	return nil
+}
+
+func (s *PoolVolumeStorage) GetDiskSource(server, key string) (string, string) {
+	return "volume", `<source pool='` + s.pool + `' volume='` + getPoolVolumeName(server, key) + `'/>`
+}
+
+func (s *PoolVolumeStorage) FindVolumeKey(server string, disk DomainDiskXML) string {
+	if disk.Source.Pool != s.pool {
+		return ""
+	}
+	owner, key, ok := parsePoolVolumeName(disk.Source.Volume)
+	if !ok || owner != server {
+		return ""
+	}
+	return key
+}
+
+func (s *PoolVolumeStorage) GetStorage(conn *libvirt.Connect) (*StorageModel, error) {
+	pool, err := s.lookupPool(conn)
+	if err != nil {
+		return nil, fmt.Errorf("GetStorage: %v", err)
+	}
+	defer pool.Free()
+	info, err := pool.GetInfo()
+	if err != nil {
+		return nil, fmt.Errorf("GetStorage: failed to get pool info: %v", err)
+	}
+	return &StorageModel{
+		Type:       PoolStorageType,
+		Name:       s.pool,
+		Capacity:   info.Capacity,
+		Allocation: info.Allocation,
+		Available:  info.Available,
+	}, nil
+}
+
+// lookupPool finds the storage pool. The caller must free the pool.
+func (s *PoolVolumeStorage) lookupPool(conn *libvirt.Connect) (*libvirt.StoragePool, error) {
+	pool, err := conn.LookupStoragePoolByName(s.pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the storage pool: %s: %v", s.pool, err)
+	}
+	return pool, nil
+}
+
+// lookupVolume finds a volume of the server from the storage pool. The caller must free the volume.
+func (s *PoolVolumeStorage) lookupVolume(conn *libvirt.Connect, server, key string) (*libvirt.StorageVol, error) {
+	pool, err := s.lookupPool(conn)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.Free()
+	return pool.LookupStorageVolByName(getPoolVolumeName(server, key))
+}
+
+// getPoolVolumeName returns the name of the volume in the pool shared by all
+// servers. Server names cannot contain a dot, so the name of the owner is
+// never ambiguous.
+func getPoolVolumeName(server, key string) string {
+	return server + PoolVolumeSeparator + key
+}
+
+// parsePoolVolumeName returns the server and the key of a pool volume, or
+// false if the volume is not managed by GoVM. The keys start with the server
+// name too.
+func parsePoolVolumeName(name string) (string, string, bool) {
+	server, key, found := strings.Cut(name, PoolVolumeSeparator)
+	if !found || !ValidateName(server) || !strings.HasPrefix(key, server+"-") {
+		return "", "", false
+	}
+	return server, key, true
+}
+
+// getStorageVolumeXML returns the XML to create a storage volume
+func getStorageVolumeXML(name, format string, capacity uint64) string {
+	return `<volume>
+  <name>` + name + `</name>
+  <capacity unit='bytes'>` + strconv.FormatUint(capacity, 10) + `</capacity>
+  <target>
+    <format type='` + format + `'/>
+  </target>
+</volume>`
+}
+
+// uploadStorageVolume streams the contents of a local file to the volume
+func uploadStorageVolume(conn *libvirt.Connect, vol *libvirt.StorageVol, sourceFile string, size uint64) error {
+
+	file, err := os.Open(sourceFile)
+	if err != nil {
+		return fmt.Errorf("uploadStorageVolume: failed to open source: %v", err)
+	}
+	defer file.Close()
+
+	stream, err := conn.NewStream(0)
+	if err != nil {
+		return fmt.Errorf("uploadStorageVolume: failed to create stream: %v", err)
+	}
+	defer stream.Free()
+
+	err = vol.Upload(stream, 0, size, 0)
+	if err != nil {
+		return fmt.Errorf("uploadStorageVolume: failed to start upload: %v", err)
+	}
+
+	buffer := make([]byte, 1024*1024)
+	for {
+		n, err := file.Read(buffer)
+		if n > 0 {
+			if sendErr := sendAllToStream(stream, buffer[:n]); sendErr != nil {
+				_ = stream.Abort()
+				return fmt.Errorf("uploadStorageVolume: failed to send: %v", sendErr)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_ = stream.Abort()
+			return fmt.Errorf("uploadStorageVolume: failed to read source: %v", err)
+		}
+	}
+
+	err = stream.Finish()
+	if err != nil {
+		return fmt.Errorf("uploadStorageVolume: failed to finish upload: %v", err)
+	}
+	return nil
+}
+
+// sendAllToStream sends the whole buffer to a libvirt stream
+func sendAllToStream(stream *libvirt.Stream, data []byte) error {
+	for len(data) > 0 {
+		n, err := stream.Send(data)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
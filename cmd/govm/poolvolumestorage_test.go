@@ -0,0 +1,30 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestPoolVolumeNameOwner(t *testing.T) {
+	name := getPoolVolumeName("web-data", getDataVolumeKey("web-data", "x"))
+	server, key, ok := parsePoolVolumeName(name)
+	if !ok || server != "web-data" || key != "web-data-data-x.qcow2" {
+		t.Fatalf("parsePoolVolumeName(%q) = %q, %q, %v", name, server, key, ok)
+	}
+
+	storage := NewPoolVolumeStorage("govm")
+	disk := DomainDiskXML{}
+	disk.Source.Pool = "govm"
+	disk.Source.Volume = name
+	if found := storage.FindVolumeKey("web", disk); found != "" {
+		t.Errorf("server web owns the volume %q of web-data", found)
+	}
+	if found := storage.FindVolumeKey("web-data", disk); found != key {
+		t.Errorf("FindVolumeKey = %q, want %q", found, key)
+	}
+
+	if _, _, ok := parsePoolVolumeName("web-data-data-x.qcow2"); ok {
+		t.Errorf("a volume without an owner was accepted")
+	}
+}
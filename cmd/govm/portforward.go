@@ -0,0 +1,301 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PortForwarder proxies host ports to the servers in process
+type PortForwarder struct {
+	address   string
+	firstPort int
+	lastPort  int
+	mutex     sync.Mutex
+	listeners map[string]io.Closer
+}
+
+func NewPortForwarder(address string, firstPort, lastPort int) *PortForwarder {
+	return &PortForwarder{
+		address:   address,
+		firstPort: firstPort,
+		lastPort:  lastPort,
+		listeners: make(map[string]io.Closer),
+	}
+}
+
+// ParsePortRange parses a port range like 20000-20999
+func ParsePortRange(value string) (int, int, error) {
+	parts := strings.SplitN(value, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("ParsePortRange: expected first-last: %s", value)
+	}
+	first, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("ParsePortRange: illegal first port: %v", err)
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("ParsePortRange: illegal last port: %v", err)
+	}
+	if first < 1 || last > 65535 || first > last {
+		return 0, 0, fmt.Errorf("ParsePortRange: illegal range: %s", value)
+	}
+	return first, last, nil
+}
+
+// InRange returns true if the host port may be used for forwarding
+func (f *PortForwarder) InRange(port int) bool {
+	return port >= f.firstPort && port <= f.lastPort
+}
+
+// AllocatePort returns the first port of the range which is not used by the
+// forwards and can be bound on the host
+func (f *PortForwarder) AllocatePort(protocol string, forwards PortForwardConfigList) (int, error) {
+	for port := f.firstPort; port <= f.lastPort; port++ {
+		if forwards.find(protocol, port) != nil {
+			continue
+		}
+		if f.isPortAvailable(protocol, port) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("AllocatePort: no free %s ports in %d-%d", protocol, f.firstPort, f.lastPort)
+}
+
+// Add starts forwarding the host port to the server
+func (f *PortForwarder) Add(forward *PortForwardConfig) error {
+	key := getPortForwardKey(forward.Protocol, forward.HostPort)
+	listenAddress := net.JoinHostPort(f.address, strconv.Itoa(forward.HostPort))
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if _, exists := f.listeners[key]; exists {
+		return fmt.Errorf("Add: port already forwarded: %s", key)
+	}
+
+	switch forward.Protocol {
+	case TCPProtocol:
+		listener, err := net.Listen("tcp", listenAddress)
+		if err != nil {
+			return fmt.Errorf("Add: failed to listen %s: %v", listenAddress, err)
+		}
+		tcp := newTCPForward(listener, forward.GuestAddressPort())
+		f.listeners[key] = tcp
+		go tcp.serve()
+	case UDPProtocol:
+		conn, err := net.ListenPacket("udp", listenAddress)
+		if err != nil {
+			return fmt.Errorf("Add: failed to listen %s: %v", listenAddress, err)
+		}
+		f.listeners[key] = conn
+		go serveUDPForward(conn, forward.GuestAddressPort())
+	default:
+		return fmt.Errorf("Add: unknown protocol: %s", forward.Protocol)
+	}
+	log.Printf("PortForwarder: Forwarding %s to %s for %s", key, forward.GuestAddressPort(), forward.Server)
+	return nil
+}
+
+// Remove stops forwarding the host port
+func (f *PortForwarder) Remove(protocol string, hostPort int) error {
+	key := getPortForwardKey(protocol, hostPort)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	listener, exists := f.listeners[key]
+	if !exists {
+		return fmt.Errorf("Remove: port not forwarded: %s", key)
+	}
+	delete(f.listeners, key)
+	log.Printf("PortForwarder: Stopped forwarding %s", key)
+	return listener.Close()
+}
+
+// Restore starts the forwards saved in the config. Failures are logged only,
+// so a single port taken by another process does not prevent the startup.
+func (f *PortForwarder) Restore(forwards PortForwardConfigList) {
+	for _, forward := range forwards {
+		err := f.Add(forward)
+		if err != nil {
+			log.Printf("PortForwarder: Warning! Failed to restore forward for %s: %v", forward.Server, err)
+		}
+	}
+}
+
+// Close stops all forwards
+func (f *PortForwarder) Close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for key, listener := range f.listeners {
+		listener.Close()
+		delete(f.listeners, key)
+	}
+}
+
+func (f *PortForwarder) isPortAvailable(protocol string, port int) bool {
+	listenAddress := net.JoinHostPort(f.address, strconv.Itoa(port))
+	var closer io.Closer
+	var err error
+	if protocol == UDPProtocol {
+		closer, err = net.ListenPacket("udp", listenAddress)
+	} else {
+		closer, err = net.Listen("tcp", listenAddress)
+	}
+	if err != nil {
+		return false
+	}
+	closer.Close()
+	return true
+}
+
+func getPortForwardKey(protocol string, hostPort int) string {
+	return fmt.Sprintf("%s/%d", protocol, hostPort)
+}
+
+// tcpForward is a forwarded TCP port and the connections proxied through it.
+// Closing it closes the open connections too, so a removed forward does not
+// keep the guest reachable.
+type tcpForward struct {
+	listener net.Listener
+	target   string
+	mutex    sync.Mutex
+	conns    map[net.Conn]struct{}
+	closed   bool
+}
+
+func newTCPForward(listener net.Listener, target string) *tcpForward {
+	return &tcpForward{
+		listener: listener,
+		target:   target,
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+func (t *tcpForward) Close() error {
+	err := t.listener.Close()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closed = true
+	for conn := range t.conns {
+		conn.Close()
+		delete(t.conns, conn)
+	}
+	return err
+}
+
+// track adds the connection to be closed with the forward. It returns false
+// and closes the connection if the forward is already closed.
+func (t *tcpForward) track(conn net.Conn) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.closed {
+		conn.Close()
+		return false
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+func (t *tcpForward) untrack(conn net.Conn) {
+	t.mutex.Lock()
+	delete(t.conns, conn)
+	t.mutex.Unlock()
+	conn.Close()
+}
+
+func (t *tcpForward) serve() {
+	for {
+		client, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		if !t.track(client) {
+			return
+		}
+		go func() {
+			defer t.untrack(client)
+			upstream, err := net.DialTimeout("tcp", t.target, PortForwardDialTimeout)
+			if err != nil {
+				log.Printf("PortForwarder: Failed to connect %s: %v", t.target, err)
+				return
+			}
+			if !t.track(upstream) {
+				return
+			}
+			defer t.untrack(upstream)
+
+			done := make(chan struct{}, 2)
+			go func() {
+				io.Copy(upstream, client)
+				done <- struct{}{}
+			}()
+			go func() {
+				io.Copy(client, upstream)
+				done <- struct{}{}
+			}()
+			<-done
+		}()
+	}
+}
+
+func serveUDPForward(conn net.PacketConn, target string) {
+	var mutex sync.Mutex
+	upstreams := make(map[string]net.Conn)
+	defer func() {
+		mutex.Lock()
+		for _, upstream := range upstreams {
+			upstream.Close()
+		}
+		mutex.Unlock()
+	}()
+
+	buffer := make([]byte, 65535)
+	for {
+		n, client, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+
+		mutex.Lock()
+		upstream, exists := upstreams[client.String()]
+		if !exists {
+			upstream, err = net.Dial("udp", target)
+			if err != nil {
+				mutex.Unlock()
+				log.Printf("PortForwarder: Failed to connect %s: %v", target, err)
+				continue
+			}
+			upstreams[client.String()] = upstream
+			go func(client net.Addr, upstream net.Conn) {
+				defer func() {
+					mutex.Lock()
+					delete(upstreams, client.String())
+					mutex.Unlock()
+					upstream.Close()
+				}()
+				reply := make([]byte, 65535)
+				for {
+					upstream.SetReadDeadline(time.Now().Add(PortForwardUDPIdleTimeout))
+					n, err := upstream.Read(reply)
+					if err != nil {
+						return
+					}
+					if _, err := conn.WriteTo(reply[:n], client); err != nil {
+						return
+					}
+				}
+			}(client, upstream)
+		}
+		mutex.Unlock()
+
+		upstream.Write(buffer[:n])
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParsePortRange(t *testing.T) {
+	first, last, err := ParsePortRange("20000-20999")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if first != 20000 || last != 20999 {
+		t.Errorf("Expected 20000-20999, got %d-%d", first, last)
+	}
+	for _, value := range []string{"20000", "b-20", "30-20", "0-10", "1-70000"} {
+		if _, _, err := ParsePortRange(value); err == nil {
+			t.Errorf("Expected an error for %s", value)
+		}
+	}
+}
+
+func TestPortForwarderTCP(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					conn.Write([]byte(line))
+				}
+			}()
+		}
+	}()
+	guestPort := echo.Addr().(*net.TCPAddr).Port
+
+	forwarder := NewPortForwarder("127.0.0.1", 42000, 42999)
+	defer forwarder.Close()
+	hostPort, err := forwarder.AllocatePort(TCPProtocol, nil)
+	if err != nil {
+		t.Fatalf("Failed to allocate port: %v", err)
+	}
+	forward := &PortForwardConfig{
+		Server:       "test",
+		Protocol:     TCPProtocol,
+		HostPort:     hostPort,
+		GuestAddress: "127.0.0.1",
+		GuestPort:    guestPort,
+	}
+	if err := forwarder.Add(forward); err != nil {
+		t.Fatalf("Failed to add forward: %v", err)
+	}
+	if err := forwarder.Add(forward); err == nil {
+		t.Errorf("Expected an error when the port is forwarded twice")
+	}
+
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(hostPort)))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("hello\n"))
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || reply != "hello\n" {
+		t.Errorf("Expected hello, got %q: %v", reply, err)
+	}
+
+	if err := forwarder.Remove(TCPProtocol, hostPort); err != nil {
+		t.Errorf("Failed to remove forward: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || isTimeout(err) {
+		t.Errorf("Expected the open connection to be closed, got %v", err)
+	}
+	if err := forwarder.Remove(TCPProtocol, hostPort); err == nil {
+		t.Errorf("Expected an error when the forward is removed twice")
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
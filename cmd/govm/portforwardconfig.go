@@ -0,0 +1,67 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"net"
+	"strconv"
+)
+
+const (
+	TCPProtocol = "tcp"
+	UDPProtocol = "udp"
+)
+
+// PortForwardConfig represents a host port forwarded to a port of a server
+type PortForwardConfig struct {
+	Server       string `yaml:"server"`
+	Protocol     string `yaml:"protocol"`
+	HostPort     int    `yaml:"hostPort"`
+	GuestAddress string `yaml:"guestAddress"`
+	GuestPort    int    `yaml:"guestPort"`
+}
+
+// GuestAddressPort returns the address and port of the server as host:port
+func (item *PortForwardConfig) GuestAddressPort() string {
+	return net.JoinHostPort(item.GuestAddress, strconv.Itoa(item.GuestPort))
+}
+
+func (item *PortForwardConfig) ToDTO() PortForwardDTO {
+	return PortForwardDTO{
+		Protocol:     item.Protocol,
+		HostPort:     item.HostPort,
+		GuestAddress: item.GuestAddress,
+		GuestPort:    item.GuestPort,
+	}
+}
+
+type PortForwardConfigList []*PortForwardConfig
+
+// find finds a forward by the protocol and the host port, otherwise nil
+func (list PortForwardConfigList) find(protocol string, hostPort int) *PortForwardConfig {
+	for _, item := range list {
+		if item.Protocol == protocol && item.HostPort == hostPort {
+			return item
+		}
+	}
+	return nil
+}
+
+// filterByServer returns the forwards of the server
+func (list PortForwardConfigList) filterByServer(name string) PortForwardConfigList {
+	var ret PortForwardConfigList
+	for _, item := range list {
+		if item.Server == name {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func (list PortForwardConfigList) ToDTO() []PortForwardDTO {
+	ret := make([]PortForwardDTO, len(list))
+	for i, item := range list {
+		ret[i] = item.ToDTO()
+	}
+	return ret
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"log"
+	"net/mail"
+)
+
+var ErrProjectNotFound = errors.New("the project does not exist")
+var ErrProjectExists = errors.New("the project exists already")
+var ErrProjectNotEmpty = errors.New("the project has servers")
+var ErrProjectMemberNotFound = errors.New("the user is not a member of the project")
+var ErrLastOwner = errors.New("the project would have no owners")
+
+const DefaultProjectName = "default"
+
+// OwnerProjectRole members use the servers and manage the members
+const OwnerProjectRole = "owner"
+
+// MemberProjectRole members use the servers of the project
+const MemberProjectRole = "member"
+
+// ProjectConfig represents a project which owns servers. The members of the
+// project have access to all of its servers.
+type ProjectConfig struct {
+	Name    string                  `yaml:"name"`
+	Members ProjectMemberConfigList `yaml:"members"`
+}
+
+// ProjectMemberConfig represents a user in a project
+type ProjectMemberConfig struct {
+	Email string `yaml:"email"`
+	Role  string `yaml:"role"`
+}
+
+type ProjectConfigList []*ProjectConfig
+
+type ProjectMemberConfigList []*ProjectMemberConfig
+
+// IsProjectRole returns true if the role is owner or member
+func IsProjectRole(role string) bool {
+	return role == OwnerProjectRole || role == MemberProjectRole
+}
+
+// ValidateEmail returns true if the string is a plain email address
+func ValidateEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// findByName finds a project by name and returns it, otherwise nil
+func (list ProjectConfigList) findByName(name string) *ProjectConfig {
+	for _, item := range list {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// findByEmail finds a member by email and returns it, otherwise nil
+func (list ProjectMemberConfigList) findByEmail(email string) *ProjectMemberConfig {
+	for _, item := range list {
+		if item.Email == email {
+			return item
+		}
+	}
+	return nil
+}
+
+// countOwners returns the number of owners
+func (list ProjectMemberConfigList) countOwners() int {
+	count := 0
+	for _, item := range list {
+		if item.Role == OwnerProjectRole {
+			count++
+		}
+	}
+	return count
+}
+
+// GetRole returns the role of the user in the project, or an empty string if
+// the user is not a member
+func (item *ProjectConfig) GetRole(email string) string {
+	member := item.Members.findByEmail(email)
+	if member == nil {
+		return ""
+	}
+	return member.Role
+}
+
+// GetProjectsForEmail returns the projects the user is a member of
+func (c *Config) GetProjectsForEmail(email string) ProjectConfigList {
+	var list ProjectConfigList
+	for _, item := range c.Projects {
+		if item.GetRole(email) != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// CountProjectServers returns the number of servers in the project
+func (c *Config) CountProjectServers(project string) int {
+	count := 0
+	for _, item := range c.Servers {
+		if item.Project == project {
+			count++
+		}
+	}
+	return count
+}
+
+// GetDefaultProjectForEmail returns the project new servers of the user are
+// created in when none is chosen: the default project if the user is a member,
+// otherwise the first project of the user, or nil
+func (c *Config) GetDefaultProjectForEmail(email string) *ProjectConfig {
+	list := c.GetProjectsForEmail(email)
+	if item := list.findByName(DefaultProjectName); item != nil {
+		return item
+	}
+	if len(list) > 0 {
+		return list[0]
+	}
+	return nil
+}
+
+// AddProject adds a project owned by the user and returns a new config object
+func (c *Config) AddProject(name, owner string) *Config {
+	newList := append(ProjectConfigList{}, c.Projects...)
+	newList = append(newList, &ProjectConfig{
+		Name:    name,
+		Members: ProjectMemberConfigList{{Email: owner, Role: OwnerProjectRole}},
+	})
+	newConfig := *c
+	newConfig.Projects = newList
+	return &newConfig
+}
+
+// RemoveProject removes a project and returns a new config object
+func (c *Config) RemoveProject(name string) *Config {
+	var newList ProjectConfigList
+	for _, item := range c.Projects {
+		if item.Name != name {
+			newList = append(newList, item)
+		}
+	}
+	newConfig := *c
+	newConfig.Projects = newList
+	return &newConfig
+}
+
+// checkProjectMember returns ErrLastOwner if giving the member the role, or
+// removing the member if the role is empty, would leave the project without
+// owners
+func (item *ProjectConfig) checkProjectMember(email, role string) error {
+	if item.GetRole(email) == OwnerProjectRole && role != OwnerProjectRole && item.Members.countOwners() == 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// SetProjectMember adds a member to the project or changes the role of the
+// member, and returns a new config object
+func (c *Config) SetProjectMember(project, email, role string) *Config {
+	return c.updateProjectMembers(project, func(members ProjectMemberConfigList) ProjectMemberConfigList {
+		var newList ProjectMemberConfigList
+		for _, item := range members {
+			if item.Email != email {
+				newList = append(newList, item)
+			}
+		}
+		return append(newList, &ProjectMemberConfig{Email: email, Role: role})
+	})
+}
+
+// RemoveProjectMember removes a member from the project and returns a new config object
+func (c *Config) RemoveProjectMember(project, email string) *Config {
+	return c.updateProjectMembers(project, func(members ProjectMemberConfigList) ProjectMemberConfigList {
+		var newList ProjectMemberConfigList
+		for _, item := range members {
+			if item.Email != email {
+				newList = append(newList, item)
+			}
+		}
+		return newList
+	})
+}
+
+// updateProjectMembers replaces the members of a project in a copy of the config
+func (c *Config) updateProjectMembers(project string, update func(members ProjectMemberConfigList) ProjectMemberConfigList) *Config {
+	newList := make(ProjectConfigList, len(c.Projects))
+	for i, item := range c.Projects {
+		if item.Name == project {
+			newList[i] = &ProjectConfig{
+				Name:    item.Name,
+				Members: update(item.Members),
+			}
+		} else {
+			newList[i] = item
+		}
+	}
+	newConfig := *c
+	newConfig.Projects = newList
+	return &newConfig
+}
+
+// MigrateProjects moves the servers of a config without projects into the
+// default project. The users who have access to every server become the
+// owners of the project. Other users keep their access in the server entries,
+// so nobody gets access to more servers than before. Returns false if there
+// was nothing to migrate.
+func (c *Config) MigrateProjects() (*Config, bool) {
+	if len(c.Projects) > 0 || len(c.Servers) == 0 {
+		return c, false
+	}
+
+	var owners UserEmailList
+	for _, email := range c.Servers[0].Users {
+		shared := true
+		for _, server := range c.Servers[1:] {
+			if !server.Users.contains(email) {
+				shared = false
+				break
+			}
+		}
+		if shared && !owners.contains(email) {
+			owners = append(owners, email)
+		}
+	}
+
+	project := &ProjectConfig{
+		Name: DefaultProjectName,
+	}
+	for _, email := range owners {
+		project.Members = append(project.Members, &ProjectMemberConfig{Email: email, Role: OwnerProjectRole})
+	}
+
+	servers := make(ServerConfigList, len(c.Servers))
+	for i, server := range c.Servers {
+		users := UserEmailList{}
+		for _, email := range server.Users {
+			if !owners.contains(email) {
+				users = append(users, email)
+			}
+		}
+		if len(users) > 0 {
+			log.Printf("MigrateProjects: Server %s keeps its own users: %v", server.Name, users)
+		}
+		servers[i] = &ServerConfig{
+			Name:    server.Name,
+			Project: DefaultProjectName,
+			Host:    server.Host,
+			Users:   users,
+		}
+	}
+
+	newConfig := *c
+	newConfig.Projects = ProjectConfigList{project}
+	newConfig.Servers = servers
+	return &newConfig, true
+}
+
+func (item *ProjectConfig) ToDTO(email string, servers int) ProjectDTO {
+	members := make([]ProjectMemberDTO, len(item.Members))
+	for i, member := range item.Members {
+		members[i] = ProjectMemberDTO{
+			Email: member.Email,
+			Role:  member.Role,
+		}
+	}
+	return ProjectDTO{
+		Name:    item.Name,
+		Role:    item.GetRole(email),
+		Members: members,
+		Servers: servers,
+	}
+}
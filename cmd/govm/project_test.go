@@ -0,0 +1,103 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestConfigMigrateProjects(t *testing.T) {
+	config := &Config{
+		Servers: ServerConfigList{
+			NewServerConfig("web1", UserEmailList{"admin@example.com", "alice@example.com"}),
+			NewServerConfig("web2", UserEmailList{"admin@example.com"}),
+		},
+	}
+	migrated, ok := config.MigrateProjects()
+	if !ok {
+		t.Fatalf("MigrateProjects did not migrate")
+	}
+	if len(migrated.Projects) != 1 || migrated.Projects[0].GetRole("admin@example.com") != OwnerProjectRole {
+		t.Fatalf("Projects = %+v, want the default project owned by admin", migrated.Projects)
+	}
+	for _, server := range migrated.Servers {
+		if server.Project != DefaultProjectName {
+			t.Errorf("server %s is in project %q, want %q", server.Name, server.Project, DefaultProjectName)
+		}
+	}
+
+	// Nobody gets access to more servers than before
+	for _, server := range config.Servers {
+		for _, email := range []string{"admin@example.com", "alice@example.com"} {
+			if config.ServerHasAccessToEmail(server.Name, email) != migrated.ServerHasAccessToEmail(server.Name, email) {
+				t.Errorf("access of %s to %s changed", email, server.Name)
+			}
+		}
+	}
+
+	if _, ok := migrated.MigrateProjects(); ok {
+		t.Errorf("MigrateProjects migrated a config with projects")
+	}
+}
+
+func TestConfigProjectMembers(t *testing.T) {
+	config := &Config{
+		Projects: ProjectConfigList{
+			{Name: "team", Members: ProjectMemberConfigList{{Email: "alice@example.com", Role: OwnerProjectRole}}},
+		},
+	}
+	config = config.AddServer("web1", "team", UserEmailList{})
+	if config.ServerHasAccessToEmail("web1", "bob@example.com") {
+		t.Errorf("bob has access before joining the project")
+	}
+
+	updated := config.SetProjectMember("team", "bob@example.com", MemberProjectRole)
+	if !updated.ServerHasAccessToEmail("web1", "bob@example.com") {
+		t.Errorf("bob has no access after joining the project")
+	}
+	if config.ServerHasAccessToEmail("web1", "bob@example.com") {
+		t.Errorf("SetProjectMember changed the original config")
+	}
+
+	updated = updated.RemoveProjectMember("team", "bob@example.com")
+	if updated.ServerHasAccessToEmail("web1", "bob@example.com") {
+		t.Errorf("bob has access after leaving the project")
+	}
+}
+
+func TestConfigAddAndRemoveProject(t *testing.T) {
+	config := &Config{}
+	updated := config.AddProject("team", "alice@example.com")
+	project := updated.Projects.findByName("team")
+	if project == nil || project.GetRole("alice@example.com") != OwnerProjectRole {
+		t.Fatalf("Projects = %+v, want team owned by alice", updated.Projects)
+	}
+	if len(config.Projects) != 0 {
+		t.Errorf("AddProject changed the original config")
+	}
+	if updated.RemoveProject("team").Projects.findByName("team") != nil {
+		t.Errorf("RemoveProject did not remove the project")
+	}
+}
+
+func TestProjectCheckLastOwner(t *testing.T) {
+	project := &ProjectConfig{
+		Name: "team",
+		Members: ProjectMemberConfigList{
+			{Email: "alice@example.com", Role: OwnerProjectRole},
+			{Email: "bob@example.com", Role: MemberProjectRole},
+		},
+	}
+	if err := project.checkProjectMember("alice@example.com", MemberProjectRole); err != ErrLastOwner {
+		t.Errorf("demoting the last owner: err = %v, want %v", err, ErrLastOwner)
+	}
+	if err := project.checkProjectMember("alice@example.com", ""); err != ErrLastOwner {
+		t.Errorf("removing the last owner: err = %v, want %v", err, ErrLastOwner)
+	}
+	if err := project.checkProjectMember("bob@example.com", ""); err != nil {
+		t.Errorf("removing a member: %v", err)
+	}
+	if err := project.checkProjectMember("bob@example.com", OwnerProjectRole); err != nil {
+		t.Errorf("promoting a member: %v", err)
+	}
+}
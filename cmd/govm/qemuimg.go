@@ -0,0 +1,48 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// qemuImgInfo is the part of `qemu-img info --output=json` we're interested in
+type qemuImgInfo struct {
+	VirtualSize uint64 `json:"virtual-size"`
+	ActualSize  uint64 `json:"actual-size"`
+	Format      string `json:"format"`
+}
+
+// qemuImgCreate creates a new qcow2 image with the given virtual size in bytes
+func qemuImgCreate(file string, size uint64) error {
+	output, err := exec.Command(QemuImgCommand, "create", "-q", "-f", "qcow2", file, strconv.FormatUint(size, 10)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("qemuImgCreate: %s: %v: %s", file, err, output)
+	}
+	return nil
+}
+
+// qemuImgResize changes the virtual size of an image which is not in use
+func qemuImgResize(file string, size uint64) error {
+	output, err := exec.Command(QemuImgCommand, "resize", "-q", file, strconv.FormatUint(size, 10)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("qemuImgResize: %s: %v: %s", file, err, output)
+	}
+	return nil
+}
+
+// qemuImgGetInfo reads image information. The image may be in use by a running domain.
+func qemuImgGetInfo(file string) (*qemuImgInfo, error) {
+	output, err := exec.Command(QemuImgCommand, "info", "-U", "--output=json", file).Output()
+	if err != nil {
+		return nil, fmt.Errorf("qemuImgGetInfo: %s: %v", file, err)
+	}
+	var info qemuImgInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("qemuImgGetInfo: %s: failed to parse output: %v", file, err)
+	}
+	return &info, nil
+}
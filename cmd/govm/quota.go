@@ -0,0 +1,178 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrQuotaExceeded = errors.New("the quota would be exceeded")
+
+const UserQuotaScope = "user"
+const GroupQuotaScope = "group"
+
+// QuotaConfig limits the resources of the servers of a user or a group. A
+// zero limit is unlimited.
+type QuotaConfig struct {
+
+	// Servers is the maximum number of servers
+	Servers int `yaml:"servers,omitempty"`
+
+	// CPUs is the maximum number of virtual CPUs in total
+	CPUs uint `yaml:"cpus,omitempty"`
+
+	// Memory is the maximum memory in total in bytes
+	Memory uint64 `yaml:"memory,omitempty"`
+
+	// Disk is the maximum size of the volumes in total in bytes
+	Disk uint64 `yaml:"disk,omitempty"`
+}
+
+// UserConfig represents the settings of a user
+type UserConfig struct {
+	Email string       `yaml:"email"`
+	Quota *QuotaConfig `yaml:"quota,omitempty"`
+}
+
+// GroupConfig represents a group of users sharing a quota
+type GroupConfig struct {
+	Name  string        `yaml:"name"`
+	Users UserEmailList `yaml:"users"`
+	Quota *QuotaConfig  `yaml:"quota,omitempty"`
+}
+
+type UserConfigList []*UserConfig
+
+type GroupConfigList []*GroupConfig
+
+// QuotaUsageModel is the resources of servers, either used or requested
+type QuotaUsageModel struct {
+	Servers int
+	CPUs    uint
+	Memory  uint64
+	Disk    uint64
+}
+
+// add adds the resources of the server
+func (usage *QuotaUsageModel) add(server *ServerModel) {
+	usage.Servers++
+	usage.CPUs += server.CPUs
+	usage.Memory += server.Memory
+	usage.Disk += server.Volumes.TotalSize()
+}
+
+// QuotaModel is a quota of a user or a group and its current usage. Each
+// server counts against all users who have access to it.
+type QuotaModel struct {
+
+	// Scope is user or group
+	Scope string
+
+	// Name is the email address of the user or the name of the group
+	Name string
+
+	// Limit is the quota
+	Limit QuotaConfig
+
+	// Usage is the resources of the servers of the user or the group members
+	Usage QuotaUsageModel
+}
+
+// Check returns ErrQuotaExceeded if the requested resources do not fit in
+// the quota
+func (item *QuotaModel) Check(request QuotaUsageModel) error {
+	if item.Limit.Servers > 0 && item.Usage.Servers+request.Servers > item.Limit.Servers {
+		return fmt.Errorf("%w: %s %s: %d of %d servers", ErrQuotaExceeded, item.Scope, item.Name, item.Usage.Servers, item.Limit.Servers)
+	}
+	if item.Limit.CPUs > 0 && item.Usage.CPUs+request.CPUs > item.Limit.CPUs {
+		return fmt.Errorf("%w: %s %s: %d of %d virtual CPUs", ErrQuotaExceeded, item.Scope, item.Name, item.Usage.CPUs, item.Limit.CPUs)
+	}
+	if item.Limit.Memory > 0 && item.Usage.Memory+request.Memory > item.Limit.Memory {
+		return fmt.Errorf("%w: %s %s: %d of %d bytes of memory", ErrQuotaExceeded, item.Scope, item.Name, item.Usage.Memory, item.Limit.Memory)
+	}
+	if item.Limit.Disk > 0 && item.Usage.Disk+request.Disk > item.Limit.Disk {
+		return fmt.Errorf("%w: %s %s: %d of %d bytes of disk", ErrQuotaExceeded, item.Scope, item.Name, item.Usage.Disk, item.Limit.Disk)
+	}
+	return nil
+}
+
+func (item *QuotaModel) ToDTO() QuotaDTO {
+	return QuotaDTO{
+		Scope: item.Scope,
+		Name:  item.Name,
+		Limit: QuotaResourcesDTO{
+			Servers: item.Limit.Servers,
+			CPUs:    item.Limit.CPUs,
+			Memory:  item.Limit.Memory,
+			Disk:    item.Limit.Disk,
+		},
+		Usage: QuotaResourcesDTO{
+			Servers: item.Usage.Servers,
+			CPUs:    item.Usage.CPUs,
+			Memory:  item.Usage.Memory,
+			Disk:    item.Usage.Disk,
+		},
+	}
+}
+
+// GetQuotas returns the quotas of the user and of the groups of the user with
+// their usage in the servers
+func (c *Config) GetQuotas(email string, servers []*ServerModel) []*QuotaModel {
+	var list []*QuotaModel
+	for _, user := range c.Users {
+		if user.Email == email && user.Quota != nil {
+			list = append(list, c.getQuota(UserQuotaScope, user.Email, *user.Quota, UserEmailList{email}, servers))
+		}
+	}
+	for _, group := range c.Groups {
+		if group.Quota != nil && group.Users.contains(email) {
+			list = append(list, c.getQuota(GroupQuotaScope, group.Name, *group.Quota, group.Users, servers))
+		}
+	}
+	return list
+}
+
+// HasQuotas returns true if any quota applies to the user
+func (c *Config) HasQuotas(email string) bool {
+	for _, user := range c.Users {
+		if user.Email == email && user.Quota != nil {
+			return true
+		}
+	}
+	for _, group := range c.Groups {
+		if group.Quota != nil && group.Users.contains(email) {
+			return true
+		}
+	}
+	return false
+}
+
+// getQuota sums the servers which any of the users has access to
+func (c *Config) getQuota(scope, name string, limit QuotaConfig, users UserEmailList, servers []*ServerModel) *QuotaModel {
+	item := &QuotaModel{
+		Scope: scope,
+		Name:  name,
+		Limit: limit,
+	}
+	for _, server := range servers {
+		for _, email := range users {
+			if c.ServerHasAccessToEmail(server.Name, email) {
+				item.Usage.add(server)
+				break
+			}
+		}
+	}
+	return item
+}
+
+// CheckQuotas returns ErrQuotaExceeded if the requested resources do not fit
+// in all quotas of the user
+func CheckQuotas(quotas []*QuotaModel, request QuotaUsageModel) error {
+	for _, quota := range quotas {
+		if err := quota.Check(request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
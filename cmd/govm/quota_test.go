@@ -0,0 +1,59 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfigGetQuotas(t *testing.T) {
+	const gib uint64 = 1024 * 1024 * 1024
+	config := &Config{
+		Servers: ServerConfigList{
+			NewServerConfig("web1", UserEmailList{"alice@example.com"}),
+			NewServerConfig("web2", UserEmailList{"alice@example.com", "bob@example.com"}),
+			NewServerConfig("db1", UserEmailList{"bob@example.com"}),
+		},
+		Users: UserConfigList{
+			{Email: "alice@example.com", Quota: &QuotaConfig{Servers: 2}},
+		},
+		Groups: GroupConfigList{
+			{Name: "team", Users: UserEmailList{"alice@example.com", "bob@example.com"}, Quota: &QuotaConfig{CPUs: 4, Disk: 40 * gib}},
+		},
+	}
+	var servers []*ServerModel
+	for _, name := range []string{"web1", "web2", "db1"} {
+		server := NewServerModel(name, StoppedServerStatusCode, nil)
+		server.CPUs = 1
+		server.Memory = gib
+		server.Volumes = VolumeModelList{NewVolumeModel(RootDiskDevice, RootDiskDevice, 10*gib, true)}
+		servers = append(servers, server)
+	}
+
+	quotas := config.GetQuotas("alice@example.com", servers)
+	if len(quotas) != 2 {
+		t.Fatalf("GetQuotas returned %d quotas, want 2", len(quotas))
+	}
+	if quotas[0].Scope != UserQuotaScope || quotas[0].Usage.Servers != 2 {
+		t.Errorf("user quota = %+v, want 2 servers used", quotas[0])
+	}
+	// Shared servers count once for the group
+	if quotas[1].Scope != GroupQuotaScope || quotas[1].Usage.Servers != 3 || quotas[1].Usage.Disk != 30*gib {
+		t.Errorf("group quota = %+v, want 3 servers and 30 GiB used", quotas[1])
+	}
+
+	if err := CheckQuotas(quotas, QuotaUsageModel{Servers: 1, CPUs: 1}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("CheckQuotas for a new server = %v, want ErrQuotaExceeded", err)
+	}
+	if err := CheckQuotas(quotas, QuotaUsageModel{Disk: 10 * gib}); err != nil {
+		t.Errorf("CheckQuotas for a volume within the quota: %v", err)
+	}
+	if err := CheckQuotas(quotas, QuotaUsageModel{Disk: 11 * gib}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("CheckQuotas for a volume over the quota = %v, want ErrQuotaExceeded", err)
+	}
+
+	if config.HasQuotas("carol@example.com") {
+		t.Errorf("HasQuotas returned true for a user without quotas")
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// decodeScreenshot decodes a screenshot in the format libvirt returned. QEMU
+// uses PPM, while newer versions may return PNG.
+func decodeScreenshot(mimeType string, data []byte) (image.Image, error) {
+	switch mimeType {
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decodeScreenshot: failed to decode PNG: %v", err)
+		}
+		return img, nil
+	case "image/x-portable-pixmap", "image/x-portable-anymap", "image/x-ppm", "":
+		img, err := decodePPM(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decodeScreenshot: %v", err)
+		}
+		return img, nil
+	default:
+		return nil, fmt.Errorf("decodeScreenshot: unsupported format: %s", mimeType)
+	}
+}
+
+// decodePPM decodes a binary (P6) portable pixmap
+func decodePPM(r io.Reader) (image.Image, error) {
+	reader := bufio.NewReader(r)
+	var header [4]int
+	for i := range header {
+		value, err := readPPMToken(reader)
+		if err != nil {
+			return nil, fmt.Errorf("decodePPM: failed to read header: %v", err)
+		}
+		if i == 0 {
+			if value != "P6" {
+				return nil, fmt.Errorf("decodePPM: unsupported format: %s", value)
+			}
+			continue
+		}
+		header[i], err = strconv.Atoi(value)
+		if err != nil || header[i] <= 0 {
+			return nil, fmt.Errorf("decodePPM: illegal header value: %s", value)
+		}
+	}
+	width, height, maxValue := header[1], header[2], header[3]
+	if maxValue > 255 {
+		return nil, fmt.Errorf("decodePPM: unsupported maximum value: %d", maxValue)
+	}
+	if width > ScreenshotMaxDimension || height > ScreenshotMaxDimension {
+		return nil, fmt.Errorf("decodePPM: image too large: %dx%d", width, height)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	row := make([]byte, width*3)
+	for y := 0; y < height; y++ {
+		if _, err := io.ReadFull(reader, row); err != nil {
+			return nil, fmt.Errorf("decodePPM: failed to read pixels: %v", err)
+		}
+		offset := y * img.Stride
+		for x := 0; x < width; x++ {
+			img.Pix[offset+x*4] = uint8(int(row[x*3]) * 255 / maxValue)
+			img.Pix[offset+x*4+1] = uint8(int(row[x*3+1]) * 255 / maxValue)
+			img.Pix[offset+x*4+2] = uint8(int(row[x*3+2]) * 255 / maxValue)
+			img.Pix[offset+x*4+3] = 0xff
+		}
+	}
+	return img, nil
+}
+
+// readPPMToken reads the next whitespace separated header value, skipping
+// comments. The single whitespace after the value is consumed.
+func readPPMToken(reader *bufio.Reader) (string, error) {
+	var token []byte
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case c == '#' && len(token) == 0:
+			if _, err := reader.ReadBytes('\n'); err != nil {
+				return "", err
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if len(token) > 0 {
+				return string(token), nil
+			}
+		default:
+			token = append(token, c)
+		}
+	}
+}
+
+// scaleImage scales the image to the width keeping the aspect ratio. Each
+// pixel is the average of the source pixels it covers.
+func scaleImage(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		return src
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			var r, g, b, count uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := src.At(sx, sy).RGBA()
+					r, g, b = r+cr, g+cg, b+cb
+					count++
+				}
+			}
+			dst.Set(x, y, color.RGBA{
+				R: uint8(r / count >> 8),
+				G: uint8(g / count >> 8),
+				B: uint8(b / count >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
+
+// encodePNG returns the image as PNG
+func encodePNG(img image.Image) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, fmt.Errorf("encodePNG: %v", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// Thumbnail is a cached small screenshot of a server
+type Thumbnail struct {
+
+	// Data is the PNG image
+	Data []byte
+
+	// Updated is the time the screenshot was taken
+	Updated time.Time
+}
+
+// ThumbnailCache keeps thumbnails of the running servers up to date
+type ThumbnailCache struct {
+	mutex      sync.Mutex
+	thumbnails map[string]*Thumbnail
+	service    ServerService
+	width      int
+	stop       chan struct{}
+}
+
+func NewThumbnailCache(service ServerService, width int) *ThumbnailCache {
+	return &ThumbnailCache{
+		thumbnails: make(map[string]*Thumbnail),
+		service:    service,
+		width:      width,
+	}
+}
+
+// Start starts refreshing the thumbnails in the background
+func (c *ThumbnailCache) Start(interval time.Duration) {
+	c.stop = make(chan struct{})
+	go func() {
+		c.refresh()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.refresh()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing the thumbnails
+func (c *ThumbnailCache) Stop() {
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
+// Get returns the thumbnail of the server, otherwise nil
+func (c *ThumbnailCache) Get(name string) *Thumbnail {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.thumbnails[name]
+}
+
+// refresh takes new thumbnails of the running servers and forgets the
+// thumbnails of the servers which are not running
+func (c *ThumbnailCache) refresh() {
+	list, err := c.service.GetServerList()
+	if err != nil {
+		log.Printf("ThumbnailCache: Failed to get server list: %v", err)
+		return
+	}
+	running := make(map[string]bool)
+	for _, server := range list {
+		if server.Status != StartedServerStatusCode {
+			continue
+		}
+		running[server.Name] = true
+		img, err := c.service.GetScreenshot(server.Name)
+		if err != nil {
+			log.Printf("ThumbnailCache: Failed to take screenshot of %s: %v", server.Name, err)
+			continue
+		}
+		data, err := encodePNG(scaleImage(img, c.width))
+		if err != nil {
+			log.Printf("ThumbnailCache: Failed to encode thumbnail of %s: %v", server.Name, err)
+			continue
+		}
+		c.mutex.Lock()
+		c.thumbnails[server.Name] = &Thumbnail{Data: data, Updated: time.Now()}
+		c.mutex.Unlock()
+	}
+	c.mutex.Lock()
+	for name := range c.thumbnails {
+		if !running[name] {
+			delete(c.thumbnails, name)
+		}
+	}
+	c.mutex.Unlock()
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+func TestDecodeScreenshotPPM(t *testing.T) {
+	data := []byte("P6\n# QEMU screendump\n2 2\n255\n" +
+		"\xff\x00\x00\x00\xff\x00" +
+		"\x00\x00\xff\xff\xff\xff")
+	img, err := decodeScreenshot("image/x-portable-pixmap", data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 2 {
+		t.Fatalf("Expected 2x2 image, got %v", img.Bounds())
+	}
+	expected := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}, {255, 255, 255, 255}}
+	for i, want := range expected {
+		got := color.RGBAModel.Convert(img.At(i%2, i/2)).(color.RGBA)
+		if got != want {
+			t.Errorf("Pixel %d: expected %v, got %v", i, want, got)
+		}
+	}
+
+	if _, err := decodeScreenshot("image/x-portable-pixmap", data[:len(data)-1]); err == nil {
+		t.Errorf("Expected an error for truncated pixels")
+	}
+	if _, err := decodeScreenshot("image/x-portable-pixmap", []byte("P3\n2 2\n255\n")); err == nil {
+		t.Errorf("Expected an error for ASCII pixmap")
+	}
+}
+
+func TestScaleImage(t *testing.T) {
+	data := []byte("P6 4 2 255 " +
+		"\x00\x00\x00\xff\xff\xff\x00\x00\x00\x00\x00\x00" +
+		"\xff\xff\xff\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	img, err := decodePPM(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	scaled := scaleImage(img, 2)
+	if scaled.Bounds().Dx() != 2 || scaled.Bounds().Dy() != 1 {
+		t.Fatalf("Expected 2x1 image, got %v", scaled.Bounds())
+	}
+	left := color.RGBAModel.Convert(scaled.At(0, 0)).(color.RGBA)
+	right := color.RGBAModel.Convert(scaled.At(1, 0)).(color.RGBA)
+	if left.R != 127 || right.R != 0 {
+		t.Errorf("Expected averaged pixels 127 and 0, got %d and %d", left.R, right.R)
+	}
+}
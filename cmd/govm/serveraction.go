@@ -14,13 +14,25 @@ type ServerAction string
 type ServerActionCode int
 
 const (
-	CreateServerAction  = "create"
-	DeployServerAction  = "deploy"
-	StartServerAction   = "start"
-	StopServerAction    = "stop"
-	RestartServerAction = "restart"
-	DeleteServerAction  = "delete"
-	ConsoleServerAction = "console"
+	CreateServerAction    = "create"
+	DeployServerAction    = "deploy"
+	StartServerAction     = "start"
+	StopServerAction      = "stop"
+	RestartServerAction   = "restart"
+	DeleteServerAction    = "delete"
+	ConsoleServerAction   = "console"
+	ResizeServerAction    = "resize"
+	VolumeServerAction    = "volume"
+	NetworkServerAction   = "network"
+	ForceOffServerAction  = "force-off"
+	ResetServerAction     = "reset"
+	PauseServerAction     = "pause"
+	ResumeServerAction    = "resume"
+	SuspendServerAction   = "suspend"
+	HibernateServerAction = "hibernate"
+	RestoreServerAction   = "restore"
+	AccessServerAction    = "access"
+	MigrateServerAction   = "migrate"
 )
 
 const (
@@ -31,6 +43,18 @@ const (
 	RestartServerActionCode
 	DeleteServerActionCode
 	ConsoleServerActionCode
+	ResizeServerActionCode
+	VolumeServerActionCode
+	NetworkServerActionCode
+	ForceOffServerActionCode
+	ResetServerActionCode
+	PauseServerActionCode
+	ResumeServerActionCode
+	SuspendServerActionCode
+	HibernateServerActionCode
+	RestoreServerActionCode
+	AccessServerActionCode
+	MigrateServerActionCode
 )
 
 func AllServerActionCodes() []ServerActionCode {
@@ -42,6 +66,18 @@ func AllServerActionCodes() []ServerActionCode {
 		RestartServerActionCode,
 		DeleteServerActionCode,
 		ConsoleServerActionCode,
+		ResizeServerActionCode,
+		VolumeServerActionCode,
+		NetworkServerActionCode,
+		ForceOffServerActionCode,
+		ResetServerActionCode,
+		PauseServerActionCode,
+		ResumeServerActionCode,
+		SuspendServerActionCode,
+		HibernateServerActionCode,
+		RestoreServerActionCode,
+		AccessServerActionCode,
+		MigrateServerActionCode,
 	}
 }
 
@@ -55,6 +91,18 @@ func (d ServerActionCode) String() string {
 		RestartServerAction,
 		DeleteServerAction,
 		ConsoleServerAction,
+		ResizeServerAction,
+		VolumeServerAction,
+		NetworkServerAction,
+		ForceOffServerAction,
+		ResetServerAction,
+		PauseServerAction,
+		ResumeServerAction,
+		SuspendServerAction,
+		HibernateServerAction,
+		RestoreServerAction,
+		AccessServerAction,
+		MigrateServerAction,
 	}[d]
 }
 
@@ -68,6 +116,18 @@ func (d ServerActionCode) ServerAction() ServerAction {
 		RestartServerAction,
 		DeleteServerAction,
 		ConsoleServerAction,
+		ResizeServerAction,
+		VolumeServerAction,
+		NetworkServerAction,
+		ForceOffServerAction,
+		ResetServerAction,
+		PauseServerAction,
+		ResumeServerAction,
+		SuspendServerAction,
+		HibernateServerAction,
+		RestoreServerAction,
+		AccessServerAction,
+		MigrateServerAction,
 	}[d]
 }
 
@@ -88,6 +148,30 @@ func ParseServerActionCode(name string) (ServerActionCode, error) {
 		return DeleteServerActionCode, nil
 	case ConsoleServerAction:
 		return ConsoleServerActionCode, nil
+	case ResizeServerAction:
+		return ResizeServerActionCode, nil
+	case VolumeServerAction:
+		return VolumeServerActionCode, nil
+	case NetworkServerAction:
+		return NetworkServerActionCode, nil
+	case ForceOffServerAction:
+		return ForceOffServerActionCode, nil
+	case ResetServerAction:
+		return ResetServerActionCode, nil
+	case PauseServerAction:
+		return PauseServerActionCode, nil
+	case ResumeServerAction:
+		return ResumeServerActionCode, nil
+	case SuspendServerAction:
+		return SuspendServerActionCode, nil
+	case HibernateServerAction:
+		return HibernateServerActionCode, nil
+	case RestoreServerAction:
+		return RestoreServerActionCode, nil
+	case AccessServerAction:
+		return AccessServerActionCode, nil
+	case MigrateServerAction:
+		return MigrateServerActionCode, nil
 	default:
 		return -1, fmt.Errorf("unknown server action code: %s", name)
 	}
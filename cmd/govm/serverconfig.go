@@ -2,10 +2,14 @@
 
 package main
 
-// ServerConfig represents a server and the users that have access to it
+// ServerConfig represents a server and the users that have access to it in
+// addition to the members of its project. The host is empty for a server on
+// this host, otherwise the name of the host the server was migrated to.
 type ServerConfig struct {
-	Name  string        `yaml:"name"`
-	Users UserEmailList `yaml:"users"`
+	Name    string        `yaml:"name"`
+	Project string        `yaml:"project,omitempty"`
+	Host    string        `yaml:"host,omitempty"`
+	Users   UserEmailList `yaml:"users"`
 }
 
 func NewServerConfig(
@@ -13,8 +17,8 @@ func NewServerConfig(
 	users UserEmailList,
 ) *ServerConfig {
 	return &ServerConfig{
-		name,
-		users,
+		Name:  name,
+		Users: users,
 	}
 }
 
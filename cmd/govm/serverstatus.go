@@ -23,6 +23,9 @@ const SuspendedServerStatus string = "suspended"
 const UnknownServerStatus string = "unknown"
 const DeletingServerStatus string = "deleting"
 const DeletedServerStatus string = "deleted"
+const HibernatedServerStatus string = "hibernated"
+const MigratingServerStatus string = "migrating"
+const MovedServerStatus string = "moved"
 
 const (
 	UninitializedServerStatusCode ServerStatusCode = iota
@@ -38,8 +41,32 @@ const (
 	UnknownServerStatusCode
 	DeletingServerStatusCode
 	DeletedServerStatusCode
+	HibernatedServerStatusCode
+	MigratingServerStatusCode
+	MovedServerStatusCode
 )
 
+func AllServerStatusCodes() []ServerStatusCode {
+	return []ServerStatusCode{
+		UninitializedServerStatusCode,
+		DeployingServerStatusCode,
+		StoppedServerStatusCode,
+		StartingServerStatusCode,
+		StoppingServerStatusCode,
+		StartedServerStatusCode,
+		BlockedServerStatusCode,
+		PausedServerStatusCode,
+		CrashedServerStatusCode,
+		SuspendedServerStatusCode,
+		UnknownServerStatusCode,
+		DeletingServerStatusCode,
+		DeletedServerStatusCode,
+		HibernatedServerStatusCode,
+		MigratingServerStatusCode,
+		MovedServerStatusCode,
+	}
+}
+
 // String method to get the name of the server status code
 func (d ServerStatusCode) String() string {
 	return [...]string{
@@ -56,6 +83,9 @@ func (d ServerStatusCode) String() string {
 		UnknownServerStatus,
 		DeletingServerStatus,
 		DeletedServerStatus,
+		HibernatedServerStatus,
+		MigratingServerStatus,
+		MovedServerStatus,
 	}[d]
 }
 
@@ -81,6 +111,21 @@ func (d ServerStatusCode) GetAvailableActions(
 		if contains(enabledActions, DeleteServerActionCode) {
 			actions = append(actions, DeleteServerActionCode)
 		}
+		if contains(enabledActions, ResizeServerActionCode) {
+			actions = append(actions, ResizeServerActionCode)
+		}
+		if contains(enabledActions, VolumeServerActionCode) {
+			actions = append(actions, VolumeServerActionCode)
+		}
+		if contains(enabledActions, NetworkServerActionCode) {
+			actions = append(actions, NetworkServerActionCode)
+		}
+		if contains(enabledActions, AccessServerActionCode) {
+			actions = append(actions, AccessServerActionCode)
+		}
+		if contains(enabledActions, MigrateServerActionCode) {
+			actions = append(actions, MigrateServerActionCode)
+		}
 		break
 
 	case StartedServerStatusCode:
@@ -90,6 +135,93 @@ func (d ServerStatusCode) GetAvailableActions(
 				actions = append(actions, RestartServerActionCode)
 			}
 		}
+		if contains(enabledActions, ResizeServerActionCode) {
+			actions = append(actions, ResizeServerActionCode)
+		}
+		if contains(enabledActions, VolumeServerActionCode) {
+			actions = append(actions, VolumeServerActionCode)
+		}
+		if contains(enabledActions, NetworkServerActionCode) {
+			actions = append(actions, NetworkServerActionCode)
+		}
+		if contains(enabledActions, AccessServerActionCode) {
+			actions = append(actions, AccessServerActionCode)
+		}
+		if contains(enabledActions, ForceOffServerActionCode) {
+			actions = append(actions, ForceOffServerActionCode)
+		}
+		if contains(enabledActions, ResetServerActionCode) {
+			actions = append(actions, ResetServerActionCode)
+		}
+		if contains(enabledActions, PauseServerActionCode) {
+			actions = append(actions, PauseServerActionCode)
+		}
+		if contains(enabledActions, SuspendServerActionCode) {
+			actions = append(actions, SuspendServerActionCode)
+		}
+		if contains(enabledActions, HibernateServerActionCode) {
+			actions = append(actions, HibernateServerActionCode)
+		}
+		if contains(enabledActions, MigrateServerActionCode) {
+			actions = append(actions, MigrateServerActionCode)
+		}
+		break
+
+	case BlockedServerStatusCode:
+		if contains(enabledActions, ForceOffServerActionCode) {
+			actions = append(actions, ForceOffServerActionCode)
+		}
+		if contains(enabledActions, ResetServerActionCode) {
+			actions = append(actions, ResetServerActionCode)
+		}
+		break
+
+	case PausedServerStatusCode:
+		if contains(enabledActions, ResumeServerActionCode) {
+			actions = append(actions, ResumeServerActionCode)
+		}
+		if contains(enabledActions, ForceOffServerActionCode) {
+			actions = append(actions, ForceOffServerActionCode)
+		}
+		if contains(enabledActions, HibernateServerActionCode) {
+			actions = append(actions, HibernateServerActionCode)
+		}
+		if contains(enabledActions, MigrateServerActionCode) {
+			actions = append(actions, MigrateServerActionCode)
+		}
+		break
+
+	case SuspendedServerStatusCode:
+		if contains(enabledActions, ResumeServerActionCode) {
+			actions = append(actions, ResumeServerActionCode)
+		}
+		if contains(enabledActions, ForceOffServerActionCode) {
+			actions = append(actions, ForceOffServerActionCode)
+		}
+		break
+
+	// The memory of the server has been saved to disk
+	case HibernatedServerStatusCode:
+		if contains(enabledActions, RestoreServerActionCode) {
+			actions = append(actions, RestoreServerActionCode)
+		}
+		if contains(enabledActions, DeleteServerActionCode) {
+			actions = append(actions, DeleteServerActionCode)
+		}
+		break
+
+	// A server on another host can only be moved back
+	case MovedServerStatusCode:
+		if contains(enabledActions, MigrateServerActionCode) {
+			actions = append(actions, MigrateServerActionCode)
+		}
+		break
+
+	// A guest which ignores the shutdown request can only be forced off
+	case StoppingServerStatusCode, CrashedServerStatusCode:
+		if contains(enabledActions, ForceOffServerActionCode) {
+			actions = append(actions, ForceOffServerActionCode)
+		}
 		break
 
 	default:
@@ -127,6 +259,12 @@ func ParseServerStatusCode(name string) (ServerStatusCode, error) {
 		return DeletedServerStatusCode, nil
 	case UnknownServerStatus:
 		return UnknownServerStatusCode, nil
+	case HibernatedServerStatus:
+		return HibernatedServerStatusCode, nil
+	case MigratingServerStatus:
+		return MigratingServerStatusCode, nil
+	case MovedServerStatus:
+		return MovedServerStatusCode, nil
 	default:
 		return -1, fmt.Errorf("unknown server status code: %s", name)
 	}
@@ -2,17 +2,59 @@
 
 package main
 
+import (
+	"image"
+	"io"
+	"time"
+)
+
 type ServerService interface {
 	Start() error
 	Stop() error
-	AddServer(name string) (*ServerModel, error)
+	AddServer(name string, options *ServerOptions) (*ServerModel, error)
 	GetServerList() ([]*ServerModel, error)
+	GetServerStats() ([]*ServerStatsModel, error)
 	FindServer(name string) (*ServerModel, error)
 	DeployServer(name string) (*ServerModel, error)
 	StartServer(name string) (*ServerModel, error)
-	StopServer(name string) (*ServerModel, error)
-	RestartServer(name string) (*ServerModel, error)
+	StopServer(name string, options StopOptions) (*ServerModel, error)
+	RestartServer(name string, options StopOptions) (*ServerModel, error)
 	DeleteServer(name string) (*ServerModel, error)
-	GetVNC(name string) (string, error)
-	SetVNCPassword(name, password string) error
+	ForceOffServer(name string) (*ServerModel, error)
+	ResetServer(name string) (*ServerModel, error)
+	PauseServer(name string) (*ServerModel, error)
+	ResumeServer(name string) (*ServerModel, error)
+	SuspendServer(name string) (*ServerModel, error)
+	HibernateServer(name string) (*ServerModel, error)
+	RestoreServer(name string) (*ServerModel, error)
+	MigrateServer(name string, options MigrateOptions) (*ServerModel, error)
+	GetGuestInfo(name string) (*GuestInfoModel, error)
+	ResetPassword(name, password string) (string, error)
+	SetAuthorizedKeys(name string, keys []string, appendKeys bool) (string, error)
+	FreezeFileSystems(name string, duration time.Duration) error
+	ThawFileSystems(name string) error
+	SendKeys(name string, keys []uint) error
+	GetConsole(name string) (*ConsoleModel, error)
+	SetConsolePassword(name, password string) error
+	GetScreenshot(name string) (image.Image, error)
+	OpenSerialConsole(name string) (io.ReadWriteCloser, error)
+	GetConsoleLog(name string, tail int) ([]string, error)
+	ResizeServer(name string, size uint64) (*ServerModel, error)
+	AddVolume(name, volume string, size uint64) (*ServerModel, error)
+	AttachVolume(name, volume string) (*ServerModel, error)
+	DetachVolume(name, volume string) (*ServerModel, error)
+	DeleteVolume(name, volume string) (*ServerModel, error)
+	GetStorage() (*StorageModel, error)
+	GetImageSize() (uint64, error)
+	GetHost() (*HostModel, error)
+	GetNetworkList() (NetworkModelList, error)
+	FindNetwork(name string) (*NetworkModel, error)
+	CreateNetwork(network *NetworkModel) (*NetworkModel, error)
+	StartNetwork(name string) (*NetworkModel, error)
+	StopNetwork(name string) (*NetworkModel, error)
+	DeleteNetwork(name string) error
+	AttachInterface(name string, nic *NetworkInterfaceModel) (*ServerModel, error)
+	DetachInterface(name, mac string) (*ServerModel, error)
+	GetFirewall(name string) (*FirewallModel, error)
+	SetFirewall(name string, firewall *FirewallModel, ruleSets []*FirewallRuleSetModel) (*FirewallModel, error)
 }
@@ -0,0 +1,53 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+// ServerStatsModel is the resource usage of a server. The counters grow from
+// the start of the server.
+type ServerStatsModel struct {
+
+	// Name is the name of the server
+	Name string
+
+	// Status is the status of the server
+	Status ServerStatusCode
+
+	// CPUTime is the CPU time used in nanoseconds
+	CPUTime uint64
+
+	// MemoryCurrent is the memory of the server in bytes
+	MemoryCurrent uint64
+
+	// MemoryMaximum is the maximum memory of the server in bytes
+	MemoryMaximum uint64
+
+	// MemoryUsable is the memory the guest could use without swapping in bytes
+	MemoryUsable uint64
+
+	// MemoryRSS is the memory the server uses on the host in bytes
+	MemoryRSS uint64
+
+	// Disks are the counters of the disks
+	Disks []DiskStatsModel
+
+	// Interfaces are the counters of the network interfaces
+	Interfaces []InterfaceStatsModel
+}
+
+// DiskStatsModel is the I/O of a disk of the server
+type DiskStatsModel struct {
+	Device        string
+	ReadBytes     uint64
+	WriteBytes    uint64
+	ReadRequests  uint64
+	WriteRequests uint64
+}
+
+// InterfaceStatsModel is the traffic of a network interface of the server
+type InterfaceStatsModel struct {
+	Device    string
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"libvirt.org/go/libvirt"
+)
+
+// serverUsername is the user cloud-init creates in new servers
+const serverUsername = "admin"
+
+// PendingAccessMetadataURI is the namespace of the access changes waiting
+// for cloud-init in the metadata of the domain
+const PendingAccessMetadataURI = "https://govm.sendanor.fi/xmlns/access/1.0"
+
+const PendingAccessMetadataPrefix = "govm"
+
+// ResetPassword sets the password of the user with the guest agent. Without
+// the agent a new cloud-init configuration sets it on the next boot.
+func (s *VirtioService) ResetPassword(name, password string) (string, error) {
+	if !s.accessEnabled {
+		return "", fmt.Errorf("ResetPassword: Not enabled")
+	}
+
+	encryptedPassword, err := encryptPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("ResetPassword: failed to encrypt password: %v", err)
+	}
+
+	log.Printf("ResetPassword: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return "", fmt.Errorf("ResetPassword: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return "", fmt.Errorf("ResetPassword: %v", err)
+	}
+	defer item.Free()
+
+	pending, err := loadPendingAccess(item)
+	if err != nil {
+		return "", fmt.Errorf("ResetPassword: %v", err)
+	}
+
+	err = item.SetUserPassword(serverUsername, encryptedPassword, libvirt.DOMAIN_PASSWORD_ENCRYPTED)
+	if err == nil {
+		log.Printf("ResetPassword: Password of %s changed with the guest agent", name)
+
+		// A pending password would replace this one on the next boot
+		if pending.Password != "" {
+			pending.Password = ""
+			err = s.updateCloudInit(conn, item, name, pending)
+			if err != nil {
+				return "", fmt.Errorf("ResetPassword: %v", err)
+			}
+		}
+		return GuestAgentAccessMethod, nil
+	}
+	if !errors.Is(describeGuestAgentError(err), ErrGuestAgentUnavailable) {
+		return "", fmt.Errorf("ResetPassword: failed to set password: %v", err)
+	}
+
+	log.Printf("ResetPassword: Guest agent of %s not available, using cloud-init: %v", name, err)
+	pending.Password = encryptedPassword
+	err = s.updateCloudInit(conn, item, name, pending)
+	if err != nil {
+		return "", fmt.Errorf("ResetPassword: %v", err)
+	}
+	return CloudInitAccessMethod, nil
+}
+
+// SetAuthorizedKeys adds the SSH keys of the user with the guest agent, or
+// replaces all keys if not appending. Without the agent a new cloud-init
+// configuration writes the keys on the next boot.
+func (s *VirtioService) SetAuthorizedKeys(name string, keys []string, appendKeys bool) (string, error) {
+	if !s.accessEnabled {
+		return "", fmt.Errorf("SetAuthorizedKeys: Not enabled")
+	}
+
+	log.Printf("SetAuthorizedKeys: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return "", fmt.Errorf("SetAuthorizedKeys: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return "", fmt.Errorf("SetAuthorizedKeys: %v", err)
+	}
+	defer item.Free()
+
+	pending, err := loadPendingAccess(item)
+	if err != nil {
+		return "", fmt.Errorf("SetAuthorizedKeys: %v", err)
+	}
+
+	var flags libvirt.DomainAuthorizedSSHKeysFlags
+	if appendKeys {
+		flags = libvirt.DOMAIN_AUTHORIZED_SSH_KEYS_SET_APPEND
+	}
+	err = item.AuthorizedSSHKeysSet(serverUsername, keys, flags)
+	if err == nil {
+		log.Printf("SetAuthorizedKeys: Keys of %s changed with the guest agent", name)
+
+		// Pending keys would be written back on the next boot after the
+		// keys have been replaced
+		if !appendKeys && pending.hasKeys() {
+			pending.Keys = nil
+			pending.ReplaceKeys = false
+			err = s.updateCloudInit(conn, item, name, pending)
+			if err != nil {
+				return "", fmt.Errorf("SetAuthorizedKeys: %v", err)
+			}
+		}
+		return GuestAgentAccessMethod, nil
+	}
+	if !errors.Is(describeGuestAgentError(err), ErrGuestAgentUnavailable) {
+		return "", fmt.Errorf("SetAuthorizedKeys: failed to set keys: %v", err)
+	}
+
+	log.Printf("SetAuthorizedKeys: Guest agent of %s not available, using cloud-init: %v", name, err)
+	pending.setKeys(keys, appendKeys)
+	err = s.updateCloudInit(conn, item, name, pending)
+	if err != nil {
+		return "", fmt.Errorf("SetAuthorizedKeys: %v", err)
+	}
+	return CloudInitAccessMethod, nil
+}
+
+// updateCloudInit replaces the cloud-init ISO of the server with the pending
+// changes and saves them to the domain, so a later change keeps the earlier
+// ones. The new instance id makes cloud-init apply the user data on the next
+// boot. A running server reads the new ISO only after it has been stopped
+// and started.
+func (s *VirtioService) updateCloudInit(conn *libvirt.Connect, item *libvirt.Domain, name string, pending *pendingAccess) error {
+	interfaces, err := getDomainInterfaces(item)
+	if err != nil {
+		return fmt.Errorf("updateCloudInit: %v", err)
+	}
+	userData, err := getPendingAccessUserData(pending)
+	if err != nil {
+		return fmt.Errorf("updateCloudInit: %v", err)
+	}
+	metaData := `instance-id: ` + name + `-` + strconv.FormatInt(time.Now().Unix(), 10) + `
+local-hostname: ` + name
+
+	err = s.createCloudInitVolume(conn, name, getCloudInitKey(name), metaData, userData, getCloudInitNetworkConfig(interfaces))
+	if err != nil {
+		return fmt.Errorf("updateCloudInit: %v", err)
+	}
+	err = savePendingAccess(item, pending)
+	if err != nil {
+		return fmt.Errorf("updateCloudInit: %v", err)
+	}
+	log.Printf("updateCloudInit: Cloud-Init ISO of %s updated", name)
+	return nil
+}
+
+// pendingAccess is the access changes in the cloud-init ISO of the server,
+// saved in the metadata of the domain
+type pendingAccess struct {
+	XMLName xml.Name `xml:"access"`
+
+	// Password is the encrypted password of the user, if changed
+	Password string `xml:"password,omitempty"`
+
+	// Keys are the SSH keys written to the authorized keys
+	Keys []string `xml:"key"`
+
+	// ReplaceKeys replaces the authorized keys instead of appending to them
+	ReplaceKeys bool `xml:"replace-keys,attr,omitempty"`
+}
+
+// hasKeys returns true if the authorized keys are changed
+func (pending *pendingAccess) hasKeys() bool {
+	return pending.ReplaceKeys || len(pending.Keys) != 0
+}
+
+// setKeys replaces the pending keys, or appends to them keeping the earlier
+// choice between replacing and appending
+func (pending *pendingAccess) setKeys(keys []string, appendKeys bool) {
+	if appendKeys {
+		pending.Keys = append(pending.Keys, keys...)
+		return
+	}
+	pending.Keys = append([]string(nil), keys...)
+	pending.ReplaceKeys = true
+}
+
+// loadPendingAccess returns the access changes saved to the domain, or empty
+// changes if there are none
+func loadPendingAccess(item *libvirt.Domain) (*pendingAccess, error) {
+	pending := &pendingAccess{}
+	data, err := item.GetMetadata(libvirt.DOMAIN_METADATA_ELEMENT, PendingAccessMetadataURI, libvirt.DOMAIN_AFFECT_CONFIG)
+	if err != nil {
+		libvirtError, ok := err.(libvirt.Error)
+		if ok && libvirtError.Code == libvirt.ERR_NO_DOMAIN_METADATA {
+			return pending, nil
+		}
+		return nil, fmt.Errorf("loadPendingAccess: failed to get metadata: %v", err)
+	}
+	err = xml.Unmarshal([]byte(data), pending)
+	if err != nil {
+		return nil, fmt.Errorf("loadPendingAccess: failed to parse metadata: %v", err)
+	}
+	return pending, nil
+}
+
+// savePendingAccess saves the access changes to the domain, or removes them
+// if there are none
+func savePendingAccess(item *libvirt.Domain, pending *pendingAccess) error {
+	data := ""
+	if pending.Password != "" || pending.hasKeys() {
+		out, err := xml.Marshal(pending)
+		if err != nil {
+			return fmt.Errorf("savePendingAccess: failed to encode metadata: %v", err)
+		}
+		data = string(out)
+	}
+	err := item.SetMetadata(libvirt.DOMAIN_METADATA_ELEMENT, data, PendingAccessMetadataPrefix, PendingAccessMetadataURI, libvirt.DOMAIN_AFFECT_CONFIG)
+	if err != nil {
+		return fmt.Errorf("savePendingAccess: failed to set metadata: %v", err)
+	}
+	return nil
+}
+
+// getPendingAccessUserData returns the cloud-init user data which creates the
+// user and applies the pending changes
+func getPendingAccessUserData(pending *pendingAccess) (string, error) {
+	userData := getCloudInitUserData(pending.Password)
+	if pending.Password != "" {
+		userData += `
+chpasswd:
+  expire: false
+  users:
+    - name: ` + serverUsername + `
+      password: ` + pending.Password + `
+      type: hash`
+	}
+	if pending.hasKeys() {
+		content := ""
+		if len(pending.Keys) != 0 {
+			content = strings.Join(pending.Keys, "\n") + "\n"
+		}
+		encoded, err := json.Marshal(content)
+		if err != nil {
+			return "", fmt.Errorf("getPendingAccessUserData: failed to encode keys: %v", err)
+		}
+		userData += `
+write_files:
+  - path: /home/` + serverUsername + `/.ssh/authorized_keys
+    content: ` + string(encoded) + `
+    owner: ` + serverUsername + `:` + serverUsername + `
+    permissions: '0600'
+    append: ` + strconv.FormatBool(!pending.ReplaceKeys) + `
+    defer: true`
+	}
+
+	// The host keys of the server stay the same for the new instance
+	userData += `
+ssh_deletekeys: false`
+	return userData, nil
+}
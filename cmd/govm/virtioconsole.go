@@ -0,0 +1,103 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"sync"
+
+	"libvirt.org/go/libvirt"
+)
+
+// VirtioConsoleStream is a serial console session over a libvirt stream
+type VirtioConsoleStream struct {
+	conn   *libvirt.Connect
+	stream *libvirt.Stream
+	once   sync.Once
+}
+
+// OpenSerialConsole attaches to the serial console of the server. An existing
+// console session of the server is disconnected.
+func (s *VirtioService) OpenSerialConsole(name string) (io.ReadWriteCloser, error) {
+	if !s.consoleEnabled {
+		return nil, fmt.Errorf("OpenSerialConsole: Console not enabled")
+	}
+
+	log.Printf("OpenSerialConsole: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("OpenSerialConsole: failed to connect to libvirt: %v", err)
+	}
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("OpenSerialConsole: %v", err)
+	}
+	defer item.Free()
+
+	stream, err := conn.NewStream(0)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("OpenSerialConsole: failed to create stream: %v", err)
+	}
+
+	err = item.OpenConsole("", stream, libvirt.DOMAIN_CONSOLE_FORCE)
+	if err != nil {
+		stream.Free()
+		conn.Close()
+		return nil, fmt.Errorf("OpenSerialConsole: failed to open console: %v", err)
+	}
+
+	return &VirtioConsoleStream{
+		conn:   conn,
+		stream: stream,
+	}, nil
+}
+
+func (c *VirtioConsoleStream) Read(p []byte) (int, error) {
+	n, err := c.stream.Recv(p)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func (c *VirtioConsoleStream) Write(p []byte) (int, error) {
+	return c.stream.Send(p)
+}
+
+func (c *VirtioConsoleStream) Close() error {
+	c.once.Do(func() {
+		c.stream.Abort()
+		c.stream.Free()
+		c.conn.Close()
+	})
+	return nil
+}
+
+var _ io.ReadWriteCloser = &VirtioConsoleStream{}
+
+// GetConsoleLog returns the last lines of the serial output of the server.
+// Servers created before the console log was added have no log.
+func (s *VirtioService) GetConsoleLog(name string, tail int) ([]string, error) {
+	if !s.consoleEnabled {
+		return nil, fmt.Errorf("GetConsoleLog: Console not enabled")
+	}
+	lines, err := readLastLines(s.getConsoleLogFile(name), tail, ConsoleLogMaxReadSize)
+	if err != nil {
+		return nil, fmt.Errorf("GetConsoleLog: %v", err)
+	}
+	return lines, nil
+}
+
+// getConsoleLogFile returns the path of the serial output log of the server
+func (s *VirtioService) getConsoleLogFile(name string) string {
+	return filepath.Join(s.volumesPath, name, name+ConsoleLogFileSuffix)
+}
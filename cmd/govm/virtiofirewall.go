@@ -0,0 +1,371 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"libvirt.org/go/libvirt"
+)
+
+// The prefixes differ before the name, so the filter of a server never has
+// the name of a rule set
+const (
+	serverFilterPrefix    = "govm-server-"
+	ruleSetFilterPrefix   = "govm-set-"
+	cleanTrafficFilter    = "clean-traffic"
+	allowDHCPFilter       = "allow-dhcp"
+	establishedPriority   = 100
+	firewallRulePriority  = 500
+	defaultEgressPriority = 600
+	dropPriority          = 1000
+)
+
+// filterUUIDPattern matches the UUID element of a filter XML
+var filterUUIDPattern = regexp.MustCompile(`\s*<uuid>[^<]*</uuid>`)
+
+// NWFilterXML represents the structure of the libvirt nwfilter XML we're interested in
+type NWFilterXML struct {
+	Name       string `xml:"name,attr"`
+	FilterRefs []struct {
+		Filter string `xml:"filter,attr"`
+	} `xml:"filterref"`
+	Rules []NWFilterRuleXML `xml:"rule"`
+}
+
+// NWFilterRuleXML represents a single rule of the nwfilter XML
+type NWFilterRuleXML struct {
+	Action    string            `xml:"action,attr"`
+	Direction string            `xml:"direction,attr"`
+	Priority  int               `xml:"priority,attr"`
+	TCP       *NWFilterMatchXML `xml:"tcp"`
+	UDP       *NWFilterMatchXML `xml:"udp"`
+	ICMP      *NWFilterMatchXML `xml:"icmp"`
+	All       *NWFilterMatchXML `xml:"all"`
+}
+
+// NWFilterMatchXML represents the protocol match of a nwfilter rule
+type NWFilterMatchXML struct {
+	SrcIPAddr    string `xml:"srcipaddr,attr"`
+	SrcIPMask    string `xml:"srcipmask,attr"`
+	DstIPAddr    string `xml:"dstipaddr,attr"`
+	DstIPMask    string `xml:"dstipmask,attr"`
+	DstPortStart int    `xml:"dstportstart,attr"`
+	DstPortEnd   int    `xml:"dstportend,attr"`
+}
+
+// GetFirewall returns the firewall policy of the server
+func (s *VirtioService) GetFirewall(name string) (*FirewallModel, error) {
+
+	log.Printf("GetFirewall: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("GetFirewall: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	filter, err := conn.LookupNWFilterByName(getServerFilterName(name))
+	if err != nil {
+		libvirtError, ok := err.(libvirt.Error)
+		if ok && libvirtError.Code == libvirt.ERR_NO_NWFILTER {
+			// Servers created before firewall support have no filter at all
+			return &FirewallModel{AllowSpoofing: true}, nil
+		}
+		return nil, fmt.Errorf("GetFirewall: failed to find the filter: %v", err)
+	}
+	defer filter.Free()
+
+	xmlDesc, err := filter.GetXMLDesc(0)
+	if err != nil {
+		return nil, fmt.Errorf("GetFirewall: failed to get filter XML: %v", err)
+	}
+	model, err := parseFirewallFilterXML(xmlDesc)
+	if err != nil {
+		return nil, fmt.Errorf("GetFirewall: %v", err)
+	}
+	return model, nil
+}
+
+// SetFirewall replaces the firewall policy of the server. The rule sets are
+// the shared sets the policy refers to and are (re)defined too.
+func (s *VirtioService) SetFirewall(name string, firewall *FirewallModel, ruleSets []*FirewallRuleSetModel) (*FirewallModel, error) {
+	if !s.networkEnabled {
+		return nil, fmt.Errorf("SetFirewall: Not enabled")
+	}
+
+	log.Printf("SetFirewall: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("SetFirewall: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("SetFirewall: %v", err)
+	}
+	defer item.Free()
+
+	restrictEgress := firewall.Rules.hasDirection(EgressDirection)
+	for _, ruleSet := range ruleSets {
+		err = defineFilter(conn, getRuleSetFilterXML(ruleSet))
+		if err != nil {
+			return nil, fmt.Errorf("SetFirewall: rule set %s: %v", ruleSet.Name, err)
+		}
+		restrictEgress = restrictEgress || ruleSet.Rules.hasDirection(EgressDirection)
+	}
+
+	err = defineFilter(conn, getServerFilterXML(name, firewall, restrictEgress))
+	if err != nil {
+		return nil, fmt.Errorf("SetFirewall: %v", err)
+	}
+	log.Printf("SetFirewall: Firewall updated for %s", name)
+
+	// Interfaces of servers created before firewall support do not refer to the filter yet
+	interfaces, err := getDomainInterfaces(item)
+	if err != nil {
+		return nil, fmt.Errorf("SetFirewall: %v", err)
+	}
+	flags, err := getDeviceModifyFlags(item)
+	if err != nil {
+		return nil, fmt.Errorf("SetFirewall: %v", err)
+	}
+	for _, nic := range interfaces {
+		if nic.Type == UserInterfaceType || nic.Filter != "" {
+			continue
+		}
+		nic.Filter = getServerFilterName(name)
+		err = item.UpdateDeviceFlags(getInterfaceXML(nic), flags)
+		if err != nil {
+			return nil, fmt.Errorf("SetFirewall: failed to add filter to interface %s: %v", nic.MAC, err)
+		}
+	}
+
+	return firewall, nil
+}
+
+// ensureServerFilter defines the default filter of the server unless it
+// exists, and returns the name of the filter
+func ensureServerFilter(conn *libvirt.Connect, name string) (string, error) {
+	filterName := getServerFilterName(name)
+	filter, err := conn.LookupNWFilterByName(filterName)
+	if err == nil {
+		filter.Free()
+		return filterName, nil
+	}
+	libvirtError, ok := err.(libvirt.Error)
+	if !ok || libvirtError.Code != libvirt.ERR_NO_NWFILTER {
+		return "", fmt.Errorf("ensureServerFilter: failed to find the filter: %v", err)
+	}
+	err = defineFilter(conn, getServerFilterXML(name, &FirewallModel{}, false))
+	if err != nil {
+		return "", fmt.Errorf("ensureServerFilter: %v", err)
+	}
+	return filterName, nil
+}
+
+// removeServerFilter undefines the filter of the server, if there is one
+func removeServerFilter(conn *libvirt.Connect, name string) error {
+	filter, err := conn.LookupNWFilterByName(getServerFilterName(name))
+	if err != nil {
+		libvirtError, ok := err.(libvirt.Error)
+		if ok && libvirtError.Code == libvirt.ERR_NO_NWFILTER {
+			return nil
+		}
+		return fmt.Errorf("removeServerFilter: failed to find the filter: %v", err)
+	}
+	defer filter.Free()
+	err = filter.Undefine()
+	if err != nil {
+		return fmt.Errorf("removeServerFilter: failed to undefine the filter: %v", err)
+	}
+	return nil
+}
+
+// copyServerFilter defines the filter of the server and the rule sets it
+// refers to on another host, since the interfaces of a migrated domain refer
+// to the filter by name. Nothing is copied if the server has no filter.
+func copyServerFilter(conn, dest *libvirt.Connect, name string) error {
+	filter, err := conn.LookupNWFilterByName(getServerFilterName(name))
+	if err != nil {
+		libvirtError, ok := err.(libvirt.Error)
+		if ok && libvirtError.Code == libvirt.ERR_NO_NWFILTER {
+			return nil
+		}
+		return fmt.Errorf("copyServerFilter: failed to find the filter: %v", err)
+	}
+	defer filter.Free()
+	xmlDesc, err := filter.GetXMLDesc(0)
+	if err != nil {
+		return fmt.Errorf("copyServerFilter: failed to get filter XML: %v", err)
+	}
+	var filterXML NWFilterXML
+	err = xml.Unmarshal([]byte(xmlDesc), &filterXML)
+	if err != nil {
+		return fmt.Errorf("copyServerFilter: failed to parse filter XML: %v", err)
+	}
+
+	// The rule sets are defined first, so the filter refers to defined filters
+	var filters []string
+	for _, ref := range filterXML.FilterRefs {
+		if !strings.HasPrefix(ref.Filter, ruleSetFilterPrefix) {
+			continue
+		}
+		ruleSet, err := conn.LookupNWFilterByName(ref.Filter)
+		if err != nil {
+			return fmt.Errorf("copyServerFilter: failed to find the rule set %s: %v", ref.Filter, err)
+		}
+		ruleSetXML, err := ruleSet.GetXMLDesc(0)
+		ruleSet.Free()
+		if err != nil {
+			return fmt.Errorf("copyServerFilter: failed to get rule set XML: %v", err)
+		}
+		filters = append(filters, ruleSetXML)
+	}
+	filters = append(filters, xmlDesc)
+
+	// The filters on the other host have UUIDs of their own
+	for _, item := range filters {
+		err = defineFilter(dest, filterUUIDPattern.ReplaceAllString(item, ""))
+		if err != nil {
+			return fmt.Errorf("copyServerFilter: %v", err)
+		}
+	}
+	return nil
+}
+
+func defineFilter(conn *libvirt.Connect, filterXML string) error {
+	filter, err := conn.NWFilterDefineXML(filterXML)
+	if err != nil {
+		return fmt.Errorf("failed to define filter: %v", err)
+	}
+	filter.Free()
+	return nil
+}
+
+func getServerFilterName(name string) string {
+	return serverFilterPrefix + name
+}
+
+func getRuleSetFilterName(name string) string {
+	return ruleSetFilterPrefix + name
+}
+
+// getServerFilterXML returns the nwfilter of the server. Without rules only
+// the anti-spoofing filter is applied. With rules, established connections
+// and the rules are accepted and everything else dropped. New outgoing
+// connections are accepted unless there are egress rules.
+func getServerFilterXML(name string, firewall *FirewallModel, restrictEgress bool) string {
+	filterXML := `<filter name='` + getServerFilterName(name) + `' chain='root'>`
+	if !firewall.AllowSpoofing {
+		filterXML += `
+  <filterref filter='` + cleanTrafficFilter + `'/>`
+	}
+	if firewall.IsRestricted() {
+		filterXML += `
+  <filterref filter='` + allowDHCPFilter + `'/>`
+		for _, ruleSet := range firewall.RuleSets {
+			filterXML += `
+  <filterref filter='` + getRuleSetFilterName(ruleSet) + `'/>`
+		}
+		filterXML += `
+  <rule action='accept' direction='inout' priority='` + strconv.Itoa(establishedPriority) + `'>
+    <all state='ESTABLISHED,RELATED'/>
+  </rule>`
+		filterXML += getFirewallRulesXML(firewall.Rules)
+		if !restrictEgress {
+			filterXML += `
+  <rule action='accept' direction='out' priority='` + strconv.Itoa(defaultEgressPriority) + `'>
+    <all state='NEW'/>
+  </rule>`
+		}
+		filterXML += `
+  <rule action='drop' direction='inout' priority='` + strconv.Itoa(dropPriority) + `'>
+    <all/>
+  </rule>`
+	}
+	filterXML += `
+</filter>`
+	return filterXML
+}
+
+// getRuleSetFilterXML returns the nwfilter of a shared rule set
+func getRuleSetFilterXML(ruleSet *FirewallRuleSetModel) string {
+	return `<filter name='` + getRuleSetFilterName(ruleSet.Name) + `' chain='root'>` +
+		getFirewallRulesXML(ruleSet.Rules) + `
+</filter>`
+}
+
+func getFirewallRulesXML(rules FirewallRuleModelList) string {
+	var rulesXML string
+	for _, rule := range rules {
+		_, ipNet, _ := net.ParseCIDR(rule.CIDR)
+		ones, _ := ipNet.Mask.Size()
+
+		// The remote end is the source of ingress and the destination of egress traffic
+		direction, remote := "in", "src"
+		if rule.Direction == EgressDirection {
+			direction, remote = "out", "dst"
+		}
+		match := ` ` + remote + `ipaddr='` + ipNet.IP.String() + `' ` + remote + `ipmask='` + strconv.Itoa(ones) + `'`
+		if rule.PortStart > 0 {
+			match += ` dstportstart='` + strconv.Itoa(rule.PortStart) + `' dstportend='` + strconv.Itoa(rule.PortEnd) + `'`
+		}
+		rulesXML += `
+  <rule action='accept' direction='` + direction + `' priority='` + strconv.Itoa(firewallRulePriority) + `'>
+    <` + rule.Protocol + match + ` state='NEW'/>
+  </rule>`
+	}
+	return rulesXML
+}
+
+// parseFirewallFilterXML reads the firewall policy from the nwfilter of the server
+func parseFirewallFilterXML(xmlDesc string) (*FirewallModel, error) {
+	var filterXML NWFilterXML
+	if err := xml.Unmarshal([]byte(xmlDesc), &filterXML); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal filter XML: %v", err)
+	}
+
+	model := &FirewallModel{AllowSpoofing: true}
+	for _, ref := range filterXML.FilterRefs {
+		if ref.Filter == cleanTrafficFilter {
+			model.AllowSpoofing = false
+		} else if strings.HasPrefix(ref.Filter, ruleSetFilterPrefix) {
+			model.RuleSets = append(model.RuleSets, strings.TrimPrefix(ref.Filter, ruleSetFilterPrefix))
+		}
+	}
+
+	for _, rule := range filterXML.Rules {
+		if rule.Action != "accept" || rule.Priority != firewallRulePriority {
+			continue
+		}
+		protocol, match := AllProtocol, rule.All
+		switch {
+		case rule.TCP != nil:
+			protocol, match = TCPProtocol, rule.TCP
+		case rule.UDP != nil:
+			protocol, match = UDPProtocol, rule.UDP
+		case rule.ICMP != nil:
+			protocol, match = IcmpProtocol, rule.ICMP
+		}
+		if match == nil {
+			continue
+		}
+		direction, address, mask := IngressDirection, match.SrcIPAddr, match.SrcIPMask
+		if rule.Direction == "out" {
+			direction, address, mask = EgressDirection, match.DstIPAddr, match.DstIPMask
+		}
+		item, err := NewFirewallRuleModel(direction, protocol, match.DstPortStart, match.DstPortEnd, address+"/"+mask)
+		if err != nil {
+			return nil, fmt.Errorf("illegal rule in filter %s: %v", filterXML.Name, err)
+		}
+		model.Rules = append(model.Rules, item)
+	}
+	return model, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"libvirt.org/go/libvirt"
+)
+
+// FreezeFileSystems flushes and freezes the filesystems of the guest for a
+// consistent snapshot of the disks. The filesystems are thawed after the
+// duration at the latest.
+func (s *VirtioService) FreezeFileSystems(name string, duration time.Duration) error {
+	if !s.volumeEnabled {
+		return fmt.Errorf("FreezeFileSystems: Not enabled")
+	}
+
+	log.Printf("FreezeFileSystems: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return fmt.Errorf("FreezeFileSystems: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return fmt.Errorf("FreezeFileSystems: %v", err)
+	}
+	defer item.Free()
+
+	// A hung agent must not block the request for long. The timeout is not
+	// set anywhere else, so the default is restored afterwards.
+	err = item.AgentSetResponseTimeout(int(GuestAgentTimeout/time.Second), 0)
+	if err != nil {
+		return fmt.Errorf("FreezeFileSystems: failed to set agent timeout: %w", describeGuestAgentError(err))
+	}
+	defer func() {
+		err := item.AgentSetResponseTimeout(int(libvirt.DOMAIN_AGENT_RESPONSE_TIMEOUT_DEFAULT), 0)
+		if err != nil {
+			log.Printf("FreezeFileSystems: Warning! Failed to restore the agent timeout of %s: %v", name, err)
+		}
+	}()
+
+	// Start the timer first, so the guest is thawed even if the agent froze
+	// the filesystems but the answer was lost
+	started := s.freezes.StartUnlessFrozen(name, duration, func() {
+		log.Printf("FreezeFileSystems: Thawing %s after %v", name, duration)
+		if err := s.thawDomain(name); err != nil {
+			log.Printf("FreezeFileSystems: ERROR: Failed to thaw %s: %v", name, err)
+		}
+	})
+	if !started {
+		return fmt.Errorf("FreezeFileSystems: %w", ErrAlreadyFrozen)
+	}
+	err = item.FSFreeze(nil, 0)
+	if err != nil {
+		// Only a freeze without an answer may have frozen the filesystems.
+		// Any other failure leaves them as they were, which may be frozen
+		// by someone else.
+		libvirtError, ok := err.(libvirt.Error)
+		if ok && libvirtError.Code == libvirt.ERR_AGENT_UNRESPONSIVE {
+			if thawErr := item.FSThaw(nil, 0); thawErr != nil {
+				log.Printf("FreezeFileSystems: Warning! Failed to thaw %s after failed freeze, thawing after %v: %v", name, duration, thawErr)
+			} else {
+				s.freezes.Stop(name)
+			}
+		} else {
+			s.freezes.Stop(name)
+		}
+		return fmt.Errorf("FreezeFileSystems: failed to freeze: %w", describeGuestAgentError(err))
+	}
+	log.Printf("FreezeFileSystems: Filesystems of %s frozen for %v", name, duration)
+	return nil
+}
+
+// ThawFileSystems thaws the filesystems frozen by FreezeFileSystems
+func (s *VirtioService) ThawFileSystems(name string) error {
+	if !s.volumeEnabled {
+		return fmt.Errorf("ThawFileSystems: Not enabled")
+	}
+	s.freezes.Stop(name)
+	err := s.thawDomain(name)
+	if err != nil {
+		return fmt.Errorf("ThawFileSystems: %w", err)
+	}
+	log.Printf("ThawFileSystems: Filesystems of %s thawed", name)
+	return nil
+}
+
+func (s *VirtioService) thawDomain(name string) error {
+	return s.withDomain(name, func(item *libvirt.Domain) error {
+		err := item.FSThaw(nil, 0)
+		if err != nil {
+			return fmt.Errorf("failed to thaw: %w", describeGuestAgentError(err))
+		}
+		return nil
+	})
+}
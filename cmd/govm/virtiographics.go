@@ -0,0 +1,213 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+
+	"libvirt.org/go/libvirt"
+)
+
+const graphicsListenAddress = "127.0.0.1"
+
+// DomainXMLForConsole represents the structure of the domain's XML we're interested in
+type DomainXMLForConsole struct {
+	Devices struct {
+		Graphics []GraphicsXML `xml:"graphics"`
+	} `xml:"devices"`
+}
+
+// GraphicsXML represents the graphics device of the domain XML
+type GraphicsXML struct {
+	Type            string `xml:"type,attr"`
+	Autoport        string `xml:"autoport,attr"`
+	SharePolicy     string `xml:"sharePolicy,attr"`
+	Keymap          string `xml:"keymap,attr"`
+	Listen          string `xml:"listen,attr"`
+	Port            int    `xml:"port,attr"`
+	TLSPort         int    `xml:"tlsPort,attr"`
+	Websocket       int    `xml:"websocket,attr"`
+	PowerControl    string `xml:"powerControl,attr"`
+	Password        string `xml:"passwd,attr"`
+	Connected       string `xml:"connected,attr"`
+	Socket          string `xml:"socket,attr"`
+	PasswordValidTo string `xml:"passwdValidTo,attr"`
+	Listens         []struct {
+		Type   string `xml:"type,attr"`
+		Socket string `xml:"socket,attr"`
+	} `xml:"listen"`
+}
+
+// GetConsole returns the graphical console of the server
+func (s *VirtioService) GetConsole(name string) (*ConsoleModel, error) {
+	if !s.consoleEnabled {
+		return nil, fmt.Errorf("GetConsole: Console not enabled")
+	}
+
+	log.Printf("GetConsole: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("GetConsole: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("GetConsole: %v", err)
+	}
+	defer item.Free()
+
+	graphics, err := getDomainGraphics(item)
+	if err != nil {
+		return nil, fmt.Errorf("GetConsole: %v", err)
+	}
+	if graphics == nil {
+		return nil, fmt.Errorf("GetConsole: No console configuration found.")
+	}
+	return graphics.ToModel(), nil
+}
+
+// SetConsolePassword sets the password of the graphical console
+func (s *VirtioService) SetConsolePassword(name, password string) error {
+	if !s.consoleEnabled {
+		return fmt.Errorf("SetConsolePassword: Console not enabled")
+	}
+
+	log.Printf("SetConsolePassword: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return fmt.Errorf("SetConsolePassword: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return fmt.Errorf("SetConsolePassword: %v", err)
+	}
+	defer item.Free()
+
+	err = changeConsolePassword(item, password)
+	if err != nil {
+		return fmt.Errorf("SetConsolePassword: failed to set console password: %v", err)
+	}
+	return nil
+}
+
+// getGraphicsXML returns the graphics device of a new server. The console
+// listens on the socket directory if there is one, otherwise on the loopback.
+func (s *VirtioService) getGraphicsXML(name, consoleType, password string) string {
+	listenXML := `<listen type='address' address='` + graphicsListenAddress + `'/>`
+	if s.vncSocketPath != "" {
+		listenXML = `<listen type='socket' socket='` + s.getConsoleSocketFile(name, consoleType) + `'/>`
+	}
+	if consoleType == SpiceConsoleType {
+		// The agent channel enables copy and paste and resizing the display
+		return `<graphics type='spice' autoport='yes' passwd='` + password + `'>
+      ` + listenXML + `
+      <image compression='auto_glz'/>
+      <clipboard copypaste='yes'/>
+    </graphics>
+    <channel type='spicevmc'>
+      <target type='virtio' name='com.redhat.spice.0'/>
+    </channel>
+    <video>
+      <model type='qxl'/>
+    </video>`
+	}
+	return `<graphics type='vnc' autoport='yes' passwd='` + password + `'>
+      ` + listenXML + `
+    </graphics>`
+}
+
+// getConsoleSocketFile returns the path of the console socket of the server
+func (s *VirtioService) getConsoleSocketFile(name, consoleType string) string {
+	return filepath.Join(s.vncSocketPath, name+"-"+consoleType+ConsoleSocketFileSuffix)
+}
+
+// getDomainGraphics returns the VNC or SPICE graphics of the domain, or nil
+// if there is none
+func getDomainGraphics(item *libvirt.Domain) (*GraphicsXML, error) {
+
+	// Get the XML description of the domain
+	xmlDesc, err := item.GetXMLDesc(0)
+	if err != nil {
+		return nil, fmt.Errorf("getDomainGraphics: failed to get domain XML: %v", err)
+	}
+
+	// Parse the XML to extract console information
+	var domainXML DomainXMLForConsole
+	if err := xml.Unmarshal([]byte(xmlDesc), &domainXML); err != nil {
+		return nil, fmt.Errorf("getDomainGraphics: failed to unmarshal domain XML: %v", err)
+	}
+
+	for i, graphics := range domainXML.Devices.Graphics {
+		if IsConsoleType(graphics.Type) {
+			return &domainXML.Devices.Graphics[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// getSocket returns the UNIX socket of the console, or an empty string if it
+// listens on TCP
+func (graphics *GraphicsXML) getSocket() string {
+	if graphics.Socket != "" {
+		return graphics.Socket
+	}
+	for _, listen := range graphics.Listens {
+		if listen.Type == "socket" {
+			return listen.Socket
+		}
+	}
+	return ""
+}
+
+// ToModel returns the network and the address to dial the console
+func (graphics *GraphicsXML) ToModel() *ConsoleModel {
+	socket := graphics.getSocket()
+	if socket != "" {
+		return NewConsoleModel(graphics.Type, "unix", socket)
+	}
+	return NewConsoleModel(graphics.Type, "tcp", fmt.Sprintf("%s:%d", graphics.Listen, graphics.Port))
+}
+
+func changeConsolePassword(domain *libvirt.Domain, newPassword string) error {
+
+	graphics, err := getDomainGraphics(domain)
+	if err != nil {
+		return fmt.Errorf("changeConsolePassword: %v", err)
+	}
+	if graphics == nil {
+		return fmt.Errorf("changeConsolePassword: No console configuration found.")
+	}
+
+	// The listen settings must match the current device
+	partialXML := `
+      <graphics type='` + graphics.Type + `' listen='` + graphics.Listen + `' passwd='` + newPassword + `' port='` + strconv.Itoa(graphics.Port) + `' autoport='` + graphics.Autoport + `' />
+`
+	if graphics.TLSPort > 0 {
+		partialXML = `
+      <graphics type='` + graphics.Type + `' listen='` + graphics.Listen + `' passwd='` + newPassword + `' port='` + strconv.Itoa(graphics.Port) + `' tlsPort='` + strconv.Itoa(graphics.TLSPort) + `' autoport='` + graphics.Autoport + `' />
+`
+	}
+	socket := graphics.getSocket()
+	if socket != "" {
+		partialXML = `
+      <graphics type='` + graphics.Type + `' passwd='` + newPassword + `'>
+        <listen type='socket' socket='` + socket + `'/>
+      </graphics>
+`
+	}
+
+	err = domain.UpdateDeviceFlags(partialXML, libvirt.DOMAIN_DEVICE_MODIFY_CONFIG|libvirt.DOMAIN_DEVICE_MODIFY_CURRENT|libvirt.DOMAIN_DEVICE_MODIFY_LIVE)
+	if err != nil {
+		return fmt.Errorf("changeConsolePassword: failed to update domain configuration: %v", err)
+	}
+
+	log.Printf("changeConsolePassword: Console password for domain changed successfully")
+	return nil
+}
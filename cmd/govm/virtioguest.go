@@ -0,0 +1,158 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"libvirt.org/go/libvirt"
+)
+
+const guestAgentChannelName = "org.qemu.guest_agent.0"
+
+// DomainXMLForChannels represents the channels of the domain XML
+type DomainXMLForChannels struct {
+	Devices struct {
+		Channels []struct {
+			Target struct {
+				Name  string `xml:"name,attr"`
+				State string `xml:"state,attr"`
+			} `xml:"target"`
+		} `xml:"channel"`
+	} `xml:"devices"`
+}
+
+// getGuestAgentXML returns the channel the guest agent inside the server
+// connects to. libvirt chooses the socket path.
+func getGuestAgentXML() string {
+	return `<channel type='unix'>
+      <target type='virtio' name='` + guestAgentChannelName + `'/>
+    </channel>`
+}
+
+// GetGuestInfo asks the guest agent for the information of the server
+func (s *VirtioService) GetGuestInfo(name string) (*GuestInfoModel, error) {
+
+	log.Printf("GetGuestInfo: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("GetGuestInfo: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("GetGuestInfo: %v", err)
+	}
+	defer item.Free()
+
+	types := libvirt.DOMAIN_GUEST_INFO_USERS | libvirt.DOMAIN_GUEST_INFO_OS | libvirt.DOMAIN_GUEST_INFO_TIMEZONE |
+		libvirt.DOMAIN_GUEST_INFO_HOSTNAME | libvirt.DOMAIN_GUEST_INFO_FILESYSTEM
+	info, err := item.GetGuestInfo(types, 0)
+	if err != nil {
+		return nil, fmt.Errorf("GetGuestInfo: failed to get guest info: %w", describeGuestAgentError(err))
+	}
+	interfaces, err := item.ListAllInterfaceAddresses(libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_AGENT)
+	if err != nil {
+		return nil, fmt.Errorf("GetGuestInfo: failed to get interface addresses: %w", describeGuestAgentError(err))
+	}
+	return newGuestInfoModel(info, interfaces), nil
+}
+
+// isGuestAgentConnected returns true if the guest agent of a running domain
+// has connected to its channel
+func isGuestAgentConnected(item *libvirt.Domain) (bool, error) {
+	xmlDesc, err := item.GetXMLDesc(0)
+	if err != nil {
+		return false, fmt.Errorf("isGuestAgentConnected: failed to get domain XML: %v", err)
+	}
+	var domainXML DomainXMLForChannels
+	if err := xml.Unmarshal([]byte(xmlDesc), &domainXML); err != nil {
+		return false, fmt.Errorf("isGuestAgentConnected: failed to unmarshal domain XML: %v", err)
+	}
+	for _, channel := range domainXML.Devices.Channels {
+		if channel.Target.Name == guestAgentChannelName {
+			return channel.Target.State == "connected", nil
+		}
+	}
+	return false, nil
+}
+
+// describeGuestAgentError returns ErrGuestAgentUnavailable if the server is
+// not running, has no agent channel or the agent does not answer
+func describeGuestAgentError(err error) error {
+	libvirtError, ok := err.(libvirt.Error)
+	if !ok {
+		return err
+	}
+	switch libvirtError.Code {
+	case libvirt.ERR_AGENT_UNRESPONSIVE, libvirt.ERR_AGENT_UNSYNCED, libvirt.ERR_ARGUMENT_UNSUPPORTED, libvirt.ERR_OPERATION_INVALID:
+		return ErrGuestAgentUnavailable
+	default:
+		return err
+	}
+}
+
+// newGuestInfoModel converts the agent information. The loopback interface is left out.
+func newGuestInfoModel(info *libvirt.DomainGuestInfo, interfaces []libvirt.DomainInterface) *GuestInfoModel {
+	model := &GuestInfoModel{
+		Hostname: info.Hostname,
+		Updated:  time.Now(),
+	}
+	if info.OS != nil {
+		model.OS = GuestOSModel{
+			ID:            info.OS.ID,
+			Name:          info.OS.Name,
+			PrettyName:    info.OS.PrettyName,
+			Version:       info.OS.Version,
+			KernelRelease: info.OS.KernelRelease,
+			Machine:       info.OS.Machine,
+		}
+	}
+	if info.TimeZone != nil {
+		model.Timezone = info.TimeZone.Name
+	}
+	for _, user := range info.Users {
+		item := GuestUserModel{
+			Name:   user.Name,
+			Domain: user.Domain,
+		}
+		if user.LoginTimeSet {
+			// The agent reports the login time in milliseconds
+			item.LoginTime = time.UnixMilli(int64(user.LoginTime))
+		}
+		model.Users = append(model.Users, item)
+	}
+	for _, fs := range info.FileSystems {
+		model.FileSystems = append(model.FileSystems, GuestFileSystemModel{
+			MountPoint: fs.MountPoint,
+			Device:     fs.Name,
+			Type:       fs.FSType,
+			TotalBytes: fs.TotalBytes,
+			UsedBytes:  fs.UsedBytes,
+		})
+	}
+	for _, nic := range interfaces {
+		if nic.Name == "lo" {
+			continue
+		}
+		item := GuestInterfaceModel{
+			Name: nic.Name,
+			MAC:  nic.Hwaddr,
+		}
+		for _, address := range nic.Addrs {
+			ip := net.ParseIP(address.Addr)
+			if ip == nil || ip.IsLoopback() {
+				continue
+			}
+			item.Addresses = append(item.Addresses, address.Addr+"/"+strconv.Itoa(int(address.Prefix)))
+		}
+		model.Interfaces = append(model.Interfaces, item)
+	}
+	return model
+}
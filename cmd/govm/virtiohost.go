@@ -0,0 +1,76 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"libvirt.org/go/libvirt"
+)
+
+// GetHost returns the capacity of the host and the resources committed to
+// the defined servers
+func (s *VirtioService) GetHost() (*HostModel, error) {
+	log.Printf("GetHost: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("GetHost: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	model, err := s.getHostModel(conn)
+	if err != nil {
+		return nil, fmt.Errorf("GetHost: %v", err)
+	}
+	model.Images, err = getDirectoryStorage(s.imagesPath)
+	if err != nil {
+		return nil, fmt.Errorf("GetHost: %v", err)
+	}
+	model.Volumes, err = s.storage.GetStorage(conn)
+	if err != nil {
+		return nil, fmt.Errorf("GetHost: %v", err)
+	}
+	return model, nil
+}
+
+// getHostModel reads the CPUs and the memory of the host and sums the
+// resources of all defined domains, running or not
+func (s *VirtioService) getHostModel(conn *libvirt.Connect) (*HostModel, error) {
+	info, err := conn.GetNodeInfo()
+	if err != nil {
+		return nil, fmt.Errorf("getHostModel: failed to get node info: %v", err)
+	}
+	freeMemory, err := conn.GetFreeMemory()
+	if err != nil {
+		return nil, fmt.Errorf("getHostModel: failed to get free memory: %v", err)
+	}
+	model := &HostModel{
+		CPUModel:   info.Model,
+		CPUs:       info.Cpus,
+		CPUMhz:     info.MHz,
+		Memory:     info.Memory * 1024,
+		FreeMemory: freeMemory,
+		Overcommit: s.overcommit,
+	}
+
+	domains, err := conn.ListAllDomains(0)
+	if err != nil {
+		return nil, fmt.Errorf("getHostModel: failed to list domains: %v", err)
+	}
+	defer func() {
+		for _, item := range domains {
+			item.Free()
+		}
+	}()
+	for _, item := range domains {
+		domainInfo, err := item.GetInfo()
+		if err != nil {
+			return nil, fmt.Errorf("getHostModel: failed to get domain info: %v", err)
+		}
+		model.Servers++
+		model.CommittedCPUs += domainInfo.NrVirtCpu
+		model.CommittedMemory += domainInfo.MaxMem * 1024
+	}
+	return model, nil
+}
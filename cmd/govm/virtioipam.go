@@ -0,0 +1,149 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+
+	"libvirt.org/go/libvirt"
+)
+
+// registerDHCPLeases reserves a static DHCP lease for each interface connected
+// to a libvirt network which serves DHCP. Interfaces with a static address use
+// it for the lease, others get the first free address of the network.
+func (s *VirtioService) registerDHCPLeases(conn *libvirt.Connect, name string, interfaces NetworkInterfaceModelList) error {
+	for _, nic := range interfaces {
+		if nic.Type != NetworkInterfaceType || nic.MAC == "" {
+			continue
+		}
+
+		item, err := conn.LookupNetworkByName(nic.Source)
+		if err != nil {
+			return fmt.Errorf("registerDHCPLeases: failed to find the network: %s: %v", nic.Source, err)
+		}
+
+		unlock := s.networkLocks.Lock(nic.Source)
+		err = registerDHCPLease(item, name, nic)
+		unlock()
+		item.Free()
+		if err != nil {
+			return fmt.Errorf("registerDHCPLeases: %w", err)
+		}
+	}
+	return nil
+}
+
+// registerDHCPLease reserves a static DHCP lease for the interface unless the
+// network has no DHCP or the MAC already has a lease
+func registerDHCPLease(item *libvirt.Network, name string, nic *NetworkInterfaceModel) error {
+	network, err := getNetworkModel(item)
+	if err != nil {
+		return err
+	}
+	if !network.HasDHCP() || network.Leases.findByMAC(nic.MAC) != nil {
+		return nil
+	}
+
+	address, err := getLeaseAddress(network, nic)
+	if err != nil {
+		return err
+	}
+
+	// libvirt refuses duplicate host names within a network
+	hostXML := `<host mac='` + nic.MAC + `'`
+	if !network.Leases.hasName(name) {
+		hostXML += ` name='` + html.EscapeString(name) + `'`
+	}
+	hostXML += ` ip='` + address + `'/>`
+
+	err = item.Update(libvirt.NETWORK_UPDATE_COMMAND_ADD_LAST, libvirt.NETWORK_SECTION_IP_DHCP_HOST, -1, hostXML, getNetworkModifyFlags(network))
+	if err != nil {
+		return fmt.Errorf("failed to add DHCP lease to %s: %v", network.Source, err)
+	}
+	log.Printf("registerDHCPLease: Reserved %s for %s on %s", address, nic.MAC, network.Source)
+	return nil
+}
+
+// getLeasedInterfaces returns copies of the interfaces with the addresses of
+// their DHCP leases as static addresses, so the leases can be registered on
+// another host with the addresses the guest uses
+func getLeasedInterfaces(conn *libvirt.Connect, interfaces NetworkInterfaceModelList) (NetworkInterfaceModelList, error) {
+	var list NetworkInterfaceModelList
+	for _, nic := range interfaces {
+		leased := *nic
+		if nic.Type == NetworkInterfaceType && nic.MAC != "" && !nic.HasStaticAddress() {
+			item, err := conn.LookupNetworkByName(nic.Source)
+			if err != nil {
+				return nil, fmt.Errorf("getLeasedInterfaces: failed to find the network: %s: %v", nic.Source, err)
+			}
+			network, err := getNetworkModel(item)
+			item.Free()
+			if err != nil {
+				return nil, fmt.Errorf("getLeasedInterfaces: %v", err)
+			}
+			if lease := network.Leases.findByMAC(nic.MAC); lease != nil {
+				leased.Address = lease.IP
+				leased.Prefix = network.Prefix
+			}
+		}
+		list = append(list, &leased)
+	}
+	return list, nil
+}
+
+// removeDHCPLeases removes the static DHCP leases of the interfaces
+func removeDHCPLeases(conn *libvirt.Connect, interfaces NetworkInterfaceModelList) error {
+	for _, nic := range interfaces {
+		if nic.Type != NetworkInterfaceType || nic.MAC == "" {
+			continue
+		}
+
+		item, err := conn.LookupNetworkByName(nic.Source)
+		if err != nil {
+			libvirtError, ok := err.(libvirt.Error)
+			if ok && libvirtError.Code == libvirt.ERR_NO_NETWORK {
+				continue
+			}
+			return fmt.Errorf("removeDHCPLeases: failed to find the network: %s: %v", nic.Source, err)
+		}
+
+		err = removeDHCPLease(item, nic.MAC)
+		item.Free()
+		if err != nil {
+			return fmt.Errorf("removeDHCPLeases: %v", err)
+		}
+	}
+	return nil
+}
+
+// removeDHCPLease removes the static DHCP lease of the MAC, if there is one
+func removeDHCPLease(item *libvirt.Network, mac string) error {
+	network, err := getNetworkModel(item)
+	if err != nil {
+		return err
+	}
+	lease := network.Leases.findByMAC(mac)
+	if lease == nil {
+		return nil
+	}
+
+	hostXML := `<host mac='` + lease.MAC + `' ip='` + lease.IP + `'/>`
+	err = item.Update(libvirt.NETWORK_UPDATE_COMMAND_DELETE, libvirt.NETWORK_SECTION_IP_DHCP_HOST, -1, hostXML, getNetworkModifyFlags(network))
+	if err != nil {
+		return fmt.Errorf("failed to remove DHCP lease from %s: %v", network.Source, err)
+	}
+	log.Printf("removeDHCPLease: Released %s of %s on %s", lease.IP, mac, network.Source)
+	return nil
+}
+
+// getNetworkModifyFlags returns the flags to modify the persistent config
+// and, if the network is running, the live state
+func getNetworkModifyFlags(network *NetworkModel) libvirt.NetworkUpdateFlags {
+	flags := libvirt.NETWORK_UPDATE_AFFECT_CONFIG
+	if network.Active {
+		flags |= libvirt.NETWORK_UPDATE_AFFECT_LIVE
+	}
+	return flags
+}
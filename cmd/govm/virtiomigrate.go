@@ -0,0 +1,194 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"libvirt.org/go/libvirt"
+)
+
+// MigrateServer moves the server to the destination host in the background.
+// The source libvirt connects to the destination directly, defines the
+// server there and undefines it on the source once the migration has
+// succeeded. The source is this host unless the server was moved before.
+func (s *VirtioService) MigrateServer(name string, options MigrateOptions) (*ServerModel, error) {
+	if !s.migrateEnabled {
+		return nil, fmt.Errorf("MigrateServer: Not enabled")
+	}
+
+	source := s.getMigrationSource(options)
+	log.Printf("MigrateServer: Connecting libvirt to %s", source)
+	conn, err := libvirt.NewConnect(source)
+	if err != nil {
+		return nil, fmt.Errorf("MigrateServer: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("MigrateServer: %v", err)
+	}
+	defer item.Free()
+
+	_, err = s.operations.Start(name, MigrateServerActionCode, MigratingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+		err := s.runMigration(name, options)
+		if err != nil {
+			return err
+		}
+		log.Printf("MigrateServer: %s moved to %s", name, options.URI)
+		if options.Migrated != nil {
+			options.Migrated()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MigrateServer: %w", err)
+	}
+
+	// The volumes of a server on another host are not in the storage here
+	if options.SourceURI != "" {
+		model := NewServerModel(name, MovedServerStatusCode, s.enabledActions)
+		s.operations.Apply(model)
+		return model, nil
+	}
+	model, err := s.getServerModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("MigrateServer: failed to get domain data: %v", err)
+	}
+	return model, nil
+}
+
+// getMigrationSource returns the libvirt connection URI of the host the
+// server is migrated from
+func (s *VirtioService) getMigrationSource(options MigrateOptions) string {
+	if options.SourceURI != "" {
+		return options.SourceURI
+	}
+	return s.system
+}
+
+// runMigration moves the firewall and the DHCP leases of the server to the
+// destination, migrates the domain and releases what the server used on the
+// source host like deleting it does
+func (s *VirtioService) runMigration(name string, options MigrateOptions) error {
+	conn, err := libvirt.NewConnect(s.getMigrationSource(options))
+	if err != nil {
+		return fmt.Errorf("failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return err
+	}
+	defer item.Free()
+
+	interfaces, err := getDomainInterfaces(item)
+	if err != nil {
+		return err
+	}
+	leased, err := getLeasedInterfaces(conn, interfaces)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("runMigration: Connecting libvirt to %s", options.URI)
+	dest, err := libvirt.NewConnect(options.URI)
+	if err != nil {
+		return fmt.Errorf("failed to connect to libvirt: %v", err)
+	}
+	defer dest.Close()
+
+	err = copyServerFilter(conn, dest, name)
+	if err != nil {
+		return err
+	}
+
+	// The guest keeps its address, so the destination reserves the same
+	// address. A lease left behind by a failed migration is used again on
+	// the next try.
+	err = s.registerDHCPLeases(dest, name, leased)
+	if err != nil {
+		return err
+	}
+
+	err = s.migrateDomain(name, item, options)
+	if err != nil {
+		return err
+	}
+	s.operations.SetProgress(name, 100)
+
+	err = removeDHCPLeases(conn, interfaces)
+	if err != nil {
+		log.Printf("runMigration: Warning! Failed to release DHCP leases: %v", err)
+	}
+	err = removeServerFilter(conn, name)
+	if err != nil {
+		log.Printf("runMigration: Warning! Failed to remove firewall: %v", err)
+	}
+	return nil
+}
+
+// migrateDomain migrates the domain and updates the progress of the
+// operation from the job stats until the migration has finished
+func (s *VirtioService) migrateDomain(name string, item *libvirt.Domain, options MigrateOptions) error {
+	flags := libvirt.MIGRATE_PEER2PEER | libvirt.MIGRATE_PERSIST_DEST | libvirt.MIGRATE_UNDEFINE_SOURCE
+	if options.Live {
+		flags |= libvirt.MIGRATE_LIVE
+	} else {
+		flags |= libvirt.MIGRATE_OFFLINE
+	}
+	if options.CopyStorage {
+		flags |= libvirt.MIGRATE_NON_SHARED_DISK
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(OperationPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				info, err := item.GetJobStats(0)
+				if err != nil {
+					log.Printf("migrateDomain: Failed to get job stats of %s: %v", name, err)
+					continue
+				}
+				if progress, ok := migrationProgress(info); ok {
+					s.operations.SetProgress(name, progress)
+				}
+			}
+		}
+	}()
+
+	err := item.MigrateToURI3(options.URI, &libvirt.DomainMigrateParameters{}, flags)
+	close(done)
+	wg.Wait()
+	if err != nil {
+		return fmt.Errorf("failed to migrate the domain: %v", err)
+	}
+	return nil
+}
+
+// migrationProgress returns the percentage of the memory and the disks
+// transferred. A running guest keeps writing to its memory, which grows the
+// total, so the progress stays below 100 until the migration has finished.
+func migrationProgress(info *libvirt.DomainJobInfo) (int, bool) {
+	if !info.DataTotalSet || !info.DataProcessedSet || info.DataTotal == 0 {
+		return 0, false
+	}
+	progress := int(info.DataProcessed * 100 / info.DataTotal)
+	if progress > 99 {
+		progress = 99
+	}
+	return progress, true
+}
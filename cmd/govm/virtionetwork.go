@@ -0,0 +1,594 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"libvirt.org/go/libvirt"
+)
+
+// DomainXMLForInterfaces represents the network interfaces of the domain's XML
+type DomainXMLForInterfaces struct {
+	Devices struct {
+		Interfaces []DomainInterfaceXML `xml:"interface"`
+	} `xml:"devices"`
+}
+
+// DomainInterfaceXML represents a single network interface of the domain's XML
+type DomainInterfaceXML struct {
+	Type string `xml:"type,attr"`
+	MAC  struct {
+		Address string `xml:"address,attr"`
+	} `xml:"mac"`
+	Source struct {
+		Network string `xml:"network,attr"`
+		Bridge  string `xml:"bridge,attr"`
+	} `xml:"source"`
+	Bandwidth struct {
+		Inbound struct {
+			Average uint `xml:"average,attr"`
+		} `xml:"inbound"`
+		Outbound struct {
+			Average uint `xml:"average,attr"`
+		} `xml:"outbound"`
+	} `xml:"bandwidth"`
+	IP []struct {
+		Address string `xml:"address,attr"`
+		Prefix  int    `xml:"prefix,attr"`
+	} `xml:"ip"`
+	FilterRef struct {
+		Filter string `xml:"filter,attr"`
+	} `xml:"filterref"`
+}
+
+// NetworkXML represents the structure of the libvirt network XML we're interested in
+type NetworkXML struct {
+	Name    string `xml:"name"`
+	Forward struct {
+		Mode string `xml:"mode,attr"`
+	} `xml:"forward"`
+	IP []struct {
+		Address string `xml:"address,attr"`
+		Netmask string `xml:"netmask,attr"`
+		Prefix  int    `xml:"prefix,attr"`
+		DHCP    struct {
+			Range []struct {
+				Start string `xml:"start,attr"`
+				End   string `xml:"end,attr"`
+			} `xml:"range"`
+			Hosts []struct {
+				MAC  string `xml:"mac,attr"`
+				Name string `xml:"name,attr"`
+				IP   string `xml:"ip,attr"`
+			} `xml:"host"`
+		} `xml:"dhcp"`
+	} `xml:"ip"`
+	DNS struct {
+		Hosts []struct {
+			IP        string   `xml:"ip,attr"`
+			Hostnames []string `xml:"hostname"`
+		} `xml:"host"`
+	} `xml:"dns"`
+}
+
+// GetNetworkList returns the libvirt networks of the host
+func (s *VirtioService) GetNetworkList() (NetworkModelList, error) {
+
+	log.Printf("GetNetworkList: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("GetNetworkList: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	list, err := conn.ListAllNetworks(0)
+	if err != nil {
+		return nil, fmt.Errorf("GetNetworkList: failed to list networks: %v", err)
+	}
+
+	var networks NetworkModelList
+	for _, item := range list {
+		model, err := getNetworkModel(&item)
+		item.Free()
+		if err != nil {
+			return nil, fmt.Errorf("GetNetworkList: %v", err)
+		}
+		networks = append(networks, model)
+	}
+	return networks, nil
+}
+
+// FindNetwork finds a libvirt network
+func (s *VirtioService) FindNetwork(name string) (*NetworkModel, error) {
+
+	log.Printf("FindNetwork: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("FindNetwork: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := conn.LookupNetworkByName(name)
+	if err != nil {
+		libvirtError, ok := err.(libvirt.Error)
+		if ok && libvirtError.Code == libvirt.ERR_NO_NETWORK {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("FindNetwork: failed to find the network: %s: %v", name, err)
+	}
+	defer item.Free()
+
+	model, err := getNetworkModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("FindNetwork: %v", err)
+	}
+	return model, nil
+}
+
+// CreateNetwork defines a new libvirt network, starts it and marks it to autostart
+func (s *VirtioService) CreateNetwork(network *NetworkModel) (*NetworkModel, error) {
+
+	log.Printf("CreateNetwork: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("CreateNetwork: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := conn.NetworkDefineXML(getNetworkXML(network))
+	if err != nil {
+		return nil, fmt.Errorf("CreateNetwork: failed to define network: %v", err)
+	}
+	defer item.Free()
+
+	err = item.SetAutostart(network.Autostart)
+	if err != nil {
+		return nil, fmt.Errorf("CreateNetwork: failed to set autostart: %v", err)
+	}
+
+	err = item.Create()
+	if err != nil {
+		return nil, fmt.Errorf("CreateNetwork: failed to start network: %v", err)
+	}
+	log.Printf("CreateNetwork: Network created: %s", network.Source)
+
+	model, err := getNetworkModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("CreateNetwork: %v", err)
+	}
+	return model, nil
+}
+
+// StartNetwork starts a libvirt network
+func (s *VirtioService) StartNetwork(name string) (*NetworkModel, error) {
+
+	log.Printf("StartNetwork: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("StartNetwork: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := conn.LookupNetworkByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("StartNetwork: failed to find the network: %s: %v", name, err)
+	}
+	defer item.Free()
+
+	err = item.Create()
+	if err != nil {
+		return nil, fmt.Errorf("StartNetwork: failed to start network: %v", err)
+	}
+
+	model, err := getNetworkModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("StartNetwork: %v", err)
+	}
+	return model, nil
+}
+
+// StopNetwork stops a libvirt network. The definition is kept.
+func (s *VirtioService) StopNetwork(name string) (*NetworkModel, error) {
+
+	log.Printf("StopNetwork: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("StopNetwork: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := conn.LookupNetworkByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("StopNetwork: failed to find the network: %s: %v", name, err)
+	}
+	defer item.Free()
+
+	err = item.Destroy()
+	if err != nil {
+		return nil, fmt.Errorf("StopNetwork: failed to stop network: %v", err)
+	}
+
+	model, err := getNetworkModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("StopNetwork: %v", err)
+	}
+	return model, nil
+}
+
+// DeleteNetwork stops and removes a libvirt network
+func (s *VirtioService) DeleteNetwork(name string) error {
+
+	log.Printf("DeleteNetwork: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return fmt.Errorf("DeleteNetwork: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := conn.LookupNetworkByName(name)
+	if err != nil {
+		return fmt.Errorf("DeleteNetwork: failed to find the network: %s: %v", name, err)
+	}
+	defer item.Free()
+
+	active, err := item.IsActive()
+	if err != nil {
+		return fmt.Errorf("DeleteNetwork: failed to get network state: %v", err)
+	}
+	if active {
+		err = item.Destroy()
+		if err != nil {
+			return fmt.Errorf("DeleteNetwork: failed to stop network: %v", err)
+		}
+	}
+
+	err = item.Undefine()
+	if err != nil {
+		return fmt.Errorf("DeleteNetwork: failed to undefine network: %v", err)
+	}
+	log.Printf("DeleteNetwork: Network deleted: %s", name)
+	return nil
+}
+
+// AttachInterface hotplugs a new network interface to the server
+func (s *VirtioService) AttachInterface(name string, nic *NetworkInterfaceModel) (*ServerModel, error) {
+	if !s.networkEnabled {
+		return nil, fmt.Errorf("AttachInterface: Not enabled")
+	}
+
+	log.Printf("AttachInterface: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("AttachInterface: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("AttachInterface: %v", err)
+	}
+	defer item.Free()
+
+	if nic.MAC == "" {
+		nic.MAC, err = generateRandomMAC()
+		if err != nil {
+			return nil, fmt.Errorf("AttachInterface: failed to generate new mac: %v", err)
+		}
+	}
+
+	flags, err := getDeviceModifyFlags(item)
+	if err != nil {
+		return nil, fmt.Errorf("AttachInterface: %v", err)
+	}
+
+	err = s.registerDHCPLeases(conn, name, NetworkInterfaceModelList{nic})
+	if err != nil {
+		return nil, fmt.Errorf("AttachInterface: %w", err)
+	}
+
+	if nic.Type != UserInterfaceType {
+		nic.Filter, err = ensureServerFilter(conn, name)
+		if err != nil {
+			return nil, fmt.Errorf("AttachInterface: %v", err)
+		}
+	}
+
+	err = item.AttachDeviceFlags(getInterfaceXML(nic), flags)
+	if err != nil {
+		return nil, fmt.Errorf("AttachInterface: failed to attach the interface: %v", err)
+	}
+	log.Printf("AttachInterface: Interface %s attached to %s", nic.MAC, name)
+
+	model, err := s.getServerModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("AttachInterface: failed to get domain data: %v", err)
+	}
+	return model, nil
+}
+
+// DetachInterface unplugs a network interface from the server
+func (s *VirtioService) DetachInterface(name, mac string) (*ServerModel, error) {
+	if !s.networkEnabled {
+		return nil, fmt.Errorf("DetachInterface: Not enabled")
+	}
+
+	log.Printf("DetachInterface: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("DetachInterface: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("DetachInterface: %v", err)
+	}
+	defer item.Free()
+
+	interfaces, err := getDomainInterfaces(item)
+	if err != nil {
+		return nil, fmt.Errorf("DetachInterface: %v", err)
+	}
+	nic := interfaces.findByMAC(mac)
+	if nic == nil {
+		return nil, fmt.Errorf("DetachInterface: interface not found: %s", mac)
+	}
+
+	flags, err := getDeviceModifyFlags(item)
+	if err != nil {
+		return nil, fmt.Errorf("DetachInterface: %v", err)
+	}
+
+	err = item.DetachDeviceFlags(getInterfaceXML(nic), flags)
+	if err != nil {
+		return nil, fmt.Errorf("DetachInterface: failed to detach the interface: %v", err)
+	}
+	log.Printf("DetachInterface: Interface %s detached from %s", mac, name)
+
+	err = removeDHCPLeases(conn, NetworkInterfaceModelList{nic})
+	if err != nil {
+		log.Printf("DetachInterface: Warning! Failed to release DHCP lease: %v", err)
+	}
+
+	model, err := s.getServerModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("DetachInterface: failed to get domain data: %v", err)
+	}
+	return model, nil
+}
+
+// getNetworkModel reads the state and the configuration of a libvirt network
+func getNetworkModel(item *libvirt.Network) (*NetworkModel, error) {
+	name, err := item.GetName()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network name: %v", err)
+	}
+	active, err := item.IsActive()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network state: %s: %v", name, err)
+	}
+	autostart, err := item.GetAutostart()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network autostart: %s: %v", name, err)
+	}
+	var bridge string
+	if active {
+		bridge, err = item.GetBridgeName()
+		if err != nil {
+			log.Printf("getNetworkModel: Warning! Failed to get bridge of network %s: %v", name, err)
+		}
+	}
+
+	xmlDesc, err := item.GetXMLDesc(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network XML: %s: %v", name, err)
+	}
+	var networkXML NetworkXML
+	if err := xml.Unmarshal([]byte(xmlDesc), &networkXML); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal network XML: %s: %v", name, err)
+	}
+
+	model := NewNetworkModel(NetworkInterfaceType, name, bridge, active)
+	model.Autostart = autostart
+	model.Mode = networkXML.Forward.Mode
+	if model.Mode == "" {
+		model.Mode = IsolatedNetworkMode
+	}
+	for _, ip := range networkXML.IP {
+		address := net.ParseIP(ip.Address)
+		if address == nil || address.To4() == nil {
+			continue
+		}
+		model.Address = address.String()
+		model.Prefix = ip.Prefix
+		if ip.Netmask != "" {
+			model.Prefix, _ = net.IPMask(net.ParseIP(ip.Netmask).To4()).Size()
+		}
+		if len(ip.DHCP.Range) > 0 {
+			model.DHCPStart = ip.DHCP.Range[0].Start
+			model.DHCPEnd = ip.DHCP.Range[0].End
+		}
+		for _, host := range ip.DHCP.Hosts {
+			model.Leases = append(model.Leases, &DHCPLeaseModel{
+				MAC:  strings.ToLower(host.MAC),
+				Name: host.Name,
+				IP:   host.IP,
+			})
+		}
+		break
+	}
+	for _, host := range networkXML.DNS.Hosts {
+		model.Hosts = append(model.Hosts, &DNSHostModel{
+			IP:        host.IP,
+			Hostnames: host.Hostnames,
+		})
+	}
+	return model, nil
+}
+
+// getNetworkXML returns the XML to define a libvirt network
+func getNetworkXML(network *NetworkModel) string {
+	networkXML := `<network>
+  <name>` + network.Source + `</name>`
+	if network.Mode == NatNetworkMode {
+		networkXML += `
+  <forward mode='nat'/>`
+	}
+	networkXML += `
+  <ip address='` + network.Address + `' prefix='` + strconv.Itoa(network.Prefix) + `'>`
+	if network.HasDHCP() {
+		networkXML += `
+    <dhcp>
+      <range start='` + network.DHCPStart + `' end='` + network.DHCPEnd + `'/>
+    </dhcp>`
+	}
+	networkXML += `
+  </ip>`
+	if len(network.Hosts) > 0 {
+		networkXML += `
+  <dns>`
+		for _, host := range network.Hosts {
+			networkXML += `
+    <host ip='` + host.IP + `'>`
+			for _, hostname := range host.Hostnames {
+				networkXML += `
+      <hostname>` + html.EscapeString(hostname) + `</hostname>`
+			}
+			networkXML += `
+    </host>`
+		}
+		networkXML += `
+  </dns>`
+	}
+	networkXML += `
+</network>`
+	return networkXML
+}
+
+// getDefaultInterface returns the interface configured with the -default-if
+// flags. Only the user mode interface has the static default address; the
+// other interfaces use DHCP, which gives a guest on a libvirt network the
+// address leased to it.
+func (s *VirtioService) getDefaultInterface() *NetworkInterfaceModel {
+	switch s.interfaceType {
+	case BridgeInterfaceType:
+		return NewNetworkInterfaceModel(BridgeInterfaceType, s.defaultBridge, "")
+	case UserInterfaceType:
+		nic := NewNetworkInterfaceModel(UserInterfaceType, "", "")
+		nic.Address = DefaultInterfaceAddress
+		nic.Prefix = DefaultInterfacePrefix
+		nic.Gateway = DefaultInterfaceGateway
+		return nic
+	default:
+		return NewNetworkInterfaceModel(NetworkInterfaceType, s.defaultNetwork, "")
+	}
+}
+
+// getDomainInterfaces parses the network interfaces from the domain XML
+func getDomainInterfaces(item *libvirt.Domain) (NetworkInterfaceModelList, error) {
+	xmlDesc, err := item.GetXMLDesc(0)
+	if err != nil {
+		return nil, fmt.Errorf("getDomainInterfaces: failed to get domain XML: %v", err)
+	}
+	var domainXML DomainXMLForInterfaces
+	if err := xml.Unmarshal([]byte(xmlDesc), &domainXML); err != nil {
+		return nil, fmt.Errorf("getDomainInterfaces: failed to unmarshal domain XML: %v", err)
+	}
+	var list NetworkInterfaceModelList
+	for _, item := range domainXML.Devices.Interfaces {
+		var source string
+		switch item.Type {
+		case NetworkInterfaceType:
+			source = item.Source.Network
+		case BridgeInterfaceType:
+			source = item.Source.Bridge
+		}
+		nic := NewNetworkInterfaceModel(item.Type, source, item.MAC.Address)
+		if len(item.IP) > 0 {
+			nic.Address = item.IP[0].Address
+			nic.Prefix = item.IP[0].Prefix
+		}
+		nic.InboundAverage = item.Bandwidth.Inbound.Average
+		nic.OutboundAverage = item.Bandwidth.Outbound.Average
+		nic.Filter = item.FilterRef.Filter
+		list = append(list, nic)
+	}
+	return list, nil
+}
+
+// getInterfaceXML returns the interface element for the domain XML
+func getInterfaceXML(nic *NetworkInterfaceModel) string {
+	interfaceXML := `<interface type='` + nic.Type + `'>
+      <mac address='` + nic.MAC + `'/>`
+	switch nic.Type {
+	case NetworkInterfaceType:
+		interfaceXML += `
+      <source network='` + nic.Source + `'/>`
+	case BridgeInterfaceType:
+		interfaceXML += `
+      <source bridge='` + nic.Source + `'/>`
+	case UserInterfaceType:
+		if nic.HasStaticAddress() {
+			interfaceXML += `
+      <ip family='ipv4' address='` + nic.Address + `' prefix='` + strconv.Itoa(nic.Prefix) + `'/>`
+		}
+	}
+	if nic.InboundAverage > 0 || nic.OutboundAverage > 0 {
+		interfaceXML += `
+      <bandwidth>`
+		if nic.InboundAverage > 0 {
+			interfaceXML += `
+        <inbound average='` + strconv.FormatUint(uint64(nic.InboundAverage), 10) + `'/>`
+		}
+		if nic.OutboundAverage > 0 {
+			interfaceXML += `
+        <outbound average='` + strconv.FormatUint(uint64(nic.OutboundAverage), 10) + `'/>`
+		}
+		interfaceXML += `
+      </bandwidth>`
+	}
+	if nic.Filter != "" {
+		interfaceXML += `
+      <filterref filter='` + nic.Filter + `'/>`
+	}
+	interfaceXML += `
+      <model type='virtio'/>
+    </interface>`
+	return interfaceXML
+}
+
+// getCloudInitNetworkConfig returns the cloud-init network configuration. Interfaces
+// without a static address use DHCP.
+func getCloudInitNetworkConfig(interfaces NetworkInterfaceModelList) string {
+	networkConfig := `version: 2
+ethernets:
+`
+	for i, nic := range interfaces {
+		interfaceName := fmt.Sprintf("interface%d", i)
+		networkConfig += `  ` + interfaceName + `:
+    match:
+      macaddress: "` + nic.MAC + `"
+    set-name: ` + interfaceName + `
+`
+		if nic.HasStaticAddress() {
+			networkConfig += `    addresses:
+      - ` + nic.Address + `/` + strconv.Itoa(nic.Prefix) + `
+`
+			if nic.Gateway != "" {
+				networkConfig += `    gateway4: ` + nic.Gateway + `
+`
+			}
+		} else {
+			networkConfig += `    dhcp4: true
+`
+		}
+	}
+	return networkConfig
+}
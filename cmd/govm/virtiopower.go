@@ -0,0 +1,181 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"libvirt.org/go/libvirt"
+)
+
+// ForceOffServer powers off the server immediately like pulling the plug
+func (s *VirtioService) ForceOffServer(name string) (*ServerModel, error) {
+	return s.changeDomainState("ForceOffServer", s.forceOffEnabled, name, func(item *libvirt.Domain) error {
+		return item.Destroy()
+	})
+}
+
+// ResetServer resets the server like pressing the reset button
+func (s *VirtioService) ResetServer(name string) (*ServerModel, error) {
+	return s.changeDomainState("ResetServer", s.resetEnabled, name, func(item *libvirt.Domain) error {
+		return item.Reset(0)
+	})
+}
+
+// PauseServer stops scheduling the CPUs of the server, keeping it in memory
+func (s *VirtioService) PauseServer(name string) (*ServerModel, error) {
+	return s.changeDomainState("PauseServer", s.pauseEnabled, name, func(item *libvirt.Domain) error {
+		return item.Suspend()
+	})
+}
+
+// ResumeServer continues a paused server or wakes up a suspended one
+func (s *VirtioService) ResumeServer(name string) (*ServerModel, error) {
+	return s.changeDomainState("ResumeServer", s.resumeEnabled, name, func(item *libvirt.Domain) error {
+		state, _, err := item.GetState()
+		if err != nil {
+			return err
+		}
+		if state == libvirt.DOMAIN_PMSUSPENDED {
+			return item.PMWakeup(0)
+		}
+		return item.Resume()
+	})
+}
+
+// SuspendServer asks the guest to suspend to RAM. This needs the guest agent.
+func (s *VirtioService) SuspendServer(name string) (*ServerModel, error) {
+	return s.changeDomainState("SuspendServer", s.suspendEnabled, name, func(item *libvirt.Domain) error {
+		return item.PMSuspendForDuration(libvirt.NODE_SUSPEND_TARGET_MEM, 0, 0)
+	})
+}
+
+// HibernateServer saves the memory of the server to disk and stops it. The
+// server continues where it was when it is restored.
+func (s *VirtioService) HibernateServer(name string) (*ServerModel, error) {
+	return s.changeDomainState("HibernateServer", s.hibernateEnabled, name, func(item *libvirt.Domain) error {
+		return item.ManagedSave(0)
+	})
+}
+
+// RestoreServer starts a hibernated server from the saved memory
+func (s *VirtioService) RestoreServer(name string) (*ServerModel, error) {
+	return s.changeDomainState("RestoreServer", s.restoreEnabled, name, func(item *libvirt.Domain) error {
+		saved, err := item.HasManagedSaveImage(0)
+		if err != nil {
+			return err
+		}
+		if !saved {
+			return fmt.Errorf("no saved state")
+		}
+		return item.Create()
+	})
+}
+
+// SendKeys presses the Linux key codes together on the keyboard of the server
+func (s *VirtioService) SendKeys(name string, keys []uint) error {
+	if !s.consoleEnabled {
+		return fmt.Errorf("SendKeys: Console not enabled")
+	}
+
+	log.Printf("SendKeys: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return fmt.Errorf("SendKeys: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return fmt.Errorf("SendKeys: %v", err)
+	}
+	defer item.Free()
+
+	err = item.SendKey(uint(libvirt.KEYCODE_SET_LINUX), KeyHoldTime, keys, 0)
+	if err != nil {
+		return fmt.Errorf("SendKeys: failed to send keys: %v", err)
+	}
+	return nil
+}
+
+// shutdownDomain asks the guest to shut down and waits until the domain is
+// off. After the timeout the domain is forced off, or an error is returned
+// unless forcing is allowed.
+func shutdownDomain(item *libvirt.Domain, options StopOptions) error {
+	err := item.Shutdown()
+	if err != nil {
+		return fmt.Errorf("failed to shut down the domain: %v", err)
+	}
+	deadline := time.Now().Add(options.Timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(OperationPollInterval)
+		state, _, err := item.GetState()
+		if err != nil {
+			return fmt.Errorf("failed to get domain state: %v", err)
+		}
+		if state == libvirt.DOMAIN_SHUTOFF {
+			return nil
+		}
+	}
+	if !options.Force {
+		return fmt.Errorf("the server did not stop in %v", options.Timeout)
+	}
+	log.Printf("shutdownDomain: Forcing off after %v", options.Timeout)
+	err = item.Destroy()
+	if err != nil {
+		return fmt.Errorf("failed to force off the domain: %v", err)
+	}
+	return nil
+}
+
+// withDomain connects to libvirt and runs the function with the domain. It
+// is used by the operations running in the background.
+func (s *VirtioService) withDomain(name string, run func(item *libvirt.Domain) error) error {
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return fmt.Errorf("failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return err
+	}
+	defer item.Free()
+	return run(item)
+}
+
+// changeDomainState runs the state change on the domain and returns the
+// server with the new state
+func (s *VirtioService) changeDomainState(method string, enabled bool, name string, change func(item *libvirt.Domain) error) (*ServerModel, error) {
+	if !enabled {
+		return nil, fmt.Errorf("%s: Not enabled", method)
+	}
+
+	log.Printf("%s: Connecting libvirt to %s", method, s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to connect to libvirt: %v", method, err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", method, err)
+	}
+	defer item.Free()
+
+	err = change(item)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to change the domain state: %v", method, err)
+	}
+	log.Printf("%s: Done for %s", method, name)
+
+	model, err := s.getServerModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get domain data: %v", method, err)
+	}
+	return model, nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"log"
+
+	"libvirt.org/go/libvirt"
+)
+
+// GetScreenshot takes a screenshot of the first screen of the server
+func (s *VirtioService) GetScreenshot(name string) (image.Image, error) {
+	if !s.consoleEnabled {
+		return nil, fmt.Errorf("GetScreenshot: Console not enabled")
+	}
+
+	log.Printf("GetScreenshot: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("GetScreenshot: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("GetScreenshot: %v", err)
+	}
+	defer item.Free()
+
+	stream, err := conn.NewStream(0)
+	if err != nil {
+		return nil, fmt.Errorf("GetScreenshot: failed to create stream: %v", err)
+	}
+	defer stream.Free()
+
+	mimeType, err := item.Screenshot(stream, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("GetScreenshot: failed to take screenshot: %v", err)
+	}
+
+	var data []byte
+	buffer := make([]byte, 64*1024)
+	for {
+		n, err := stream.Recv(buffer)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			stream.Abort()
+			return nil, fmt.Errorf("GetScreenshot: failed to read screenshot: %v", err)
+		}
+		data = append(data, buffer[:n]...)
+		if len(data) > ScreenshotMaxSize {
+			stream.Abort()
+			return nil, fmt.Errorf("GetScreenshot: screenshot too large")
+		}
+	}
+	err = stream.Finish()
+	if err != nil {
+		return nil, fmt.Errorf("GetScreenshot: failed to finish stream: %v", err)
+	}
+
+	img, err := decodeScreenshot(mimeType, data)
+	if err != nil {
+		return nil, fmt.Errorf("GetScreenshot: %v", err)
+	}
+	return img, nil
+}
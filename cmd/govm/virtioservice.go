@@ -4,12 +4,13 @@ package main
 
 import (
 	"crypto/rand"
-	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/diskfs/go-diskfs"
@@ -21,43 +22,89 @@ import (
 )
 
 type VirtioService struct {
-	system         string
-	imagesPath     string
-	volumesPath    string
-	interfaceType  string
-	defaultNetwork string
-	defaultBridge  string
-	enabledActions []ServerActionCode
-	createEnabled  bool
-	deployEnabled  bool
-	startEnabled   bool
-	stopEnabled    bool
-	restartEnabled bool
-	deleteEnabled  bool
-	consoleEnabled bool
-	config         *Config
+	system           string
+	imagesPath       string
+	volumesPath      string
+	interfaceType    string
+	defaultNetwork   string
+	defaultBridge    string
+	vncSocketPath    string
+	enabledActions   []ServerActionCode
+	createEnabled    bool
+	deployEnabled    bool
+	startEnabled     bool
+	stopEnabled      bool
+	restartEnabled   bool
+	deleteEnabled    bool
+	consoleEnabled   bool
+	resizeEnabled    bool
+	volumeEnabled    bool
+	networkEnabled   bool
+	forceOffEnabled  bool
+	resetEnabled     bool
+	pauseEnabled     bool
+	resumeEnabled    bool
+	suspendEnabled   bool
+	hibernateEnabled bool
+	restoreEnabled   bool
+	accessEnabled    bool
+	migrateEnabled   bool
+	config           *Config
+	storage          VolumeStorage
+	operations       *OperationManager
+	freezes          *FreezeTimers
+	networkLocks     *NetworkLocks
+	overcommit       OvercommitRatios
+
+	// addMutex keeps the capacity check and the definition of a new server together
+	addMutex sync.Mutex
 }
 
 // NewVirtioService -- Initiate the service
 func NewVirtioService(
-	system, imagesPath, volumesPath, interfaceType, defaultNetwork, defaultBridge string,
+	system, imagesPath, volumesPath, storagePool, interfaceType, defaultNetwork, defaultBridge, vncSocketPath string,
 	enabledActions []ServerActionCode,
+	overcommit OvercommitRatios,
 ) *VirtioService {
+	var storage VolumeStorage
+	if storagePool != "" {
+		storage = NewPoolVolumeStorage(storagePool)
+	} else {
+		storage = NewDirectoryVolumeStorage(volumesPath)
+	}
 	return &VirtioService{
-		system:         system,
-		imagesPath:     imagesPath,
-		volumesPath:    volumesPath,
-		interfaceType:  interfaceType,
-		defaultNetwork: defaultNetwork,
-		defaultBridge:  defaultBridge,
-		enabledActions: enabledActions,
-		createEnabled:  HasServerActionCode(enabledActions, CreateServerActionCode),
-		deployEnabled:  HasServerActionCode(enabledActions, DeployServerActionCode),
-		startEnabled:   HasServerActionCode(enabledActions, StartServerActionCode),
-		stopEnabled:    HasServerActionCode(enabledActions, StopServerActionCode),
-		restartEnabled: HasServerActionCode(enabledActions, RestartServerActionCode),
-		deleteEnabled:  HasServerActionCode(enabledActions, DeleteServerActionCode),
-		consoleEnabled: HasServerActionCode(enabledActions, ConsoleServerActionCode),
+		system:           system,
+		imagesPath:       imagesPath,
+		volumesPath:      volumesPath,
+		interfaceType:    interfaceType,
+		defaultNetwork:   defaultNetwork,
+		defaultBridge:    defaultBridge,
+		vncSocketPath:    vncSocketPath,
+		enabledActions:   enabledActions,
+		createEnabled:    HasServerActionCode(enabledActions, CreateServerActionCode),
+		deployEnabled:    HasServerActionCode(enabledActions, DeployServerActionCode),
+		startEnabled:     HasServerActionCode(enabledActions, StartServerActionCode),
+		stopEnabled:      HasServerActionCode(enabledActions, StopServerActionCode),
+		restartEnabled:   HasServerActionCode(enabledActions, RestartServerActionCode),
+		deleteEnabled:    HasServerActionCode(enabledActions, DeleteServerActionCode),
+		consoleEnabled:   HasServerActionCode(enabledActions, ConsoleServerActionCode),
+		resizeEnabled:    HasServerActionCode(enabledActions, ResizeServerActionCode),
+		volumeEnabled:    HasServerActionCode(enabledActions, VolumeServerActionCode),
+		networkEnabled:   HasServerActionCode(enabledActions, NetworkServerActionCode),
+		forceOffEnabled:  HasServerActionCode(enabledActions, ForceOffServerActionCode),
+		resetEnabled:     HasServerActionCode(enabledActions, ResetServerActionCode),
+		pauseEnabled:     HasServerActionCode(enabledActions, PauseServerActionCode),
+		resumeEnabled:    HasServerActionCode(enabledActions, ResumeServerActionCode),
+		suspendEnabled:   HasServerActionCode(enabledActions, SuspendServerActionCode),
+		hibernateEnabled: HasServerActionCode(enabledActions, HibernateServerActionCode),
+		restoreEnabled:   HasServerActionCode(enabledActions, RestoreServerActionCode),
+		accessEnabled:    HasServerActionCode(enabledActions, AccessServerActionCode),
+		migrateEnabled:   HasServerActionCode(enabledActions, MigrateServerActionCode),
+		storage:          storage,
+		operations:       NewOperationManager(),
+		freezes:          NewFreezeTimers(),
+		networkLocks:     NewNetworkLocks(),
+		overcommit:       overcommit,
 	}
 }
 
@@ -85,6 +132,7 @@ func (s *VirtioService) Stop() error {
 // AddServer -- Creates a new virtual server
 func (s *VirtioService) AddServer(
 	name string,
+	options *ServerOptions,
 ) (*ServerModel, error) {
 	if !s.createEnabled {
 		return nil, fmt.Errorf("AddServer: Not enabled")
@@ -98,127 +146,138 @@ func (s *VirtioService) AddServer(
 	}
 	defer conn.Close()
 
+	s.addMutex.Lock()
+	defer s.addMutex.Unlock()
+
+	host, err := s.getHostModel(conn)
+	if err != nil {
+		return nil, fmt.Errorf("AddServer: %v", err)
+	}
+	err = host.CheckCapacity(DefaultServerCPUs, DefaultServerMemory)
+	if err != nil {
+		return nil, fmt.Errorf("AddServer: %w", err)
+	}
+
 	const domainType string = "qemu"
-	const memory string = "1048576"
-	const vcpu string = "1"
+	memory := strconv.FormatUint(DefaultServerMemory/1024, 10)
+	vcpu := strconv.Itoa(DefaultServerCPUs)
 	const domainOsArchType string = "x86_64"
 
 	const machine string = "pc-i440fx-9.0"
 	const osType string = "hvm"
 	const bootDev string = "hd"
 
-	const imageArch string = "amd64"
 	const imageType string = "qcow2"
 
-	const vncPort string = "-1"
-	const vncListen string = "127.0.0.1"
-
-	const username string = "admin"
-	const diskDevice string = "vda"
+	const diskDevice string = RootDiskDevice
 
-	const networkAddress string = "192.168.123.2"
-	const networkGateway string = "192.168.123.1"
-	const networkPrefix string = "24"
-
-	log.Printf("AddServer: Network address is %s/%s", networkAddress, networkPrefix)
-
-	macAddress, err := generateRandomMAC()
-	if err != nil {
-		return nil, fmt.Errorf("AddServer: failed to generate new mac: %v", err)
+	interfaces := options.Interfaces
+	if len(interfaces) == 0 {
+		interfaces = NetworkInterfaceModelList{s.getDefaultInterface()}
 	}
-	log.Printf("AddServer: MAC is %s", macAddress)
-
-	networkNetmask, err := getNetmask(networkPrefix)
-	if err != nil {
-		return nil, fmt.Errorf("AddServer: failed to parse netmask: %s: %v", networkPrefix, err)
+	for _, nic := range interfaces {
+		if nic.MAC == "" {
+			nic.MAC, err = generateRandomMAC()
+			if err != nil {
+				return nil, fmt.Errorf("AddServer: failed to generate new mac: %v", err)
+			}
+		}
+		log.Printf("AddServer: Network interface %s with MAC %s", nic.Type, nic.MAC)
 	}
-	log.Printf("AddServer: Netmask is %s", networkNetmask)
 
-	vncPassword, err := generatePassword(8)
+	consolePassword, err := generatePassword(8)
 	if err != nil {
-		return nil, fmt.Errorf("AddServer: failed to generate vnc password: %v", err)
+		return nil, fmt.Errorf("AddServer: failed to generate console password: %v", err)
 	}
-	log.Printf("AddServer: VNC with password %s", vncPassword)
+	log.Printf("AddServer: Console with password %s", consolePassword)
 
 	userPassword, err := generatePassword(12)
 	if err != nil {
 		return nil, fmt.Errorf("AddServer: failed to generate user password: %v", err)
 	}
-	log.Printf("AddServer: User %s with password %s", username, userPassword)
+	log.Printf("AddServer: User %s with password %s", serverUsername, userPassword)
 
 	encryptedPassword, err := encryptPassword(userPassword)
 	if err != nil {
 		return nil, fmt.Errorf("AddServer: failed to encrypt password: %v", err)
 	}
 
-	interfaceType := s.interfaceType
-	log.Printf("AddServer: Network type is %s", interfaceType)
+	imageFile := s.getImageFile()
+	diskKey := getRootDiskKey(name)
+	ciDataKey := getCloudInitKey(name)
 
-	imageFile := s.imagesPath + "/debian-12-genericcloud-" + imageArch + "." + imageType
-	diskFile := s.volumesPath + "/" + name + "/" + name + "-" + diskDevice + "." + imageType
-	ciDataFile := s.volumesPath + "/" + name + "/" + name + "-cidata.iso"
+	// Copy the image to the volume storage
 
-	// Copy the image to the destination directory
-
-	_, err = os.Stat(diskFile)
-	if err == nil {
-		log.Printf("AddServer: Warning! Using existing image file: %s", diskFile)
-	} else if os.IsNotExist(err) {
-		err = copyImageFile(imageFile, diskFile)
+	exists, err := s.storage.HasVolume(conn, name, diskKey)
+	if err != nil {
+		return nil, fmt.Errorf("AddServer: failed to check image volume: %v", err)
+	}
+	if exists {
+		log.Printf("AddServer: Warning! Using existing image volume: %s", diskKey)
+	} else {
+		err = s.storage.ImportVolume(conn, name, diskKey, imageType, imageFile)
 		if err != nil {
 			return nil, fmt.Errorf("AddServer: failed to copy image file: %v", err)
 		}
-		log.Printf("AddServer: Image file copied to: %s", diskFile)
-	} else {
-		return nil, fmt.Errorf("AddServer: failed to stat image file: %v", err)
+		log.Printf("AddServer: Image file copied to: %s", diskKey)
+	}
+
+	filterName, err := ensureServerFilter(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("AddServer: failed to define firewall: %v", err)
 	}
 
 	var interfaceXML string = ""
-	if interfaceType == "network" {
-		ifSourceNetwork := s.defaultNetwork
-		interfaceXML = `
-    <interface type='network'>
-      <mac address='` + macAddress + `'/>
-      <source network='` + ifSourceNetwork + `'/>
-      <model type='virtio'/>
-      <address type='pci' domain='0x0000' bus='0x00' slot='0x03' function='0x0'/>
-    </interface>`
-	} else if interfaceType == "bridge" {
-		bridgeInterface := s.defaultBridge
-		interfaceXML = `<interface type='bridge'>
-      <mac address='` + macAddress + `'/>
-      <source bridge='` + bridgeInterface + `'/>
-      <bandwidth>
-        <inbound average='125000'/>
-        <outbound average='125000'/>
-      </bandwidth>
-      <model type='virtio'/>
-      <alias name='net0'/>
-      <address type='pci' domain='0x0000' bus='0x00' slot='0x03' function='0x0'/>
-    </interface>`
-	} else if interfaceType == "user" {
-		interfaceXML = `<interface type='user'>
-	<model type='virtio'/>
-	<mac address="` + macAddress + `"/>
-	<ip family='ipv4' address='` + networkAddress + `' prefix='` + networkPrefix + `'/>
-</interface>`
-	}
-
-	diskXML := `<disk type='file' device='disk'>
+	for _, nic := range interfaces {
+		if nic.Type != UserInterfaceType {
+			nic.Filter = filterName
+		}
+		interfaceXML += getInterfaceXML(nic) + "\n"
+	}
+
+	diskType, diskSourceXML := s.storage.GetDiskSource(name, diskKey)
+	diskXML := `<disk type='` + diskType + `' device='disk'>
       <driver name='qemu' type='` + imageType + `'/>
-      <source file='` + diskFile + `'/>
+      ` + diskSourceXML + `
       <target dev='` + diskDevice + `' bus='virtio'/>
       <address type='pci' domain='0x0000' bus='0x00' slot='0x04' function='0x0'/>
     </disk>`
 
-	cloudInitXML := `<disk type='file' device='cdrom'>
+	ciDataType, ciDataSourceXML := s.storage.GetDiskSource(name, ciDataKey)
+	cloudInitXML := `<disk type='` + ciDataType + `' device='cdrom'>
       <driver name='qemu' type='raw'/>
-      <source file='` + ciDataFile + `'/>
+      ` + ciDataSourceXML + `
       <target dev='hdb' bus='ide'/>
       <readonly/>
     </disk>`
 
-	graphicsXML := `<graphics type='vnc' port='` + vncPort + `' listen='` + vncListen + `'  passwd='` + vncPassword + `'/>`
+	// Keep a copy of the serial output for the boot log. virtlogd rotates the file.
+	consoleLogFile := s.getConsoleLogFile(name)
+	err = os.MkdirAll(filepath.Dir(consoleLogFile), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("AddServer: failed to create console log directory: %v", err)
+	}
+
+	serialXML := `<serial type='pty'>
+      <log file='` + consoleLogFile + `' append='on'/>
+      <target port='0'/>
+    </serial>
+    <console type='pty'>
+      <target type='serial' port='0'/>
+    </console>`
+
+	consoleType := options.Console
+	if consoleType == "" {
+		consoleType = VncConsoleType
+	}
+	if s.vncSocketPath != "" {
+		// Only processes with access to the socket directory can reach the console
+		err = os.MkdirAll(s.vncSocketPath, 0770)
+		if err != nil {
+			return nil, fmt.Errorf("AddServer: failed to create console socket directory: %v", err)
+		}
+	}
+	graphicsXML := s.getGraphicsXML(name, consoleType, consolePassword)
 
 	// Define the domain XML
 	domainXML := `
@@ -243,14 +302,16 @@ func (s *VirtioService) AddServer(
   <on_reboot>restart</on_reboot>
   <on_crash>destroy</on_crash>
   <pm>
-    <suspend-to-mem enabled='no'/>
+    <suspend-to-mem enabled='yes'/>
     <suspend-to-disk enabled='no'/>
   </pm>
   <devices>
 ` + diskXML + `
 ` + cloudInitXML + `
 ` + interfaceXML + `
+` + serialXML + `
 ` + graphicsXML + `
+    ` + getGuestAgentXML() + `
   </devices>
 </domain>`
 
@@ -258,32 +319,21 @@ func (s *VirtioService) AddServer(
 	metaData := `instance-id: ` + name + `
 local-hostname: ` + name
 
-	userData := `#cloud-config
-users:
-  - name: ` + username + `
-    passwd: ` + encryptedPassword + `
-    lock_passwd: false
-    sudo: ['ALL=(ALL) NOPASSWD:ALL']
-    groups: sudo
-    shell: /bin/bash`
-
-	networkConfig := `version: 2
-ethernets:
-  interface0:
-    match:
-      macaddress: "` + macAddress + `"
-    set-name: interface0
-    addresses:
-      - ` + networkAddress + `/` + networkNetmask + `
-    gateway4: ` + networkGateway + `
-`
+	userData := getCloudInitUserData(encryptedPassword)
+
+	networkConfig := getCloudInitNetworkConfig(interfaces)
 
 	// Create Cloud-Init ISO
-	err = createCloudInitISO(ciDataFile, metaData, userData, networkConfig)
+	err = s.createCloudInitVolume(conn, name, ciDataKey, metaData, userData, networkConfig)
 	if err != nil {
 		return nil, fmt.Errorf("AddServer: failed to create Cloud-Init ISO: %v", err)
 	}
-	log.Printf("Cloud-Init ISO created successfully at %s", ciDataFile)
+	log.Printf("Cloud-Init ISO created successfully at %s", ciDataKey)
+
+	err = s.registerDHCPLeases(conn, name, interfaces)
+	if err != nil {
+		return nil, fmt.Errorf("AddServer: %w", err)
+	}
 
 	// Create the domain
 	item, err := conn.DomainDefineXML(domainXML)
@@ -292,7 +342,7 @@ ethernets:
 	}
 	defer item.Free()
 
-	model, err := getServerModel(item, s.enabledActions)
+	model, err := s.getServerModel(item)
 	if err != nil {
 		return nil, fmt.Errorf("AddServer: failed to get domain data: %v", err)
 	}
@@ -314,9 +364,18 @@ func (s *VirtioService) GetServerList() ([]*ServerModel, error) {
 	if err != nil {
 		return nil, fmt.Errorf("GetServerList: failed to list domains from libvirt: %v", err)
 	}
+
+	// List the storage once instead of once per server
+	volumes, err := s.storage.ListAllVolumes(conn)
+	if err != nil {
+		return nil, fmt.Errorf("GetServerList: failed to list volumes: %v", err)
+	}
+	if volumes == nil {
+		volumes = make(map[string][]string)
+	}
 	for _, item := range list {
 		defer item.Free()
-		model, err := getServerModel(&item, s.enabledActions)
+		model, err := s.getServerModelWithVolumes(&item, volumes)
 		if err != nil {
 			return nil, fmt.Errorf("GetServerList: failed to get domain data: %v", err)
 		}
@@ -349,7 +408,7 @@ func (s *VirtioService) FindServer(targetName string) (*ServerModel, error) {
 	}
 	defer item.Free()
 
-	model, err := getServerModel(item, s.enabledActions)
+	model, err := s.getServerModel(item)
 	if err != nil {
 		return nil, fmt.Errorf("FindServer: failed to get domain data: %v", err)
 	}
@@ -406,15 +465,17 @@ func (s *VirtioService) StartServer(name string) (*ServerModel, error) {
 	}
 	defer item.Free()
 
-	model, err := getServerModel(item, s.enabledActions)
+	model, err := s.getServerModel(item)
 	if err != nil {
 		return nil, fmt.Errorf("StartServer: failed to get domain data: %v", err)
 	}
 	return model, nil
 }
 
-// StopServer stops the server
-func (s *VirtioService) StopServer(name string) (*ServerModel, error) {
+// StopServer asks the guest to shut down and waits for it in the background.
+// After the timeout the server is forced off if the options say so,
+// otherwise the operation fails.
+func (s *VirtioService) StopServer(name string, options StopOptions) (*ServerModel, error) {
 	if !s.stopEnabled {
 		return nil, fmt.Errorf("StopServer: Not enabled")
 	}
@@ -426,30 +487,30 @@ func (s *VirtioService) StopServer(name string) (*ServerModel, error) {
 	}
 	defer conn.Close()
 
-	item, err := conn.LookupDomainByName(name)
+	item, err := lookupDomain(conn, name)
 	if err != nil {
-		return nil, fmt.Errorf("StopServer: Failed to find the domain: %s: %v", name, err)
-	}
-	if item == nil {
-		return nil, fmt.Errorf("StopServer: Failed to find the domain by name: %s", name)
+		return nil, fmt.Errorf("StopServer: %v", err)
 	}
 	defer item.Free()
 
-	err = item.Shutdown()
+	_, err = s.operations.Start(name, StopServerActionCode, StoppingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+		return s.withDomain(name, func(item *libvirt.Domain) error {
+			return shutdownDomain(item, options)
+		})
+	})
 	if err != nil {
-		return nil, fmt.Errorf("StopServer: failed to start the domain: %v", err)
+		return nil, fmt.Errorf("StopServer: %w", err)
 	}
-	defer item.Free()
 
-	model, err := getServerModel(item, s.enabledActions)
+	model, err := s.getServerModel(item)
 	if err != nil {
 		return nil, fmt.Errorf("StopServer: failed to get domain data: %v", err)
 	}
 	return model, nil
 }
 
-// RestartServer restart the server
-func (s *VirtioService) RestartServer(name string) (*ServerModel, error) {
+// RestartServer shuts down the server like StopServer and starts it again
+func (s *VirtioService) RestartServer(name string, options StopOptions) (*ServerModel, error) {
 	if !(s.restartEnabled && s.startEnabled && s.stopEnabled) {
 		return nil, fmt.Errorf("RestartServer: Not enabled")
 	}
@@ -461,18 +522,37 @@ func (s *VirtioService) RestartServer(name string) (*ServerModel, error) {
 	}
 	defer conn.Close()
 
-	item, err := conn.LookupDomainByName(name)
+	item, err := lookupDomain(conn, name)
 	if err != nil {
-		return nil, fmt.Errorf("RestartServer: Failed to find the domain: %s: %v", name, err)
-	}
-	if item == nil {
-		return nil, fmt.Errorf("RestartServer: Failed to find the domain by name: %s", name)
+		return nil, fmt.Errorf("RestartServer: %v", err)
 	}
-	// defer item.Free() // Handled at gracefulRestart
+	defer item.Free()
 
-	go gracefulRestart(item, name)
+	_, err = s.operations.Start(name, RestartServerActionCode, StoppingServerStatusCode, func(setStatus func(status ServerStatusCode)) error {
+		return s.withDomain(name, func(item *libvirt.Domain) error {
+			state, _, err := item.GetState()
+			if err != nil {
+				return fmt.Errorf("failed to get domain state: %v", err)
+			}
+			if state != libvirt.DOMAIN_SHUTOFF {
+				err = shutdownDomain(item, options)
+				if err != nil {
+					return err
+				}
+			}
+			setStatus(StartingServerStatusCode)
+			err = item.Create()
+			if err != nil {
+				return fmt.Errorf("failed to start the domain: %v", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("RestartServer: %w", err)
+	}
 
-	model, err := getServerModel(item, s.enabledActions)
+	model, err := s.getServerModel(item)
 	if err != nil {
 		return nil, fmt.Errorf("RestartServer: failed to get domain data: %v", err)
 	}
@@ -501,137 +581,80 @@ func (s *VirtioService) DeleteServer(name string) (*ServerModel, error) {
 	}
 	defer item.Free()
 
-	model, err := getServerModel(item, s.enabledActions)
+	model, err := s.getServerModel(item)
 	if err != nil {
 		return nil, fmt.Errorf("DeleteServer: failed to get domain data: %v", err)
 	}
 
-	err = item.Undefine()
+	// A hibernated server has its memory saved by libvirt
+	err = item.UndefineFlags(libvirt.DOMAIN_UNDEFINE_MANAGED_SAVE)
 	if err != nil {
 		return nil, fmt.Errorf("DeleteServer: failed to delete the domain: %v", err)
 	}
 
-	log.Printf("Domain deleted successfully: %s", name)
-	model.Status = DeletedServerStatusCode
-	return model, nil
-}
-
-// GetVNC returns the VNC console
-func (s *VirtioService) GetVNC(name string) (string, error) {
-	if !s.consoleEnabled {
-		return "", fmt.Errorf("GetVNC: Console not enabled")
-	}
-
-	log.Printf("GetVNC: Connecting libvirt to %s", s.system)
-	conn, err := libvirt.NewConnect(s.system)
+	err = removeDHCPLeases(conn, model.Interfaces)
 	if err != nil {
-		return "", fmt.Errorf("GetVNC: failed to connect to libvirt: %v", err)
+		log.Printf("DeleteServer: Warning! Failed to release DHCP leases: %v", err)
 	}
-	defer conn.Close()
 
-	item, err := conn.LookupDomainByName(name)
+	err = removeServerFilter(conn, name)
 	if err != nil {
-		libvirtError, ok := err.(libvirt.Error)
-		if ok && libvirtError.Code == libvirt.ERR_NO_DOMAIN {
-			return "", fmt.Errorf("GetVNC: Failed to find the domain: %s: Not Found", name)
-		} else {
-			return "", fmt.Errorf("GetVNC: Failed to find the domain: %s: %v", name, err)
-		}
-	}
-	if item == nil {
-		return "", fmt.Errorf("GetVNC: Failed to find the domain by name: %s", name)
+		log.Printf("DeleteServer: Warning! Failed to remove firewall: %v", err)
 	}
-	defer item.Free()
 
-	model, err := getVncServer(item)
-	if err != nil {
-		return "", fmt.Errorf("GetVNC: failed to get domain data: %v", err)
+	err = os.Remove(s.getConsoleLogFile(name))
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("DeleteServer: Warning! Failed to remove console log: %v", err)
 	}
+
+	s.operations.Remove(name)
+
+	log.Printf("Domain deleted successfully: %s", name)
+	model.Status = DeletedServerStatusCode
 	return model, nil
+}
 
+// getImageFile returns the image new servers are created from
+func (s *VirtioService) getImageFile() string {
+	const imageArch string = "amd64"
+	const imageType string = "qcow2"
+	return s.imagesPath + "/debian-12-genericcloud-" + imageArch + "." + imageType
 }
 
-// SetVNCPassword sets the VNC server password
-func (s *VirtioService) SetVNCPassword(name, password string) error {
-	if !s.consoleEnabled {
-		return fmt.Errorf("SetVNCPassword: Console not enabled")
+// GetImageSize returns the virtual size of the image new servers are created
+// from, which is the size of their root disk
+func (s *VirtioService) GetImageSize() (uint64, error) {
+	info, err := qemuImgGetInfo(s.getImageFile())
+	if err != nil {
+		return 0, fmt.Errorf("GetImageSize: %v", err)
 	}
+	return info.VirtualSize, nil
+}
 
-	log.Printf("SetVNCPassword: Connecting libvirt to %s", s.system)
+// GetStorage returns the capacity of the volume storage
+func (s *VirtioService) GetStorage() (*StorageModel, error) {
+	log.Printf("GetStorage: Connecting libvirt to %s", s.system)
 	conn, err := libvirt.NewConnect(s.system)
 	if err != nil {
-		return fmt.Errorf("SetVNCPassword: failed to connect to libvirt: %v", err)
+		return nil, fmt.Errorf("GetStorage: failed to connect to libvirt: %v", err)
 	}
 	defer conn.Close()
-
-	item, err := conn.LookupDomainByName(name)
-	if err != nil {
-		libvirtError, ok := err.(libvirt.Error)
-		if ok && libvirtError.Code == libvirt.ERR_NO_DOMAIN {
-			return fmt.Errorf("SetVNCPassword: Failed to find the domain: %s: Not Found", name)
-		} else {
-			return fmt.Errorf("SetVNCPassword: Failed to find the domain: %s: %v", name, err)
-		}
-	}
-	if item == nil {
-		return fmt.Errorf("SetVNCPassword: Failed to find the domain by name: %s", name)
-	}
-	defer item.Free()
-
-	err = changeVNCPassword(item, password)
-	if err != nil {
-		return fmt.Errorf("SetVNCPassword: failed to set VNC password: %v", err)
-	}
-	return nil
+	return s.storage.GetStorage(conn)
 }
 
 var _ ServerService = &VirtioService{}
 
-// DomainXMLForVNC represents the structure of the domain's XML we're interested in
-type DomainXMLForVNC struct {
-	Devices struct {
-		Graphics struct {
-			Type            string `xml:"type,attr"`
-			Autoport        string `xml:"autoport,attr"`
-			SharePolicy     string `xml:"sharePolicy,attr"`
-			Keymap          string `xml:"keymap,attr"`
-			Listen          string `xml:"listen,attr"`
-			Port            int    `xml:"port,attr"`
-			Websocket       int    `xml:"websocket,attr"`
-			PowerControl    string `xml:"powerControl,attr"`
-			Password        string `xml:"passwd,attr"`
-			Connected       string `xml:"connected,attr"`
-			Socket          string `xml:"socket,attr"`
-			PasswordValidTo string `xml:"passwdValidTo,attr"`
-		} `xml:"graphics"`
-	} `xml:"devices"`
-}
-
-func getVncServer(item *libvirt.Domain) (string, error) {
-
-	// Get the XML description of the domain
-	xmlDesc, err := item.GetXMLDesc(0)
-	if err != nil {
-		return "", fmt.Errorf("getVncServer: failed to get domain XML: %v", err)
-	}
-
-	// Parse the XML to extract VNC information
-	var domainXML DomainXMLForVNC
-	if err := xml.Unmarshal([]byte(xmlDesc), &domainXML); err != nil {
-		return "", fmt.Errorf("getVncServer: failed to unmarshal domain XML: %v", err)
-	}
-
-	// Check if the graphics type is VNC and print the details
-	if domainXML.Devices.Graphics.Type != "vnc" {
-		return "", fmt.Errorf("getVncServer: No VNC configuration found.")
-	}
-
-	return fmt.Sprintf("%s:%d", domainXML.Devices.Graphics.Listen, domainXML.Devices.Graphics.Port), nil
+func (s *VirtioService) getServerModel(
+	item *libvirt.Domain,
+) (*ServerModel, error) {
+	return s.getServerModelWithVolumes(item, nil)
 }
 
-func getServerModel(
+// getServerModelWithVolumes returns the server using the volume keys of all
+// servers listed already, or lists the volumes of the server if they are nil
+func (s *VirtioService) getServerModelWithVolumes(
 	item *libvirt.Domain,
-	enabledActions ServerActionCodeList,
+	volumes map[string][]string,
 ) (*ServerModel, error) {
 	state, _, err := item.GetState()
 	if err != nil {
@@ -641,7 +664,63 @@ func getServerModel(
 	if err != nil {
 		return nil, fmt.Errorf("failed to get domain name: %v", err)
 	}
-	return NewServerModel(name, domainStateToServerStatusCode(state), enabledActions), nil
+	status := domainStateToServerStatusCode(state)
+	if state == libvirt.DOMAIN_SHUTOFF {
+		saved, err := item.HasManagedSaveImage(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check saved state: %v", err)
+		}
+		if saved {
+			status = HibernatedServerStatusCode
+		}
+	}
+	info, err := item.GetInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain info: %v", err)
+	}
+	model := NewServerModel(name, status, s.enabledActions)
+	model.CPUs = info.NrVirtCpu
+	model.Memory = info.MaxMem * 1024
+	model.Volumes, err = s.getServerVolumes(item, name, volumes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain volumes: %v", err)
+	}
+	model.Interfaces, err = getDomainInterfaces(item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain interfaces: %v", err)
+	}
+	graphics, err := getDomainGraphics(item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain console: %v", err)
+	}
+	if graphics != nil {
+		model.Console = graphics.Type
+	}
+	if state == libvirt.DOMAIN_RUNNING {
+		model.GuestAgent, err = isGuestAgentConnected(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get guest agent state: %v", err)
+		}
+	}
+	model.Frozen = s.freezes.IsFrozen(name)
+	s.operations.Apply(model)
+	return model, nil
+}
+
+// lookupDomain finds a domain by name. The caller must free the domain.
+func lookupDomain(conn *libvirt.Connect, name string) (*libvirt.Domain, error) {
+	item, err := conn.LookupDomainByName(name)
+	if err != nil {
+		libvirtError, ok := err.(libvirt.Error)
+		if ok && libvirtError.Code == libvirt.ERR_NO_DOMAIN {
+			return nil, fmt.Errorf("failed to find the domain: %s: Not Found", name)
+		}
+		return nil, fmt.Errorf("failed to find the domain: %s: %v", name, err)
+	}
+	if item == nil {
+		return nil, fmt.Errorf("failed to find the domain by name: %s", name)
+	}
+	return item, nil
 }
 
 func domainStateToServerStatusCode(state libvirt.DomainState) ServerStatusCode {
@@ -667,66 +746,6 @@ func domainStateToServerStatusCode(state libvirt.DomainState) ServerStatusCode {
 	}
 }
 
-// gracefulRestart performs a graceful restart of the domain
-func gracefulRestart(domain *libvirt.Domain, domainName string) {
-
-	// Gracefully stop the domain
-	err := domain.Shutdown()
-	if err != nil {
-		log.Printf("gracefulRestart: Failed to shut down the domain: %v", err)
-		return
-	}
-	log.Printf("gracefulRestart: Domain '%s' shutdown signal sent", domainName)
-
-	// Wait for the domain to shut down completely
-	for {
-		state, _, err := domain.GetState()
-		if err != nil {
-			log.Printf("gracefulRestart: ERROR: Failed to get domain state: %v", err)
-			return
-		}
-		if state == libvirt.DOMAIN_SHUTOFF {
-			break
-		}
-		time.Sleep(1 * time.Second)
-	}
-
-	// Start the domain again
-	err = domain.Create()
-	if err != nil {
-		log.Printf("gracefulRestart: ERROR: Failed to start the domain: %v", err)
-		return
-	}
-	log.Printf("gracefulRestart: Domain '%s' restarted successfully", domainName)
-}
-
-func changeVNCPassword(domain *libvirt.Domain, newPassword string) error {
-
-	// Get the XML description of the domain
-	xmlDesc, err := domain.GetXMLDesc(0)
-	if err != nil {
-		return fmt.Errorf("changeVNCPassword: failed to get domain XML: %v", err)
-	}
-
-	// Parse the XML to extract VNC information
-	var domainXML DomainXMLForVNC
-	if err := xml.Unmarshal([]byte(xmlDesc), &domainXML); err != nil {
-		return fmt.Errorf("changeVNCPassword: failed to unmarshal domain XML: %v", err)
-	}
-
-	partialXML := fmt.Sprintf(`
-      <graphics type='vnc' listen='%s' passwd='%s' port='%d' autoport='%s' />
-`, domainXML.Devices.Graphics.Listen, newPassword, domainXML.Devices.Graphics.Port, domainXML.Devices.Graphics.Autoport)
-
-	err = domain.UpdateDeviceFlags(partialXML, libvirt.DOMAIN_DEVICE_MODIFY_CONFIG|libvirt.DOMAIN_DEVICE_MODIFY_CURRENT|libvirt.DOMAIN_DEVICE_MODIFY_LIVE)
-	if err != nil {
-		return fmt.Errorf("changeVNCPassword: failed to update domain configuration: %v", err)
-	}
-
-	log.Printf("changeVNCPassword: VNC password for domain changed successfully: %s", newPassword)
-	return nil
-}
-
 func copyImageFile(sourcePath, destinationFile string) error {
 
 	destinationDir := filepath.Dir(destinationFile)
@@ -756,6 +775,62 @@ func copyImageFile(sourcePath, destinationFile string) error {
 	return nil
 }
 
+// getCloudInitUserData returns the cloud-init user data which creates the
+// user. The password is left out if it is empty.
+func getCloudInitUserData(encryptedPassword string) string {
+	userData := `#cloud-config
+users:
+  - name: ` + serverUsername
+	if encryptedPassword != "" {
+		userData += `
+    passwd: ` + encryptedPassword
+	}
+	userData += `
+    lock_passwd: false
+    sudo: ['ALL=(ALL) NOPASSWD:ALL']
+    groups: sudo
+    shell: /bin/bash
+packages:
+  - qemu-guest-agent
+runcmd:
+  - [systemctl, start, qemu-guest-agent]`
+	return userData
+}
+
+// createCloudInitVolume builds the cloud-init ISO in a temporary directory and
+// imports it to the volume storage, replacing any previous ISO
+func (s *VirtioService) createCloudInitVolume(conn *libvirt.Connect, name, key, metaData, userData, networkConfig string) error {
+
+	tmpDir, err := os.MkdirTemp("", "govm-cidata-")
+	if err != nil {
+		return fmt.Errorf("createCloudInitVolume: failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	isoPath := filepath.Join(tmpDir, key)
+	err = createCloudInitISO(isoPath, metaData, userData, networkConfig)
+	if err != nil {
+		return fmt.Errorf("createCloudInitVolume: %v", err)
+	}
+
+	exists, err := s.storage.HasVolume(conn, name, key)
+	if err != nil {
+		return fmt.Errorf("createCloudInitVolume: %v", err)
+	}
+	if exists {
+		err = s.storage.DeleteVolume(conn, name, key)
+		if err != nil {
+			return fmt.Errorf("createCloudInitVolume: failed to remove previous ISO: %v", err)
+		}
+	}
+
+	err = s.storage.ImportVolume(conn, name, key, "raw", isoPath)
+	if err != nil {
+		return fmt.Errorf("createCloudInitVolume: %v", err)
+	}
+	return nil
+}
+
 func createCloudInitISO(isoPath, metaData, userData, networkConfig string) error {
 
 	// Create the ISO file
@@ -825,62 +900,6 @@ func encryptPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
-func getNetmask(prefix string) (string, error) {
-	switch prefix {
-	case "8":
-		return "255.0.0.0", nil
-	case "9":
-		return "255.128.0.0", nil
-	case "10":
-		return "255.192.0.0", nil
-	case "11":
-		return "255.224.0.0", nil
-	case "12":
-		return "255.240.0.0", nil
-	case "13":
-		return "255.248.0.0", nil
-	case "14":
-		return "255.252.0.0", nil
-	case "15":
-		return "255.254.0.0", nil
-	case "16":
-		return "255.255.0.0", nil
-	case "17":
-		return "255.255.128.0", nil
-	case "18":
-		return "255.255.192.0", nil
-	case "19":
-		return "255.255.224.0", nil
-	case "20":
-		return "255.255.240.0", nil
-	case "21":
-		return "255.255.248.0", nil
-	case "22":
-		return "255.255.252.0", nil
-	case "23":
-		return "255.255.254.0", nil
-	case "24":
-		return "255.255.255.0", nil
-	case "25":
-		return "255.255.255.128", nil
-	case "26":
-		return "255.255.255.192", nil
-	case "27":
-		return "255.255.255.224", nil
-	case "28":
-		return "255.255.255.240", nil
-	case "29":
-		return "255.255.255.248", nil
-	case "30":
-		return "255.255.255.252", nil
-	case "31":
-		return "255.255.255.254", nil
-	case "32":
-		return "255.255.255.255", nil
-	}
-	return "", fmt.Errorf("getNetmask: failed to parse netmask: %s", prefix)
-}
-
 func generateRandomMAC() (string, error) {
 
 	// 02:00:00 is a common prefix for locally administered MAC addresses
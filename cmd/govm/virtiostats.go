@@ -0,0 +1,79 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+
+	"libvirt.org/go/libvirt"
+)
+
+// GetServerStats returns the resource usage of all servers with one libvirt call
+func (s *VirtioService) GetServerStats() ([]*ServerStatsModel, error) {
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("GetServerStats: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	types := libvirt.DOMAIN_STATS_STATE | libvirt.DOMAIN_STATS_CPU_TOTAL | libvirt.DOMAIN_STATS_BALLOON |
+		libvirt.DOMAIN_STATS_INTERFACE | libvirt.DOMAIN_STATS_BLOCK
+	stats, err := conn.GetAllDomainStats(nil, types, 0)
+	if err != nil {
+		return nil, fmt.Errorf("GetServerStats: failed to get domain stats: %v", err)
+	}
+	defer func() {
+		for _, item := range stats {
+			item.Domain.Free()
+		}
+	}()
+
+	list := make([]*ServerStatsModel, 0, len(stats))
+	for _, item := range stats {
+		name, err := item.Domain.GetName()
+		if err != nil {
+			return nil, fmt.Errorf("GetServerStats: failed to get domain name: %v", err)
+		}
+		list = append(list, newServerStatsModel(name, item))
+	}
+	return list, nil
+}
+
+// newServerStatsModel converts the libvirt stats. Memory is reported in KiB.
+func newServerStatsModel(name string, stats libvirt.DomainStats) *ServerStatsModel {
+	model := &ServerStatsModel{
+		Name:   name,
+		Status: UnknownServerStatusCode,
+	}
+	if stats.State != nil {
+		model.Status = domainStateToServerStatusCode(stats.State.State)
+	}
+	if stats.Cpu != nil {
+		model.CPUTime = stats.Cpu.Time
+	}
+	if stats.Balloon != nil {
+		model.MemoryCurrent = stats.Balloon.Current * 1024
+		model.MemoryMaximum = stats.Balloon.Maximum * 1024
+		model.MemoryUsable = stats.Balloon.Usable * 1024
+		model.MemoryRSS = stats.Balloon.Rss * 1024
+	}
+	for _, disk := range stats.Block {
+		model.Disks = append(model.Disks, DiskStatsModel{
+			Device:        disk.Name,
+			ReadBytes:     disk.RdBytes,
+			WriteBytes:    disk.WrBytes,
+			ReadRequests:  disk.RdReqs,
+			WriteRequests: disk.WrReqs,
+		})
+	}
+	for _, nic := range stats.Net {
+		model.Interfaces = append(model.Interfaces, InterfaceStatsModel{
+			Device:    nic.Name,
+			RxBytes:   nic.RxBytes,
+			TxBytes:   nic.TxBytes,
+			RxPackets: nic.RxPkts,
+			TxPackets: nic.TxPkts,
+		})
+	}
+	return model
+}
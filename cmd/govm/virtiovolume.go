@@ -0,0 +1,449 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"strings"
+
+	"libvirt.org/go/libvirt"
+)
+
+// DomainXMLForDisks represents the disk devices of the domain's XML
+type DomainXMLForDisks struct {
+	Devices struct {
+		Disks []DomainDiskXML `xml:"disk"`
+	} `xml:"devices"`
+}
+
+// DomainDiskXML represents a single disk device of the domain's XML
+type DomainDiskXML struct {
+	Type   string `xml:"type,attr"`
+	Device string `xml:"device,attr"`
+	Source struct {
+		File   string `xml:"file,attr"`
+		Pool   string `xml:"pool,attr"`
+		Volume string `xml:"volume,attr"`
+	} `xml:"source"`
+	Target struct {
+		Dev string `xml:"dev,attr"`
+		Bus string `xml:"bus,attr"`
+	} `xml:"target"`
+}
+
+// ResizeServer grows the root disk of the server. A running server is resized
+// online through libvirt and a stopped server offline with qemu-img.
+func (s *VirtioService) ResizeServer(name string, size uint64) (*ServerModel, error) {
+	if !s.resizeEnabled {
+		return nil, fmt.Errorf("ResizeServer: Not enabled")
+	}
+
+	log.Printf("ResizeServer: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("ResizeServer: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("ResizeServer: %v", err)
+	}
+	defer item.Free()
+
+	disk, err := findDomainDiskByDevice(item, RootDiskDevice)
+	if err != nil {
+		return nil, fmt.Errorf("ResizeServer: %v", err)
+	}
+
+	blockInfo, err := item.GetBlockInfo(RootDiskDevice, 0)
+	if err != nil {
+		return nil, fmt.Errorf("ResizeServer: failed to get block info: %v", err)
+	}
+	err = checkDiskGrows(blockInfo.Capacity, size)
+	if err != nil {
+		return nil, fmt.Errorf("ResizeServer: %v", err)
+	}
+
+	active, err := item.IsActive()
+	if err != nil {
+		return nil, fmt.Errorf("ResizeServer: failed to get domain state: %v", err)
+	}
+
+	if active {
+		err = item.BlockResize(RootDiskDevice, size, libvirt.DOMAIN_BLOCK_RESIZE_BYTES)
+		if err != nil {
+			return nil, fmt.Errorf("ResizeServer: failed to resize the disk online: %v", err)
+		}
+	} else if key := s.storage.FindVolumeKey(name, *disk); key != "" {
+		err = s.storage.ResizeVolume(conn, name, key, size)
+		if err != nil {
+			return nil, fmt.Errorf("ResizeServer: failed to resize the disk offline: %v", err)
+		}
+	} else if disk.Source.File != "" {
+		err = qemuImgResize(disk.Source.File, size)
+		if err != nil {
+			return nil, fmt.Errorf("ResizeServer: failed to resize the disk offline: %v", err)
+		}
+	} else {
+		return nil, fmt.Errorf("ResizeServer: root disk is not managed by GoVM")
+	}
+	log.Printf("ResizeServer: Root disk of %s resized to %d bytes", name, size)
+
+	model, err := s.getServerModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("ResizeServer: failed to get domain data: %v", err)
+	}
+	return model, nil
+}
+
+// AddVolume creates a new qcow2 data volume and attaches it to the server
+func (s *VirtioService) AddVolume(name, volume string, size uint64) (*ServerModel, error) {
+	if !s.volumeEnabled {
+		return nil, fmt.Errorf("AddVolume: Not enabled")
+	}
+
+	log.Printf("AddVolume: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("AddVolume: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	key := getDataVolumeKey(name, volume)
+	exists, err := s.storage.HasVolume(conn, name, key)
+	if err != nil {
+		return nil, fmt.Errorf("AddVolume: %v", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("AddVolume: volume exists already: %s", volume)
+	}
+
+	err = s.storage.CreateVolume(conn, name, key, "qcow2", size)
+	if err != nil {
+		return nil, fmt.Errorf("AddVolume: failed to create volume: %v", err)
+	}
+	log.Printf("AddVolume: Volume created: %s", key)
+
+	return s.AttachVolume(name, volume)
+}
+
+// AttachVolume attaches an existing data volume to the server
+func (s *VirtioService) AttachVolume(name, volume string) (*ServerModel, error) {
+	if !s.volumeEnabled {
+		return nil, fmt.Errorf("AttachVolume: Not enabled")
+	}
+
+	log.Printf("AttachVolume: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	key := getDataVolumeKey(name, volume)
+	exists, err := s.storage.HasVolume(conn, name, key)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("AttachVolume: volume not found: %s", volume)
+	}
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: %v", err)
+	}
+	defer item.Free()
+
+	disks, err := getDomainDisks(item)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: %v", err)
+	}
+
+	var usedDevices []string
+	for _, disk := range disks {
+		if s.storage.FindVolumeKey(name, disk) == key {
+			return nil, fmt.Errorf("AttachVolume: volume is already attached: %s", volume)
+		}
+		usedDevices = append(usedDevices, disk.Target.Dev)
+	}
+
+	device, err := findFreeDiskDevice(usedDevices)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: %v", err)
+	}
+
+	flags, err := getDeviceModifyFlags(item)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: %v", err)
+	}
+
+	diskType, sourceXML := s.storage.GetDiskSource(name, key)
+	diskXML := `<disk type='` + diskType + `' device='disk'>
+      <driver name='qemu' type='qcow2'/>
+      ` + sourceXML + `
+      <target dev='` + device + `' bus='virtio'/>
+    </disk>`
+
+	err = item.AttachDeviceFlags(diskXML, flags)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: failed to attach the volume: %v", err)
+	}
+	log.Printf("AttachVolume: Volume %s attached to %s as %s", volume, name, device)
+
+	model, err := s.getServerModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("AttachVolume: failed to get domain data: %v", err)
+	}
+	return model, nil
+}
+
+// DetachVolume detaches a data volume from the server. The volume file is kept.
+func (s *VirtioService) DetachVolume(name, volume string) (*ServerModel, error) {
+	if !s.volumeEnabled {
+		return nil, fmt.Errorf("DetachVolume: Not enabled")
+	}
+
+	log.Printf("DetachVolume: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("DetachVolume: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("DetachVolume: %v", err)
+	}
+	defer item.Free()
+
+	disks, err := getDomainDisks(item)
+	if err != nil {
+		return nil, fmt.Errorf("DetachVolume: %v", err)
+	}
+
+	key := getDataVolumeKey(name, volume)
+	var device string
+	for _, disk := range disks {
+		if s.storage.FindVolumeKey(name, disk) == key {
+			device = disk.Target.Dev
+			break
+		}
+	}
+	if device == "" {
+		return nil, fmt.Errorf("DetachVolume: volume is not attached: %s", volume)
+	}
+
+	flags, err := getDeviceModifyFlags(item)
+	if err != nil {
+		return nil, fmt.Errorf("DetachVolume: %v", err)
+	}
+
+	diskType, sourceXML := s.storage.GetDiskSource(name, key)
+	diskXML := `<disk type='` + diskType + `' device='disk'>
+      ` + sourceXML + `
+      <target dev='` + device + `' bus='virtio'/>
+    </disk>`
+
+	err = item.DetachDeviceFlags(diskXML, flags)
+	if err != nil {
+		return nil, fmt.Errorf("DetachVolume: failed to detach the volume: %v", err)
+	}
+	log.Printf("DetachVolume: Volume %s detached from %s", volume, name)
+
+	model, err := s.getServerModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("DetachVolume: failed to get domain data: %v", err)
+	}
+	return model, nil
+}
+
+// DeleteVolume removes a detached data volume
+func (s *VirtioService) DeleteVolume(name, volume string) (*ServerModel, error) {
+	if !s.volumeEnabled {
+		return nil, fmt.Errorf("DeleteVolume: Not enabled")
+	}
+
+	log.Printf("DeleteVolume: Connecting libvirt to %s", s.system)
+	conn, err := libvirt.NewConnect(s.system)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteVolume: failed to connect to libvirt: %v", err)
+	}
+	defer conn.Close()
+
+	item, err := lookupDomain(conn, name)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteVolume: %v", err)
+	}
+	defer item.Free()
+
+	model, err := s.getServerModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteVolume: failed to get domain data: %v", err)
+	}
+
+	found := model.Volumes.findByName(volume)
+	if found == nil || found.Root {
+		return nil, fmt.Errorf("DeleteVolume: volume not found: %s", volume)
+	}
+	if found.IsAttached() {
+		return nil, fmt.Errorf("DeleteVolume: volume must be detached first: %s", volume)
+	}
+
+	err = s.storage.DeleteVolume(conn, name, getDataVolumeKey(name, volume))
+	if err != nil {
+		return nil, fmt.Errorf("DeleteVolume: failed to remove the volume: %v", err)
+	}
+	log.Printf("DeleteVolume: Volume %s of %s deleted", volume, name)
+
+	model, err = s.getServerModel(item)
+	if err != nil {
+		return nil, fmt.Errorf("DeleteVolume: failed to get domain data: %v", err)
+	}
+	return model, nil
+}
+
+// getServerVolumes returns the attached disks of the domain and any detached
+// data volumes left in the storage. The volumes are listed from the storage
+// unless the keys of all servers are given.
+func (s *VirtioService) getServerVolumes(item *libvirt.Domain, name string, volumes map[string][]string) (VolumeModelList, error) {
+
+	conn, err := item.DomainGetConnect()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	disks, err := getDomainDisks(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var list VolumeModelList
+	for _, disk := range disks {
+		if disk.Device != "disk" {
+			continue
+		}
+		var size uint64
+		blockInfo, err := item.GetBlockInfo(disk.Target.Dev, 0)
+		if err != nil {
+			log.Printf("getServerVolumes: Warning! Failed to get block info: %s: %s: %v", name, disk.Target.Dev, err)
+		} else {
+			size = blockInfo.Capacity
+		}
+		volumeName := getDataVolumeName(name, s.storage.FindVolumeKey(name, disk))
+		if volumeName == "" {
+			volumeName = disk.Target.Dev
+		}
+		list = append(list, NewVolumeModel(volumeName, disk.Target.Dev, size, disk.Target.Dev == RootDiskDevice))
+	}
+
+	keys := volumes[name]
+	if volumes == nil {
+		keys, err = s.storage.ListVolumes(conn, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list volumes: %v", err)
+		}
+	}
+	for _, key := range keys {
+		volumeName := getDataVolumeName(name, key)
+		if volumeName == "" || list.findByName(volumeName) != nil {
+			continue
+		}
+		size, err := s.storage.GetVolumeSize(conn, name, key)
+		if err != nil {
+			log.Printf("getServerVolumes: Warning! Failed to read volume: %v", err)
+		}
+		list = append(list, NewVolumeModel(volumeName, "", size, false))
+	}
+
+	return list, nil
+}
+
+// getRootDiskKey returns the storage key of the root disk of the server
+func getRootDiskKey(name string) string {
+	return name + "-" + RootDiskDevice + ".qcow2"
+}
+
+// getCloudInitKey returns the storage key of the cloud-init ISO of the server
+func getCloudInitKey(name string) string {
+	return name + "-cidata.iso"
+}
+
+// getDataVolumeKey returns the storage key of a data volume of the server
+func getDataVolumeKey(name, volume string) string {
+	return name + DataVolumeFilePrefix + volume + ".qcow2"
+}
+
+// getDataVolumeName returns the volume name from the storage key of a data
+// volume, or an empty string if the key isn't a data volume of the server
+func getDataVolumeName(name, key string) string {
+	prefix := name + DataVolumeFilePrefix
+	if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, ".qcow2") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(key, prefix), ".qcow2")
+}
+
+// getDomainDisks parses the disk devices from the domain XML
+func getDomainDisks(item *libvirt.Domain) ([]DomainDiskXML, error) {
+	xmlDesc, err := item.GetXMLDesc(0)
+	if err != nil {
+		return nil, fmt.Errorf("getDomainDisks: failed to get domain XML: %v", err)
+	}
+	var domainXML DomainXMLForDisks
+	if err := xml.Unmarshal([]byte(xmlDesc), &domainXML); err != nil {
+		return nil, fmt.Errorf("getDomainDisks: failed to unmarshal domain XML: %v", err)
+	}
+	return domainXML.Devices.Disks, nil
+}
+
+// findDomainDiskByDevice finds a disk of the domain by the target device name
+func findDomainDiskByDevice(item *libvirt.Domain, device string) (*DomainDiskXML, error) {
+	disks, err := getDomainDisks(item)
+	if err != nil {
+		return nil, err
+	}
+	for _, disk := range disks {
+		if disk.Target.Dev == device {
+			return &disk, nil
+		}
+	}
+	return nil, fmt.Errorf("findDomainDiskByDevice: no such disk: %s", device)
+}
+
+// findFreeDiskDevice returns the first virtio disk device name not in use
+func findFreeDiskDevice(usedDevices []string) (string, error) {
+	for c := 'b'; c <= 'z'; c++ {
+		device := fmt.Sprintf("vd%c", c)
+		if !contains(usedDevices, device) {
+			return device, nil
+		}
+	}
+	return "", fmt.Errorf("findFreeDiskDevice: no free disk devices")
+}
+
+// checkDiskGrows returns an error unless the requested size is larger than
+// the current size, since a disk can not be shrunk without losing data
+func checkDiskGrows(current, size uint64) error {
+	if size <= current {
+		return fmt.Errorf("disk can only grow: current size %d, requested %d", current, size)
+	}
+	return nil
+}
+
+// getDeviceModifyFlags returns flags to modify the persistent configuration
+// and, if the domain is running, the live domain too
+func getDeviceModifyFlags(item *libvirt.Domain) (libvirt.DomainDeviceModifyFlags, error) {
+	active, err := item.IsActive()
+	if err != nil {
+		return 0, fmt.Errorf("getDeviceModifyFlags: failed to get domain state: %v", err)
+	}
+	if active {
+		return libvirt.DOMAIN_DEVICE_MODIFY_CONFIG | libvirt.DOMAIN_DEVICE_MODIFY_LIVE, nil
+	}
+	return libvirt.DOMAIN_DEVICE_MODIFY_CONFIG, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestFindFreeDiskDevice(t *testing.T) {
+	all := []string{RootDiskDevice}
+	for c := 'b'; c <= 'z'; c++ {
+		all = append(all, "vd"+string(c))
+	}
+	tests := []struct {
+		used   []string
+		device string
+	}{
+		{nil, "vdb"},
+		{[]string{RootDiskDevice}, "vdb"},
+		{[]string{RootDiskDevice, "vdb"}, "vdc"},
+		{[]string{RootDiskDevice, "vdc"}, "vdb"},
+		{[]string{RootDiskDevice, "vdb", "vdc", "sda"}, "vdd"},
+		{all, ""},
+	}
+	for _, test := range tests {
+		device, err := findFreeDiskDevice(test.used)
+		if device != test.device {
+			t.Errorf("%v: device %q, want %q", test.used, device, test.device)
+		}
+		if (err != nil) != (test.device == "") {
+			t.Errorf("%v: unexpected error %v", test.used, err)
+		}
+	}
+}
+
+func TestGetDataVolumeName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"web-data-logs.qcow2", "logs"},
+		{"/var/lib/libvirt/images/web-data-logs.qcow2", ""},
+		{"web-data-.qcow2", ""},
+		{"web.qcow2", ""},
+		{"web-data-logs.img", ""},
+		{"webapp-data-logs.qcow2", ""},
+		{"other-data-logs.qcow2", ""},
+	}
+	for _, test := range tests {
+		if got := getDataVolumeName("web", test.key); got != test.want {
+			t.Errorf("getDataVolumeName(web, %q) = %q, want %q", test.key, got, test.want)
+		}
+	}
+}
+
+func TestCheckDiskGrows(t *testing.T) {
+	tests := []struct {
+		current uint64
+		size    uint64
+		ok      bool
+	}{
+		{10, 11, true},
+		{10, 10, false},
+		{10, 9, false},
+		{0, 1, true},
+		{0, 0, false},
+	}
+	for _, test := range tests {
+		if err := checkDiskGrows(test.current, test.size); (err == nil) != test.ok {
+			t.Errorf("checkDiskGrows(%d, %d) = %v, want ok %v", test.current, test.size, err, test.ok)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// vncBufferPool holds the copy buffers of the VNC proxies
+var vncBufferPool = sync.Pool{
+	New: func() any {
+		buffer := make([]byte, VncProxyBufferSize)
+		return &buffer
+	},
+}
+
+// VncTraffic counts the bytes a VNC session has moved
+type VncTraffic struct {
+
+	// ToServer is the number of bytes sent from the browser to the VNC server
+	ToServer atomic.Uint64
+
+	// ToClient is the number of bytes sent from the VNC server to the browser
+	ToClient atomic.Uint64
+}
+
+// VncProxy copies the traffic between a WebSocket and a VNC server in both
+// directions. Reading the next chunk waits until the previous one has been
+// written, so a slow peer slows down the other end instead of filling memory.
+type VncProxy struct {
+
+	// PingInterval is how often the WebSocket is pinged and the idle time checked
+	PingInterval time.Duration
+
+	// PongTimeout closes the proxy if nothing is heard from the browser in time
+	PongTimeout time.Duration
+
+	// WriteTimeout is the maximum time a single write may block
+	WriteTimeout time.Duration
+
+	// IdleTimeout closes the proxy if no data has moved in either direction
+	IdleTimeout time.Duration
+}
+
+// NewVncProxy returns a proxy with the default timeouts
+func NewVncProxy() *VncProxy {
+	return &VncProxy{
+		PingInterval: VncPingInterval,
+		PongTimeout:  VncPongTimeout,
+		WriteTimeout: VncWriteTimeout,
+		IdleTimeout:  VncIdleTimeout,
+	}
+}
+
+// Run proxies the traffic of the session until either end closes, the
+// browser stops answering pings or the session is idle for too long. Both
+// connections are closed when it returns.
+func (p *VncProxy) Run(wsConn *websocket.Conn, vncConn net.Conn, session *VncSession) {
+
+	toServerMetric := vncBytesTotal.WithLabelValues(session.ID, session.Server, "to_server")
+	toClientMetric := vncBytesTotal.WithLabelValues(session.ID, session.Server, "to_client")
+	defer vncBytesTotal.DeleteLabelValues(session.ID, session.Server, "to_server")
+	defer vncBytesTotal.DeleteLabelValues(session.ID, session.Server, "to_client")
+
+	var lastActivity atomic.Int64
+	lastActivity.Store(time.Now().UnixNano())
+
+	done := make(chan struct{})
+	var closeOnce sync.Once
+	shutdown := func(reason string) {
+		closeOnce.Do(func() {
+			log.Printf("VncProxy: Closing session %s of %s: %s", session.ID, session.Server, reason)
+			close(done)
+			message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			_ = wsConn.WriteControl(websocket.CloseMessage, message, time.Now().Add(p.WriteTimeout))
+			_ = wsConn.Close()
+			_ = vncConn.Close()
+		})
+	}
+
+	_ = wsConn.SetReadDeadline(time.Now().Add(p.PongTimeout))
+	wsConn.SetPongHandler(func(string) error {
+		return wsConn.SetReadDeadline(time.Now().Add(p.PongTimeout))
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	// Forward messages from the WebSocket to the VNC server
+	go func() {
+		defer wg.Done()
+		buffer := vncBufferPool.Get().(*[]byte)
+		defer vncBufferPool.Put(buffer)
+		for {
+			messageType, reader, err := wsConn.NextReader()
+			if err != nil {
+				shutdown(describeVncProxyError("browser", err))
+				return
+			}
+			_ = wsConn.SetReadDeadline(time.Now().Add(p.PongTimeout))
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			for {
+				n, err := reader.Read(*buffer)
+				if n > 0 {
+					_ = vncConn.SetWriteDeadline(time.Now().Add(p.WriteTimeout))
+					if _, err := vncConn.Write((*buffer)[:n]); err != nil {
+						shutdown(describeVncProxyError("VNC server", err))
+						return
+					}
+					session.Traffic.ToServer.Add(uint64(n))
+					toServerMetric.Add(float64(n))
+					lastActivity.Store(time.Now().UnixNano())
+				}
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					shutdown(describeVncProxyError("browser", err))
+					return
+				}
+			}
+		}
+	}()
+
+	// Forward messages from the VNC server to the WebSocket
+	go func() {
+		defer wg.Done()
+		buffer := vncBufferPool.Get().(*[]byte)
+		defer vncBufferPool.Put(buffer)
+		for {
+			n, err := vncConn.Read(*buffer)
+			if n > 0 {
+				_ = wsConn.SetWriteDeadline(time.Now().Add(p.WriteTimeout))
+				if err := wsConn.WriteMessage(websocket.BinaryMessage, (*buffer)[:n]); err != nil {
+					shutdown(describeVncProxyError("browser", err))
+					return
+				}
+				session.Traffic.ToClient.Add(uint64(n))
+				toClientMetric.Add(float64(n))
+				lastActivity.Store(time.Now().UnixNano())
+			}
+			if err != nil {
+				shutdown(describeVncProxyError("VNC server", err))
+				return
+			}
+		}
+	}()
+
+	// Ping the browser and watch for idle sessions
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(p.PingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if p.IdleTimeout > 0 && now.Sub(time.Unix(0, lastActivity.Load())) >= p.IdleTimeout {
+					shutdown("idle timeout")
+					return
+				}
+				if err := wsConn.WriteControl(websocket.PingMessage, nil, now.Add(p.WriteTimeout)); err != nil {
+					shutdown(describeVncProxyError("browser", err))
+					return
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+	log.Printf("VncProxy: Session %s of %s closed after %d bytes to server and %d bytes to client",
+		session.ID, session.Server, session.Traffic.ToServer.Load(), session.Traffic.ToClient.Load())
+}
+
+// describeVncProxyError returns the reason a direction of the proxy stopped
+func describeVncProxyError(peer string, err error) string {
+	if err == io.EOF || errors.Is(err, net.ErrClosed) ||
+		websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		return peer + " disconnected"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return peer + " timed out"
+	}
+	return peer + " error: " + err.Error()
+}
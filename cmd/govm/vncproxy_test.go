@@ -0,0 +1,154 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const fakeVncBanner = "RFB 003.008\n"
+
+// startFakeVncServer starts a VNC server which sends the protocol banner and
+// then echoes everything back. Closed connections are sent to the channel.
+func startFakeVncServer(t *testing.T) (string, chan struct{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	closed := make(chan struct{}, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte(fakeVncBanner))
+				io.Copy(conn, conn)
+				closed <- struct{}{}
+			}()
+		}
+	}()
+	return listener.Addr().String(), closed
+}
+
+// startVncProxy starts a WebSocket server which proxies to the VNC server
+func startVncProxy(t *testing.T, proxy *VncProxy, vncAddress string, session *VncSession) (*websocket.Conn, chan struct{}) {
+	finished := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsConn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade failed: %v", err)
+			return
+		}
+		vncConn, err := net.Dial("tcp", vncAddress)
+		if err != nil {
+			t.Errorf("Dial failed: %v", err)
+			wsConn.Close()
+			return
+		}
+		proxy.Run(wsConn, vncConn, session)
+		close(finished)
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, finished
+}
+
+// readVncData reads binary messages until the number of bytes has been received
+func readVncData(t *testing.T, client *websocket.Conn, size int) []byte {
+	var data []byte
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(data) < size {
+		messageType, message, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if messageType != websocket.BinaryMessage {
+			t.Fatalf("Expected a binary message, got %d", messageType)
+		}
+		data = append(data, message...)
+	}
+	return data
+}
+
+func waitVncProxy(t *testing.T, channel chan struct{}, what string) {
+	select {
+	case <-channel:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for %s", what)
+	}
+}
+
+func TestVncProxy(t *testing.T) {
+	vncAddress, vncClosed := startFakeVncServer(t)
+	session := &VncSession{ID: "test1", Server: "web1"}
+	client, finished := startVncProxy(t, NewVncProxy(), vncAddress, session)
+
+	banner := readVncData(t, client, len(fakeVncBanner))
+	if string(banner) != fakeVncBanner {
+		t.Errorf("Expected the banner, got %q", banner)
+	}
+
+	// Larger than the buffer to be split into multiple reads
+	payload := bytes.Repeat([]byte("0123456789abcdef"), VncProxyBufferSize/8)
+	if err := client.WriteMessage(websocket.BinaryMessage, payload); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	echo := readVncData(t, client, len(payload))
+	if !bytes.Equal(echo, payload) {
+		t.Errorf("Expected the payload back, got %d bytes", len(echo))
+	}
+
+	// Closing the browser closes the VNC connection
+	client.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	waitVncProxy(t, finished, "the proxy")
+	waitVncProxy(t, vncClosed, "the VNC connection")
+
+	if got := session.Traffic.ToServer.Load(); got != uint64(len(payload)) {
+		t.Errorf("Expected %d bytes to server, got %d", len(payload), got)
+	}
+	if got := session.Traffic.ToClient.Load(); got != uint64(len(fakeVncBanner)+len(payload)) {
+		t.Errorf("Expected %d bytes to client, got %d", len(fakeVncBanner)+len(payload), got)
+	}
+}
+
+func TestVncProxyIdleTimeout(t *testing.T) {
+	vncAddress, vncClosed := startFakeVncServer(t)
+	proxy := &VncProxy{
+		PingInterval: 10 * time.Millisecond,
+		PongTimeout:  time.Second,
+		WriteTimeout: time.Second,
+		IdleTimeout:  50 * time.Millisecond,
+	}
+	client, finished := startVncProxy(t, proxy, vncAddress, &VncSession{ID: "test2", Server: "web1"})
+	readVncData(t, client, len(fakeVncBanner))
+
+	// The client must keep reading for the pings to be answered
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	waitVncProxy(t, finished, "the idle timeout")
+	waitVncProxy(t, vncClosed, "the VNC connection")
+}
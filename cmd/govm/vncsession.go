@@ -0,0 +1,282 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrTooManyVncSessions = errors.New("too many console sessions for the server")
+
+// VncSession is a VNC or SPICE console opened by a user
+type VncSession struct {
+
+	// ID identifies the session for admins without revealing the token
+	ID string
+
+	// Token is the secret used to connect the WebSocket
+	Token string
+
+	// Owner is the email of the user who opened the session
+	Owner string
+
+	// Server is the name of the server
+	Server string
+
+	// Type is the console protocol, vnc or spice
+	Type string
+
+	// Created is the time the session was opened
+	Created time.Time
+
+	// Expires is the time the session is closed at the latest
+	Expires time.Time
+
+	// Traffic counts the bytes proxied while connected
+	Traffic VncTraffic
+
+	// conns are the connected WebSockets. SPICE uses one for each channel.
+	conns []io.Closer
+}
+
+// IsConnected returns true if a WebSocket of the session is connected
+func (item *VncSession) IsConnected() bool {
+	return len(item.conns) > 0
+}
+
+func (item *VncSession) ToDTO() VncSessionDTO {
+	return VncSessionDTO{
+		ID:            item.ID,
+		Owner:         item.Owner,
+		Server:        item.Server,
+		Type:          item.Type,
+		Created:       item.Created.UTC().Format(time.RFC3339),
+		Expires:       item.Expires.UTC().Format(time.RFC3339),
+		Connected:     item.IsConnected(),
+		BytesToServer: item.Traffic.ToServer.Load(),
+		BytesToClient: item.Traffic.ToClient.Load(),
+	}
+}
+
+// VncSessionManager keeps track of the open VNC consoles
+type VncSessionManager struct {
+	mutex        sync.Mutex
+	sessions     map[string]*VncSession
+	ttl          time.Duration
+	maxPerServer int
+
+	// onRemove is called without the lock when the last session of a server
+	// has been removed, so the VNC password can be rotated
+	onRemove func(server string)
+
+	stop chan struct{}
+}
+
+func NewVncSessionManager(ttl time.Duration, maxPerServer int, onRemove func(server string)) *VncSessionManager {
+	return &VncSessionManager{
+		sessions:     make(map[string]*VncSession),
+		ttl:          ttl,
+		maxPerServer: maxPerServer,
+		onRemove:     onRemove,
+	}
+}
+
+// Start starts closing the expired sessions in the background
+func (m *VncSessionManager) Start(interval time.Duration) {
+	m.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				m.expireSessions(now)
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops closing the expired sessions
+func (m *VncSessionManager) Stop() {
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// Create opens a new session unless the server has too many sessions already
+func (m *VncSessionManager) Create(owner, server, consoleType string) (*VncSession, error) {
+	token, err := generatePassword(32)
+	if err != nil {
+		return nil, fmt.Errorf("Create: failed to generate token: %v", err)
+	}
+	id, err := generatePassword(12)
+	if err != nil {
+		return nil, fmt.Errorf("Create: failed to generate id: %v", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.countByServer(server) >= m.maxPerServer {
+		return nil, ErrTooManyVncSessions
+	}
+	now := time.Now()
+	item := &VncSession{
+		ID:      id,
+		Token:   token,
+		Owner:   owner,
+		Server:  server,
+		Type:    consoleType,
+		Created: now,
+		Expires: now.Add(m.ttl),
+	}
+	m.sessions[token] = item
+	log.Printf("VncSessionManager: Session %s opened by %s for %s", id, owner, server)
+	return item, nil
+}
+
+// Connect marks the session connected with the WebSocket. A VNC token can be
+// used for one connection only, while SPICE connects each channel separately.
+func (m *VncSessionManager) Connect(token string, conn io.Closer) (*VncSession, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	item, exists := m.sessions[token]
+	if !exists || !time.Now().Before(item.Expires) {
+		return nil, errors.New("session not found")
+	}
+	if item.IsConnected() && item.Type != SpiceConsoleType {
+		return nil, errors.New("session already connected")
+	}
+	item.conns = append(item.conns, conn)
+	return item, nil
+}
+
+// Disconnect forgets a closed WebSocket of the session. The session is
+// removed when the last WebSocket disconnects.
+func (m *VncSessionManager) Disconnect(token string, conn io.Closer) {
+	m.mutex.Lock()
+	item, exists := m.sessions[token]
+	if exists {
+		for i, c := range item.conns {
+			if c == conn {
+				item.conns = append(item.conns[:i], item.conns[i+1:]...)
+				break
+			}
+		}
+		exists = !item.IsConnected()
+	}
+	m.mutex.Unlock()
+	if exists {
+		m.Remove(token)
+	}
+}
+
+// FindByToken returns the session of the token, otherwise nil
+func (m *VncSessionManager) FindByToken(token string) *VncSession {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.sessions[token]
+}
+
+// List returns the sessions ordered by the creation time
+func (m *VncSessionManager) List() []*VncSession {
+	m.mutex.Lock()
+	list := make([]*VncSession, 0, len(m.sessions))
+	for _, item := range m.sessions {
+		list = append(list, item)
+	}
+	m.mutex.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.Before(list[j].Created)
+	})
+	return list
+}
+
+// Remove closes the session of the token. It returns the removed session, or
+// nil if there was no such session.
+func (m *VncSessionManager) Remove(token string) *VncSession {
+	m.mutex.Lock()
+	item, exists := m.sessions[token]
+	if exists {
+		delete(m.sessions, token)
+	}
+	m.mutex.Unlock()
+	if !exists {
+		return nil
+	}
+	m.closeSession(item)
+	return item
+}
+
+// RemoveByID closes the session with the id, used by admins
+func (m *VncSessionManager) RemoveByID(id string) *VncSession {
+	m.mutex.Lock()
+	var token string
+	for key, item := range m.sessions {
+		if item.ID == id {
+			token = key
+			break
+		}
+	}
+	m.mutex.Unlock()
+	if token == "" {
+		return nil
+	}
+	return m.Remove(token)
+}
+
+// expireSessions closes the sessions which have expired at the time
+func (m *VncSessionManager) expireSessions(now time.Time) {
+	var expired []*VncSession
+	m.mutex.Lock()
+	for token, item := range m.sessions {
+		if !now.Before(item.Expires) {
+			delete(m.sessions, token)
+			expired = append(expired, item)
+		}
+	}
+	m.mutex.Unlock()
+	for _, item := range expired {
+		log.Printf("VncSessionManager: Session %s of %s expired", item.ID, item.Server)
+		m.closeSession(item)
+	}
+}
+
+// closeSession disconnects the removed session and rotates the password of
+// the server if it was the last session
+func (m *VncSessionManager) closeSession(item *VncSession) {
+	m.mutex.Lock()
+	conns := item.conns
+	item.conns = nil
+	m.mutex.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	log.Printf("VncSessionManager: Session %s closed for %s", item.ID, item.Server)
+
+	m.mutex.Lock()
+	remaining := m.countByServer(item.Server)
+	m.mutex.Unlock()
+	if remaining == 0 && m.onRemove != nil {
+		m.onRemove(item.Server)
+	}
+}
+
+// countByServer returns the number of sessions of the server. The lock must be held.
+func (m *VncSessionManager) countByServer(server string) int {
+	count := 0
+	for _, item := range m.sessions {
+		if item.Server == server {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+type testCloser struct {
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestVncSessionManager(t *testing.T) {
+	var rotated []string
+	manager := NewVncSessionManager(time.Minute, 2, func(server string) {
+		rotated = append(rotated, server)
+	})
+
+	first, err := manager.Create("user@example.com", "web1", VncConsoleType)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	second, err := manager.Create("user@example.com", "web1", VncConsoleType)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := manager.Create("user@example.com", "web1", VncConsoleType); err != ErrTooManyVncSessions {
+		t.Errorf("Expected the session limit error, got %v", err)
+	}
+
+	conn := &testCloser{}
+	if _, err := manager.Connect(first.Token, conn); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := manager.Connect(first.Token, &testCloser{}); err == nil {
+		t.Errorf("Expected an error when the token is used twice")
+	}
+
+	// The password is rotated only after the last session of the server
+	if manager.RemoveByID(first.ID) == nil {
+		t.Fatalf("Expected the session to be removed")
+	}
+	if !conn.closed {
+		t.Errorf("Expected the connection to be closed")
+	}
+	if len(rotated) != 0 {
+		t.Errorf("Expected no rotation, got %v", rotated)
+	}
+
+	manager.expireSessions(time.Now())
+	if manager.FindByToken(second.Token) == nil {
+		t.Errorf("Expected the session not to expire yet")
+	}
+	manager.expireSessions(second.Expires)
+	if manager.FindByToken(second.Token) != nil {
+		t.Errorf("Expected the session to expire")
+	}
+	if len(rotated) != 1 || rotated[0] != "web1" {
+		t.Errorf("Expected the password of web1 to be rotated, got %v", rotated)
+	}
+}
+
+func TestVncSessionManagerSpice(t *testing.T) {
+	var rotated []string
+	manager := NewVncSessionManager(time.Minute, 1, func(server string) {
+		rotated = append(rotated, server)
+	})
+
+	session, err := manager.Create("user@example.com", "web1", SpiceConsoleType)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// SPICE connects each channel separately
+	mainChannel, displayChannel := &testCloser{}, &testCloser{}
+	if _, err := manager.Connect(session.Token, mainChannel); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := manager.Connect(session.Token, displayChannel); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	manager.Disconnect(session.Token, displayChannel)
+	if manager.FindByToken(session.Token) == nil {
+		t.Fatalf("Expected the session to stay open while a channel is connected")
+	}
+	manager.Disconnect(session.Token, mainChannel)
+	if manager.FindByToken(session.Token) != nil {
+		t.Errorf("Expected the session to be removed with the last channel")
+	}
+	if len(rotated) != 1 {
+		t.Errorf("Expected the password to be rotated once, got %v", rotated)
+	}
+}
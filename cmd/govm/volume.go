@@ -0,0 +1,77 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+// VolumeModel This is the data model of a disk volume of the server
+type VolumeModel struct {
+
+	// Name the name of the volume
+	Name string
+
+	// Device the target device inside the guest, empty if the volume is detached
+	Device string
+
+	// Size the virtual size of the volume in bytes
+	Size uint64
+
+	// Root is true for the boot disk of the server
+	Root bool
+}
+
+func NewVolumeModel(
+	name string,
+	device string,
+	size uint64,
+	root bool,
+) *VolumeModel {
+	return &VolumeModel{
+		Name:   name,
+		Device: device,
+		Size:   size,
+		Root:   root,
+	}
+}
+
+// IsAttached returns true if the volume is attached to the server
+func (item *VolumeModel) IsAttached() bool {
+	return item.Device != ""
+}
+
+func (item *VolumeModel) ToDTO() VolumeDTO {
+	return VolumeDTO{
+		Name:     item.Name,
+		Device:   item.Device,
+		Size:     item.Size,
+		Root:     item.Root,
+		Attached: item.IsAttached(),
+	}
+}
+
+type VolumeModelList []*VolumeModel
+
+// findByName finds a volume by name and returns it, otherwise nil
+func (list VolumeModelList) findByName(name string) *VolumeModel {
+	for _, item := range list {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// TotalSize returns the sum of the virtual sizes of all volumes in bytes
+func (list VolumeModelList) TotalSize() uint64 {
+	var total uint64
+	for _, item := range list {
+		total += item.Size
+	}
+	return total
+}
+
+func (list VolumeModelList) ToDTO() []VolumeDTO {
+	ret := make([]VolumeDTO, len(list))
+	for i, item := range list {
+		ret[i] = item.ToDTO()
+	}
+	return ret
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024. Sendanor <info@sendanor.fi>. All rights reserved.
+
+package main
+
+import (
+	"libvirt.org/go/libvirt"
+)
+
+// VolumeStorage stores the disk images of the servers. Volumes are identified
+// by the server name and a key, which is the file name of the volume.
+type VolumeStorage interface {
+
+	// CreateVolume creates a new empty volume with the given format and size in bytes
+	CreateVolume(conn *libvirt.Connect, server, key, format string, size uint64) error
+
+	// ImportVolume creates a new volume from the contents of a local file
+	ImportVolume(conn *libvirt.Connect, server, key, format, sourceFile string) error
+
+	// DeleteVolume removes the volume
+	DeleteVolume(conn *libvirt.Connect, server, key string) error
+
+	// HasVolume returns true if the volume exists
+	HasVolume(conn *libvirt.Connect, server, key string) (bool, error)
+
+	// ListVolumes returns the keys of all volumes of the server
+	ListVolumes(conn *libvirt.Connect, server string) ([]string, error)
+
+	// ListAllVolumes returns the keys of the volumes of all servers by the
+	// server name
+	ListAllVolumes(conn *libvirt.Connect) (map[string][]string, error)
+
+	// GetVolumeSize returns the virtual size of the volume in bytes
+	GetVolumeSize(conn *libvirt.Connect, server, key string) (uint64, error)
+
+	// ResizeVolume changes the virtual size of a volume which is not in use
+	ResizeVolume(conn *libvirt.Connect, server, key string, size uint64) error
+
+	// GetDiskSource returns the disk type and the source element for the domain XML
+	GetDiskSource(server, key string) (string, string)
+
+	// FindVolumeKey returns the key of the volume used by a disk of the
+	// server, otherwise an empty string
+	FindVolumeKey(server string, disk DomainDiskXML) string
+
+	// GetStorage returns the capacity of the storage
+	GetStorage(conn *libvirt.Connect) (*StorageModel, error)
+}
+
+// StorageModel This is the data model of the storage capacity for volumes
+type StorageModel struct {
+
+	// Type the type of the storage, either directory or pool
+	Type string
+
+	// Name the directory path or the name of the storage pool
+	Name string
+
+	// Capacity the total size of the storage in bytes
+	Capacity uint64
+
+	// Allocation the size in use in bytes
+	Allocation uint64
+
+	// Available the size available for new volumes in bytes
+	Available uint64
+}
+
+func (item *StorageModel) ToDTO() StorageDTO {
+	return StorageDTO{
+		Type:       item.Type,
+		Name:       item.Name,
+		Capacity:   item.Capacity,
+		Allocation: item.Allocation,
+		Available:  item.Available,
+	}
+}
@@ -3,19 +3,27 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
+// consoleSessionCookie carries the session of the user to the console
+// WebSockets, since a browser cannot send the authorization header there
+const consoleSessionCookie = "govm-console-session"
+
 var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// Idle connections do not keep a write buffer
+	WriteBufferPool: &sync.Pool{},
 	CheckOrigin: func(r *http.Request) bool {
 		logRequest("CheckOrigin", r)
 		// Allow connections from any origin
@@ -30,42 +38,63 @@ func (api *ApiServer) onVncOpen(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session := api.authenticateSession(r)
-	if session == nil {
-		sendJsonError("onVncOpen", w, UnauthorizedError, http.StatusUnauthorized)
+	session, name, ok := api.authorizeServerRequest("onVncOpen", w, r)
+	if !ok {
 		return
 	}
 
-	vars := mux.Vars(r)
-	name := vars["name"]
+	console, err := api.service.GetConsole(name)
+	if err != nil {
+		logAndSendJsonError(err, "onVncOpen", w, VncOpenError, http.StatusInternalServerError)
+		return
+	}
 
-	vncPassword, err := generatePassword(8)
+	vncSession, err := api.vncSessions.Create(session.Email, name, console.Type)
 	if err != nil {
-		logAndSendJsonError(err, "onVncOpen", w, VncGeneratePasswordError, http.StatusInternalServerError)
+		if errors.Is(err, ErrTooManyVncSessions) {
+			sendJsonError("onVncOpen", w, VncSessionLimitError, http.StatusTooManyRequests)
+		} else {
+			logAndSendJsonError(err, "onVncOpen", w, VncGenerateTokenError, http.StatusInternalServerError)
+		}
 		return
 	}
 
-	err = api.service.SetVNCPassword(name, vncPassword)
+	vncPassword, err := generatePassword(8)
 	if err != nil {
-		logAndSendJsonError(err, "onVncOpen", w, VncSetPasswordError, http.StatusInternalServerError)
+		api.vncSessions.Remove(vncSession.Token)
+		logAndSendJsonError(err, "onVncOpen", w, VncGeneratePasswordError, http.StatusInternalServerError)
 		return
 	}
 
-	token, err := generatePassword(32)
+	err = api.service.SetConsolePassword(name, vncPassword)
 	if err != nil {
-		logAndSendJsonError(err, "onVncOpen", w, VncGenerateTokenError, http.StatusInternalServerError)
+		api.vncSessions.Remove(vncSession.Token)
+		logAndSendJsonError(err, "onVncOpen", w, VncSetPasswordError, http.StatusInternalServerError)
 		return
 	}
-	path := fmt.Sprintf("api/vnc/%s", token)
+
+	path := fmt.Sprintf("api/vnc/%s", vncSession.Token)
 	url := fmt.Sprintf("/api/novnc/vnc_lite.html?path=%s&password=%s&scale=true", path, vncPassword)
+	if console.Type == SpiceConsoleType {
+		path = fmt.Sprintf("api/spice/%s", vncSession.Token)
+		url = fmt.Sprintf("/api/spice-html5/spice.html?path=%s&password=%s", path, vncPassword)
+	}
 
-	api.vncSessions[token] = name
+	http.SetCookie(w, &http.Cookie{
+		Name:     consoleSessionCookie,
+		Value:    session.Token,
+		Path:     "/api/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
 
 	response := ServerVncDTO{
+		Type:     console.Type,
 		URL:      url,
 		WS:       "/" + path,
 		Password: vncPassword,
-		Token:    token,
+		Token:    vncSession.Token,
 	}
 	sendJsonData("onVncOpen", w, response)
 
@@ -88,26 +117,83 @@ func (api *ApiServer) onVncClose(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	name, exists := api.vncSessions[token]
-	if exists {
-		delete(api.vncSessions, token)
+	vncSession := api.vncSessions.FindByToken(token)
+	if vncSession == nil || (vncSession.Owner != session.Email && !api.isAdminSession(session)) {
+		sendJsonError("onVncClose", w, VncSessionNotFoundError, http.StatusNotFound)
+		return
 	}
 
-	vncPassword, err := generatePassword(8)
-	if err != nil {
-		log.Printf("onVncClose: Could not generate a VNC password: %v", err)
+	// The password is rotated when the last session of the server is removed
+	api.vncSessions.Remove(token)
+
+	response := ServerVncDTO{}
+	sendJsonData("onVncClose", w, response)
+
+}
+
+// onVncSessionListRequest lists the open VNC sessions for admins
+func (api *ApiServer) onVncSessionListRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onVncSessionListRequest", r)
+	_, ok := api.authorizeAdminRequest("onVncSessionListRequest", w, r)
+	if !ok {
 		return
 	}
+	list := api.vncSessions.List()
+	payload := make([]VncSessionDTO, len(list))
+	for i, item := range list {
+		payload[i] = item.ToDTO()
+	}
+	response := VncSessionListDTO{
+		Payload: payload,
+	}
+	sendJsonData("onVncSessionListRequest", w, response)
+}
 
-	err = api.service.SetVNCPassword(name, vncPassword)
-	if err != nil {
-		log.Printf("onVncClose: Could not change VNC password: %v", err)
+// onVncSessionDeleteRequest disconnects a VNC session for admins
+func (api *ApiServer) onVncSessionDeleteRequest(w http.ResponseWriter, r *http.Request) {
+	logRequest("onVncSessionDeleteRequest", r)
+	_, ok := api.authorizeAdminRequest("onVncSessionDeleteRequest", w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	item := api.vncSessions.RemoveByID(vars["id"])
+	if item == nil {
+		sendJsonError("onVncSessionDeleteRequest", w, NotFoundError, http.StatusNotFound)
 		return
 	}
+	sendJsonData("onVncSessionDeleteRequest", w, item.ToDTO())
+}
 
-	response := ServerVncDTO{}
-	sendJsonData("onVncClose", w, response)
+// rotateVNCPassword changes the console password of the server to a random one,
+// so that the password of a closed session cannot be used anymore
+func (api *ApiServer) rotateVNCPassword(name string) {
+	vncPassword, err := generatePassword(8)
+	if err != nil {
+		log.Printf("rotateVNCPassword: Could not generate a VNC password: %v", err)
+		return
+	}
+	err = api.service.SetConsolePassword(name, vncPassword)
+	if err != nil {
+		log.Printf("rotateVNCPassword: Could not change VNC password: %v", err)
+	}
+}
 
+// authenticateConsoleSession returns the session of the authorization header
+// or of the console session cookie, otherwise nil
+func (api *ApiServer) authenticateConsoleSession(r *http.Request) *Session {
+	if session := api.authenticateSession(r); session != nil {
+		return session
+	}
+	cookie, err := r.Cookie(consoleSessionCookie)
+	if err != nil {
+		return nil
+	}
+	session, err := api.session.ValidateSession(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	return session
 }
 
 func (api *ApiServer) onVncWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -117,18 +203,38 @@ func (api *ApiServer) onVncWebSocket(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	token := vars["token"]
 
-	name, exists := api.vncSessions[token]
-	if !exists {
+	session := api.authenticateConsoleSession(r)
+	if session == nil {
 		sendJsonError("onVncWebSocket", w, UnauthorizedError, http.StatusUnauthorized)
 		return
 	}
 
-	vncTarget, err := api.service.GetVNC(name)
+	// A VNC token is not valid for SPICE and the other way around
+	consoleType := VncConsoleType
+	if strings.HasPrefix(r.URL.Path, "/api/spice/") {
+		consoleType = SpiceConsoleType
+	}
+	vncSession := api.vncSessions.FindByToken(token)
+	isAdmin := api.isAdminSession(session)
+	if vncSession == nil || vncSession.Type != consoleType || (vncSession.Owner != session.Email && !isAdmin) {
+		sendJsonError("onVncWebSocket", w, VncSessionNotFoundError, http.StatusNotFound)
+		return
+	}
+	name := vncSession.Server
+
+	// The owner may have lost the access since the session was opened
+	if !isAdmin && !api.config.GetConfig().ServerHasAccessToEmail(name, session.Email) {
+		api.vncSessions.Remove(token)
+		sendJsonError("onVncWebSocket", w, VncSessionNotFoundError, http.StatusNotFound)
+		return
+	}
+
+	console, err := api.service.GetConsole(name)
 	if err != nil {
-		log.Printf("onVncWebSocket: Could not get VNC: %v", err)
+		log.Printf("onVncWebSocket: Could not get console: %v", err)
 		return
 	}
-	log.Printf("onVncWebSocket: Connecting to: %s", vncTarget)
+	log.Printf("onVncWebSocket: Connecting to %s: %s", console.Type, console.Address)
 
 	// Upgrade the HTTP server connection to a WebSocket connection
 	wsConn, err := upgrader.Upgrade(w, r, nil)
@@ -138,45 +244,20 @@ func (api *ApiServer) onVncWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	defer wsConn.Close()
 
-	// Connect to the VNC server
-	vncConn, err := net.Dial("tcp", vncTarget)
+	_, err = api.vncSessions.Connect(token, wsConn)
 	if err != nil {
-		log.Println("onVncWebSocket: Error connecting to VNC server:", err)
+		log.Printf("onVncWebSocket: Could not connect the session: %v", err)
 		return
 	}
-	defer vncConn.Close()
-
-	// Forward messages from the WebSocket to the VNC server
-	go func() {
-		for {
-			_, message, err := wsConn.ReadMessage()
-			if err != nil {
-				log.Println("onVncWebSocket: Read error:", err)
-				break
-			}
-			_, err = vncConn.Write(message)
-			if err != nil {
-				log.Println("onVncWebSocket: Write error:", err)
-				break
-			}
-		}
-	}()
-
-	// Forward messages from the VNC server to the WebSocket
-	buffer := make([]byte, 1024)
-	for {
-		n, err := vncConn.Read(buffer)
-		if err != nil {
-			if err != io.EOF {
-				log.Println("onVncWebSocket: Read error:", err)
-			}
-			break
-		}
-		err = wsConn.WriteMessage(websocket.BinaryMessage, buffer[:n])
-		if err != nil {
-			log.Println("onVncWebSocket: Write error:", err)
-			break
-		}
+	defer api.vncSessions.Disconnect(token, wsConn)
+
+	// Connect to the VNC or SPICE server
+	vncConn, err := net.DialTimeout(console.Network, console.Address, VncDialTimeout)
+	if err != nil {
+		log.Println("onVncWebSocket: Error connecting to VNC server:", err)
+		return
 	}
 
+	api.vncProxy.Run(wsConn, vncConn, vncSession)
+
 }
@@ -16,6 +16,14 @@ var BuildFrontend fs.FS
 var novncWebContent embed.FS
 var BuildNoVNC fs.FS
 
+//go:embed frontend-spice/*
+var spiceWebContent embed.FS
+var BuildSpice fs.FS
+
+//go:embed frontend-serial/*
+var serialWebContent embed.FS
+var BuildSerial fs.FS
+
 func init() {
 
 	var err error
@@ -32,4 +40,16 @@ func init() {
 		log.Fatalf("NoVNC initialization failed: %v", err)
 	}
 
+	// SPICE console page
+	BuildSpice, err = fs.Sub(spiceWebContent, "frontend-spice")
+	if err != nil {
+		log.Fatalf("SPICE console initialization failed: %v", err)
+	}
+
+	// Serial console terminal page
+	BuildSerial, err = fs.Sub(serialWebContent, "frontend-serial")
+	if err != nil {
+		log.Fatalf("Serial console initialization failed: %v", err)
+	}
+
 }